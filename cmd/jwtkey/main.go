@@ -0,0 +1,82 @@
+// Command jwtkey generates a private key for signing access tokens. Write it
+// to SIGNING_KEYS_DIR as <kid>.pem; the file name becomes the key id.
+//
+//	go run ./cmd/jwtkey -alg ES256 > keys/2026-10.pem
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func main() {
+	alg := flag.String("alg", "ES256", "signing algorithm: RS256, ES256 or EdDSA")
+	public := flag.Bool("public", false, "read a private key from stdin and print its public key, for retiring it")
+	flag.Parse()
+
+	if *public {
+		if err := printPublic(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var key crypto.Signer
+	var err error
+	switch *alg {
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm %q", *alg)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := pem.Encode(os.Stdout, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printPublic() error {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return fmt.Errorf("expected a PKCS#8 PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported key %T", key)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	return pem.Encode(os.Stdout, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,40 @@
+// Command mockoidc is a minimal OpenID provider for trying out OIDC login
+// locally. Any email address typed into its login form is accepted.
+//
+//	go run ./cmd/mockoidc -addr localhost:9000
+//
+// and point a provider entry at issuer http://localhost:9000 with the
+// client id and secret given by the flags.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/oidc/mockoidc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "listen address")
+	issuer := flag.String("issuer", "", "issuer URL (default http://<addr>)")
+	clientID := flag.String("client-id", "todo", "accepted client id")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+
+	s, err := mockoidc.New(mockoidc.Options{
+		Issuer:       *issuer,
+		ClientID:     *clientID,
+		ClientSecret: *clientSecret,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("mock oidc provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
@@ -1,11 +1,21 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 
+	"github.com/devvdark0/todo/internal/auth"
 	"github.com/devvdark0/todo/internal/config"
 	"github.com/devvdark0/todo/internal/handler"
+	"github.com/devvdark0/todo/internal/mail"
 	"github.com/devvdark0/todo/internal/middleware"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/oidc"
 	"github.com/devvdark0/todo/internal/service"
 	"github.com/devvdark0/todo/internal/storage"
 	"github.com/devvdark0/todo/pkg/db"
@@ -27,20 +37,213 @@ func InitApp() error {
 	}
 	defer database.Close()
 
+	projectStore := storage.NewProjectStore(database, log)
+	projectService := service.NewProjectService(projectStore)
+	projectHandler := handler.NewProjectHandler(projectService, log)
+
 	taskStore := storage.NewStore(database, log)
-	taskService := service.NewService(taskStore)
+	taskService, err := service.NewService(taskStore, projectStore, service.TaskOptions{
+		SubtaskPolicy: cfg.TaskConfig.SubtaskPolicy,
+		MaxPageSize:   cfg.TaskConfig.MaxPageSize,
+	})
+	if err != nil {
+		return err
+	}
 	taskHandler := handler.NewHandler(taskService, log)
 
+	viewStore := storage.NewViewStore(database, log)
+	viewService := service.NewViewService(viewStore, taskService)
+	viewHandler := handler.NewViewHandler(viewService, log)
+
+	searchService := service.NewSearchService(storage.NewFullTextSearch(database, log))
+	searchHandler := handler.NewSearchHandler(searchService, log)
+
+	tagStore := storage.NewTagStore(database, log)
+	tagService := service.NewTagService(tagStore)
+	tagHandler := handler.NewTagHandler(tagService, log)
+
+	sender, err := mail.NewSender(cfg.MailConfig, log)
+	if err != nil {
+		return err
+	}
+	sender = mail.NewAsyncSender(sender, log)
+
 	userStore := storage.NewUserStore(database, log)
-	authService := service.NewJWTService([]byte(cfg.JWTConfig.Secret), cfg.JWTConfig.TokenTTL, userStore)
+	verifier := service.NewEmailVerifier(userStore, sender, service.VerificationOptions{
+		Secret:         auth.DeriveKey([]byte(cfg.JWTConfig.LinkSecret), "verify-email"),
+		LinkTTL:        cfg.JWTConfig.VerificationTTL,
+		ResendInterval: cfg.JWTConfig.VerificationResend,
+		VerifyURL:      strings.TrimSuffix(cfg.PublicURL, "/") + "/api/verify-email",
+	})
+	verificationHandler := handler.NewVerificationHandler(verifier, log)
+
+	refreshStore := storage.NewRefreshTokenStore(database, log)
+	revoker := service.NewTokenRevoker(storage.NewRevocationStore(database, log), cfg.JWTConfig.TokenTTL)
+	if err := revoker.Load(); err != nil {
+		return err
+	}
+	auditStore := storage.NewAuditStore(database, log)
+	limiter := service.NewLoginLimiter(service.LoginLimitOptions{
+		MaxAccountFailures: cfg.LoginConfig.MaxAccountFailures,
+		MaxIPFailures:      cfg.LoginConfig.MaxIPFailures,
+		BackoffAfter:       cfg.LoginConfig.BackoffAfter,
+		BackoffBase:        cfg.LoginConfig.BackoffBase,
+		BackoffMax:         cfg.LoginConfig.BackoffMax,
+		LockoutDuration:    cfg.LoginConfig.LockoutDuration,
+		FailureWindow:      cfg.LoginConfig.FailureWindow,
+	}, auditStore)
+	keys, err := configureKeys(cfg)
+	if err != nil {
+		return err
+	}
+	passwords, err := auth.NewPasswordHasher(auth.PasswordHashOptions{
+		Algorithm: cfg.HashConfig.Algorithm,
+		Argon2: auth.Argon2Params{
+			Memory:      cfg.HashConfig.Argon2Memory,
+			Time:        cfg.HashConfig.Argon2Time,
+			Parallelism: cfg.HashConfig.Argon2Parallelism,
+		},
+		BcryptCost: cfg.HashConfig.BcryptCost,
+	})
+	if err != nil {
+		return err
+	}
+	policy, err := configurePolicy(cfg, passwords)
+	if err != nil {
+		return err
+	}
+	totpKeys, err := configureTOTPKeys(cfg)
+	if err != nil {
+		return err
+	}
+	authService := service.NewJWTService(
+		service.JWTOptions{
+			Secret:               []byte(cfg.JWTConfig.Secret),
+			Issuer:               cfg.JWTConfig.TokenIssuer,
+			Audience:             cfg.JWTConfig.TokenAudience,
+			TokenTTL:             cfg.JWTConfig.TokenTTL,
+			RefreshTTL:           cfg.JWTConfig.RefreshTTL,
+			SessionTouchInterval: cfg.JWTConfig.SessionTouchInterval,
+			RequireVerifiedEmail: cfg.JWTConfig.RequireVerifiedLogin,
+			TOTPIssuer:           cfg.JWTConfig.TOTPIssuer,
+			ChallengeTTL:         cfg.JWTConfig.ChallengeTTL,
+			TOTPKeys:             totpKeys,
+			Keys:                 keys,
+			Limiter:              limiter,
+			Passwords:            passwords,
+			Policy:               policy,
+			OnError: func(err error) {
+				log.Error("auth service error", zap.Error(err))
+			},
+		},
+		service.AuthStores{
+			Users:         userStore,
+			RefreshTokens: refreshStore,
+			Sessions:      storage.NewSessionStore(database, log),
+			TOTP:          storage.NewTOTPStore(database, log),
+			AccessTokens:  storage.NewPersonalTokenStore(database, log),
+		},
+		revoker,
+		verifier,
+	)
 	authHandler := handler.NewJWTHandler(*authService, log)
-	userHandler := handler.NewUserHandler(userStore)
 
-	r := configureRouter(taskHandler, authHandler, userHandler, authService)
+	resetStore := storage.NewPasswordResetStore(database, log)
+	passwordService := service.NewPasswordResetService(
+		userStore,
+		resetStore,
+		sender,
+		authService,
+		service.PasswordResetOptions{
+			TokenTTL:       cfg.JWTConfig.PasswordResetTTL,
+			ResendInterval: cfg.JWTConfig.PasswordResetResend,
+			ResetURL:       strings.TrimSuffix(cfg.PublicURL, "/") + "/reset-password",
+			Hasher:         passwords,
+			Policy:         policy,
+		},
+	)
+	passwordHandler := handler.NewPasswordHandler(passwordService, log)
+
+	oidcService := service.NewOIDCService(
+		configureProviders(cfg),
+		storage.NewIdentityStore(database, log),
+		userStore,
+		authService,
+	)
+	oidcHandler := handler.NewOIDCHandler(oidcService, log)
+	profileService := service.NewProfileService(userStore, resetStore, sender, authService, service.ProfileOptions{
+		Secret:     auth.DeriveKey([]byte(cfg.JWTConfig.LinkSecret), "change-email"),
+		LinkTTL:    cfg.JWTConfig.VerificationTTL,
+		ConfirmURL: strings.TrimSuffix(cfg.PublicURL, "/") + "/api/profile/email/confirm",
+		Hasher:     passwords,
+		Policy:     policy,
+	})
+	accountService := service.NewAccountService(userStore, taskStore, authService, sender, auditStore, service.AccountOptions{
+		GracePeriod:  cfg.AccountConfig.DeletionGracePeriod,
+		ReauthWindow: cfg.AccountConfig.ReauthWindow,
+		Hasher:       passwords,
+	})
+	userHandler := handler.NewUserHandler(profileService, accountService, log)
+
+	roleService := service.NewRoleService(storage.NewRoleStore(database, log))
+	if err := roleService.Load(); err != nil {
+		return err
+	}
+	adminService := service.NewAdminService(userStore, roleService, authService, passwordService, auditStore)
+	if cfg.AdminEmail != "" {
+		promoted, err := adminService.BootstrapAdmin(cfg.AdminEmail)
+		if errors.Is(err, service.ErrAdminUnverified) {
+			log.Warn("admin role not granted until the email is verified", zap.String("email", cfg.AdminEmail))
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		if promoted {
+			log.Info("granted admin role", zap.String("email", cfg.AdminEmail))
+		}
+	}
+	adminHandler := handler.NewAdminHandler(adminService, roleService, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go accountService.RunDeletions(ctx, cfg.AccountConfig.DeletionCheckInterval, func(err error) {
+		log.Error("failed to purge deleted accounts", zap.Error(err))
+	})
+	go revoker.RunSync(ctx, cfg.JWTConfig.RevocationSync, func(err error) {
+		log.Error("failed to reload token revocations", zap.Error(err))
+	})
+	go roleService.RunSync(ctx, cfg.JWTConfig.RoleSync, func(err error) {
+		log.Error("failed to reload roles", zap.Error(err))
+	})
+
+	r := configureRouter(routes{
+		tasks:                taskHandler,
+		search:               searchHandler,
+		tags:                 tagHandler,
+		projects:             projectHandler,
+		views:                viewHandler,
+		auth:                 authHandler,
+		passwords:            passwordHandler,
+		verification:         verificationHandler,
+		oidc:                 oidcHandler,
+		users:                userHandler,
+		admin:                adminHandler,
+		authService:          authService,
+		roles:                roleService,
+		verifier:             verifier,
+		requireVerifiedTasks: cfg.JWTConfig.RequireVerifiedTasks,
+		log:                  log,
+	})
+
+	proxies, err := configureProxies(cfg)
+	if err != nil {
+		return err
+	}
 
 	srv := http.Server{
 		Addr:         "localhost:" + cfg.Port,
-		Handler:      r,
+		Handler:      middleware.RealIP(proxies)(r),
 		ReadTimeout:  cfg.Timeout,
 		WriteTimeout: cfg.Timeout,
 		IdleTimeout:  cfg.IdleTimeout,
@@ -55,30 +258,241 @@ func InitApp() error {
 
 }
 
-func configureRouter(
-	todoHandler *handler.TodoHandler,
-	authHandler *handler.JWTHandler,
-	userHandler *handler.UserHandler,
-	authService *service.JWTService,
-) *mux.Router {
+type routes struct {
+	tasks        *handler.TodoHandler
+	search       *handler.SearchHandler
+	tags         *handler.TagHandler
+	projects     *handler.ProjectHandler
+	views        *handler.ViewHandler
+	auth         *handler.JWTHandler
+	passwords    *handler.PasswordHandler
+	verification *handler.VerificationHandler
+	oidc         *handler.OIDCHandler
+	users        *handler.UserHandler
+	admin        *handler.AdminHandler
+
+	authService          *service.JWTService
+	roles                *service.RoleService
+	verifier             *service.EmailVerifier
+	requireVerifiedTasks bool
+	log                  *zap.Logger
+}
+
+func configureRouter(rt routes) *mux.Router {
 	r := mux.NewRouter()
 
-	r.HandleFunc("/api/register", authHandler.Register).Methods("POST")
-	r.HandleFunc("/api/login", authHandler.Login).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", rt.auth.JWKS).Methods("GET")
+	r.HandleFunc("/api/register", rt.auth.Register).Methods("POST")
+	r.HandleFunc("/api/login", rt.auth.Login).Methods("POST")
+	r.HandleFunc("/api/login/2fa", rt.auth.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/api/token/refresh", rt.auth.Refresh).Methods("POST")
+	r.HandleFunc("/api/password/forgot", rt.passwords.ForgotPassword).Methods("POST")
+	r.HandleFunc("/api/password/reset", rt.passwords.ResetPassword).Methods("POST")
+	r.HandleFunc("/api/verify-email", rt.verification.VerifyEmail).Methods("GET")
+	r.HandleFunc("/api/verify-email/resend", rt.verification.ResendVerification).Methods("POST")
+	r.HandleFunc("/api/profile/email/confirm", rt.users.ConfirmEmailChange).Methods("GET")
+	r.HandleFunc("/api/oidc/providers", rt.oidc.GetProviders).Methods("GET")
+	r.HandleFunc("/api/oidc/{provider}/login", rt.oidc.Login).Methods("GET")
+	r.HandleFunc("/api/oidc/{provider}/callback", rt.oidc.Callback).Methods("GET")
 
 	protected := r.PathPrefix("/api").Subrouter()
+	protected.Use(middleware.AuthMiddleware(*rt.authService, rt.log))
+
+	// Every protected route names the scope it needs, so personal access
+	// tokens only reach what they were granted.
+	handle := func(path, method, scope string, h http.HandlerFunc) {
+		protected.Handle(path, middleware.RequireScope(scope)(h)).Methods(method)
+	}
+
+	var createTask http.Handler = http.HandlerFunc(rt.tasks.CreateTask)
+	if rt.requireVerifiedTasks {
+		createTask = middleware.RequireVerifiedEmail(rt.verifier)(createTask)
+	}
+
+	handle("/logout", "POST", model.ScopeAccount, rt.auth.Logout)
+	handle("/logout/all", "POST", model.ScopeAccount, rt.auth.LogoutAll)
+	handle("/2fa/totp", "POST", model.ScopeAccount, rt.auth.EnrollTOTP)
+	handle("/2fa/totp/confirm", "POST", model.ScopeAccount, rt.auth.ConfirmTOTP)
+	handle("/2fa/totp/disable", "POST", model.ScopeAccount, rt.auth.DisableTOTP)
+	handle("/sessions", "GET", model.ScopeAccount, rt.auth.GetSessions)
+	handle("/sessions/{session_id}", "DELETE", model.ScopeAccount, rt.auth.DeleteSession)
+	handle("/tokens", "GET", model.ScopeAccount, rt.auth.GetPersonalTokens)
+	handle("/tokens", "POST", model.ScopeAccount, rt.auth.CreatePersonalToken)
+	handle("/tokens/{token_id}", "DELETE", model.ScopeAccount, rt.auth.DeletePersonalToken)
+	handle("/tasks", "GET", model.ScopeTasksRead, rt.tasks.GetTasks)
+	handle("/tasks/search", "GET", model.ScopeTasksRead, rt.search.SearchTasks)
+	handle("/tasks/{task_id}", "GET", model.ScopeTasksRead, rt.tasks.GetTask)
+	handle("/tasks", "POST", model.ScopeTasksWrite, createTask.ServeHTTP)
+	handle("/tasks/{task_id}", "PUT", model.ScopeTasksWrite, rt.tasks.UpdateTask)
+	handle("/tasks/{task_id}", "DELETE", model.ScopeTasksWrite, rt.tasks.DeleteTask)
+	handle("/tasks/{task_id}/occurrences", "GET", model.ScopeTasksRead, rt.tasks.GetOccurrences)
+	handle("/tasks/{task_id}/skip", "POST", model.ScopeTasksWrite, rt.tasks.SkipOccurrence)
+	handle("/tasks/{task_id}/end-series", "POST", model.ScopeTasksWrite, rt.tasks.EndSeries)
+	handle("/tags", "GET", model.ScopeTasksRead, rt.tags.GetTags)
+	handle("/tags/{tag_id}", "GET", model.ScopeTasksRead, rt.tags.GetTag)
+	handle("/tags", "POST", model.ScopeTasksWrite, rt.tags.CreateTag)
+	handle("/tags/{tag_id}", "PUT", model.ScopeTasksWrite, rt.tags.UpdateTag)
+	handle("/tags/{tag_id}", "DELETE", model.ScopeTasksWrite, rt.tags.DeleteTag)
+	handle("/projects", "GET", model.ScopeTasksRead, rt.projects.GetProjects)
+	handle("/projects/{project_id}", "GET", model.ScopeTasksRead, rt.projects.GetProject)
+	handle("/projects", "POST", model.ScopeTasksWrite, rt.projects.CreateProject)
+	handle("/projects/{project_id}", "PUT", model.ScopeTasksWrite, rt.projects.UpdateProject)
+	handle("/projects/{project_id}", "DELETE", model.ScopeTasksWrite, rt.projects.DeleteProject)
+	handle("/views", "GET", model.ScopeTasksRead, rt.views.GetViews)
+	handle("/views/{view_id}", "GET", model.ScopeTasksRead, rt.views.GetView)
+	handle("/views/{view_id}/tasks", "GET", model.ScopeTasksRead, rt.views.GetViewTasks)
+	handle("/views", "POST", model.ScopeTasksWrite, rt.views.CreateView)
+	handle("/views/{view_id}", "PUT", model.ScopeTasksWrite, rt.views.UpdateView)
+	handle("/views/{view_id}", "DELETE", model.ScopeTasksWrite, rt.views.DeleteView)
+	handle("/profile", "GET", model.ScopeProfileRead, rt.users.Profile)
+	handle("/profile", "PATCH", model.ScopeAccount, rt.users.UpdateProfile)
+	handle("/profile/password", "POST", model.ScopeAccount, rt.users.ChangePassword)
+	handle("/profile/email", "POST", model.ScopeAccount, rt.users.ChangeEmail)
+	handle("/profile/export", "GET", model.ScopeAccount, rt.users.ExportData)
+	handle("/profile", "DELETE", model.ScopeAccount, rt.users.DeleteAccount)
+	handle("/profile/deletion", "DELETE", model.ScopeAccount, rt.users.CancelDeletion)
 
-	protected.Use(middleware.AuthMiddleware(*authService))
-	protected.HandleFunc("/tasks", todoHandler.GetTasks).Methods("GET")
-	protected.HandleFunc("/tasks/{task_id}", todoHandler.GetTask).Methods("GET")
-	protected.HandleFunc("/tasks", todoHandler.CreateTask).Methods("POST")
-	protected.HandleFunc("/tasks/{task_id}", todoHandler.UpdateTask).Methods("PUT")
-	protected.HandleFunc("/tasks/{task_id}", todoHandler.DeleteTask).Methods("DELETE")
-	protected.HandleFunc("/profile", userHandler.Profile).Methods("GET")
+	// Admin routes additionally need a permission from the user's role.
+	admin := func(path, method, permission string, h http.HandlerFunc) {
+		handle(path, method, model.ScopeAccount, middleware.RequirePermission(rt.roles, permission)(h).ServeHTTP)
+	}
+
+	admin("/admin/users", "GET", model.PermissionUsersRead, rt.admin.ListUsers)
+	admin("/admin/users/{user_id}", "GET", model.PermissionUsersRead, rt.admin.GetUser)
+	admin("/admin/users/{user_id}/disable", "POST", model.PermissionUsersManage, rt.admin.DisableUser)
+	admin("/admin/users/{user_id}/enable", "POST", model.PermissionUsersManage, rt.admin.EnableUser)
+	admin("/admin/users/{user_id}/password-reset", "POST", model.PermissionUsersManage, rt.admin.ForcePasswordReset)
+	admin("/admin/users/{user_id}/role", "PUT", model.PermissionRolesManage, rt.admin.SetRole)
+	admin("/admin/roles", "GET", model.PermissionRolesManage, rt.admin.ListRoles)
+	admin("/admin/roles", "POST", model.PermissionRolesManage, rt.admin.CreateRole)
+	admin("/admin/roles/{role}", "PUT", model.PermissionRolesManage, rt.admin.UpdateRole)
+	admin("/admin/roles/{role}", "DELETE", model.PermissionRolesManage, rt.admin.DeleteRole)
 
 	return r
 }
 
+// configureKeys returns the token signing keys, or nil to sign with the HMAC
+// secret. An empty secret is refused wherever tokens would be signed or
+// checked with it, since anyone could mint tokens with an empty key.
+func configureKeys(cfg *config.Config) (*auth.KeySet, error) {
+	if cfg.JWTConfig.SigningKeysDir == "" {
+		if cfg.JWTConfig.Secret == "" {
+			return nil, errors.New("SECRET_KEY is required unless SIGNING_KEYS_DIR is set")
+		}
+		return nil, nil
+	}
+
+	var legacy []byte
+	if cfg.JWTConfig.AcceptHMACTokens {
+		if cfg.JWTConfig.Secret == "" {
+			return nil, errors.New("ACCEPT_HMAC_TOKENS needs SECRET_KEY")
+		}
+		legacy = []byte(cfg.JWTConfig.Secret)
+	}
+	return auth.LoadKeySet(cfg.JWTConfig.SigningKeysDir, cfg.JWTConfig.SigningKeyID, legacy)
+}
+
+// configureTOTPKeys also keeps the key TOTP secrets were sealed with before
+// they had a dedicated one, so existing enrolments keep working until their
+// next use seals them again. Without a dedicated key that old key stays in
+// use.
+func configureTOTPKeys(cfg *config.Config) (*auth.SealKeys, error) {
+	var legacy []byte
+	if cfg.JWTConfig.Secret != "" {
+		legacy = auth.DeriveKey([]byte(cfg.JWTConfig.Secret), "totp-secret")
+	}
+
+	if cfg.JWTConfig.TOTPEncryptionKey == "" {
+		if strings.TrimSpace(cfg.JWTConfig.TOTPRetiredEncryption) != "" {
+			return nil, errors.New("TOTP_RETIRED_ENCRYPTION_KEYS needs TOTP_ENCRYPTION_KEY")
+		}
+		return auth.NewLegacySealKeys(legacy), nil
+	}
+
+	active, err := auth.ParseSealKey(cfg.JWTConfig.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP_ENCRYPTION_KEY: %w", err)
+	}
+	keys := map[string][]byte{cfg.JWTConfig.TOTPEncryptionKeyID: active}
+
+	if retired := strings.TrimSpace(cfg.JWTConfig.TOTPRetiredEncryption); retired != "" {
+		for _, pair := range strings.Split(retired, ",") {
+			id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if _, dup := keys[id]; !ok || dup {
+				return nil, fmt.Errorf("TOTP_RETIRED_ENCRYPTION_KEYS: %w: expected id:key", auth.ErrInvalidSealKey)
+			}
+			key, err := auth.ParseSealKey(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("TOTP_RETIRED_ENCRYPTION_KEYS: %w", err)
+			}
+			keys[id] = key
+		}
+	}
+
+	return auth.NewSealKeys(cfg.JWTConfig.TOTPEncryptionKeyID, keys, legacy)
+}
+
+func configureProxies(cfg *config.Config) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, raw := range strings.Split(cfg.TrustedProxies, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(raw)
+			if addrErr != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func configurePolicy(cfg *config.Config, passwords *auth.PasswordHasher) (*auth.PasswordPolicy, error) {
+	var breached *auth.BreachedList
+	if cfg.PolicyConfig.BreachedList != "" {
+		var err error
+		if breached, err = auth.NewBreachedList(cfg.PolicyConfig.BreachedList); err != nil {
+			return nil, err
+		}
+	}
+
+	return auth.NewPasswordPolicy(auth.PasswordPolicyOptions{
+		MinLength:        cfg.PolicyConfig.MinLength,
+		MaxLength:        cfg.PolicyConfig.MaxLength,
+		MaxBytes:         passwords.MaxPasswordBytes(),
+		MinClasses:       cfg.PolicyConfig.MinClasses,
+		DisallowIdentity: cfg.PolicyConfig.DisallowIdentity,
+	}, breached), nil
+}
+
+func configureProviders(cfg *config.Config) []*oidc.Provider {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	providers := make([]*oidc.Provider, 0, len(cfg.OIDCConfig.Providers))
+	for _, p := range cfg.OIDCConfig.Providers {
+		redirectURL := p.RedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimSuffix(cfg.PublicURL, "/") + "/api/oidc/" + p.Name + "/callback"
+		}
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       p.Scopes,
+		}, client))
+	}
+
+	return providers
+}
+
 func configureLogger(cfg *config.Config) *zap.Logger {
 	if cfg.Env == "local" {
 		return zap.Must(zap.NewDevelopment())
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hashPrefixLength is the length of the hash prefix that names a range, as
+// in the Pwned Passwords range API.
+const hashPrefixLength = 5
+
+// BreachedList looks passwords up in a local copy of a breached password
+// list of upper case SHA-1 hashes, in one of two layouts:
+//
+//   - a directory of range files named by the first five hex digits of the
+//     hash (optionally with a .txt extension), each holding lines of
+//     SUFFIX:COUNT, so a lookup only reads the range of its prefix;
+//   - a single file of HASH:COUNT lines sorted by hash, which is binary
+//     searched on disk rather than loaded.
+type BreachedList struct {
+	path  string
+	isDir bool
+}
+
+func NewBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening breached password list: %w", err)
+	}
+	return &BreachedList{path: path, isDir: info.IsDir()}, nil
+}
+
+func (b *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if b.isDir {
+		return b.searchRange(hash[:hashPrefixLength], hash[hashPrefixLength:])
+	}
+	return b.searchSorted(hash)
+}
+
+func (b *BreachedList) searchRange(prefix, suffix string) (bool, error) {
+	file, err := os.Open(filepath.Join(b.path, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(b.path, prefix+".txt"))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if hash, count, ok := parseHashLine(scanner.Text()); ok && hash == suffix {
+			return count, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func (b *BreachedList) searchSorted(hash string) (bool, error) {
+	file, err := os.Open(b.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	// The search keeps the invariant that a line holding hash, if there is
+	// one, starts within [lo, hi).
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, next, err := lineFrom(file, mid)
+		if err != nil {
+			return false, err
+		}
+		if line == "" {
+			hi = mid
+			continue
+		}
+
+		found, count, ok := parseHashLine(line)
+		if !ok {
+			return false, fmt.Errorf("malformed breached password list line %q", line)
+		}
+		switch strings.Compare(found, hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+// maxLineLength bounds a line of the sorted list: a hash, a colon and a
+// count, with room to spare.
+const maxLineLength = 128
+
+// lineFrom returns the first line that starts at or after offset, and the
+// offset just past it. It returns an empty line at the end of the file.
+func lineFrom(r io.ReaderAt, offset int64) (string, int64, error) {
+	start := offset
+	if offset > 0 {
+		// A line starts at offset only if the byte before it ends a line.
+		start = offset - 1
+	}
+
+	buf := make([]byte, 2*maxLineLength)
+	n, err := r.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, err
+	}
+	buf = buf[:n]
+
+	if offset > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			// Short of a full buffer, the file ended inside the line.
+			if n < 2*maxLineLength {
+				return "", 0, nil
+			}
+			return "", 0, errors.New("breached password list line too long")
+		}
+		buf = buf[i+1:]
+		start += int64(i + 1)
+	}
+	if len(buf) == 0 {
+		return "", 0, nil
+	}
+
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		end = len(buf)
+	}
+	return strings.TrimRight(string(buf[:end]), "\r"), start + int64(end) + 1, nil
+}
+
+// parseHashLine splits a HASH:COUNT line. A count of zero, used by some
+// lists as padding, does not count as breached.
+func parseHashLine(line string) (string, bool, bool) {
+	hash, count, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok {
+		return strings.ToUpper(hash), true, hash != ""
+	}
+	return strings.ToUpper(hash), strings.TrimLeft(count, "0") != "", hash != ""
+}
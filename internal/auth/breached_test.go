@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeSortedList writes HASH:COUNT lines for passwords, sorted by hash, and
+// returns the passwords in that order.
+func writeSortedList(t *testing.T, passwords []string, trailingNewline bool) (string, []string) {
+	t.Helper()
+
+	sorted := append([]string(nil), passwords...)
+	sort.Slice(sorted, func(i, j int) bool { return sha1Hex(sorted[i]) < sha1Hex(sorted[j]) })
+
+	lines := make([]string, len(sorted))
+	for i, password := range sorted {
+		lines[i] = fmt.Sprintf("%s:%d", sha1Hex(password), i+1)
+	}
+	content := strings.Join(lines, "\n")
+	if trailingNewline {
+		content += "\n"
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, sorted
+}
+
+func TestBreachedListSortedFile(t *testing.T) {
+	var passwords []string
+	for i := 0; i < 200; i++ {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+
+	for _, trailingNewline := range []bool{true, false} {
+		path, sorted := writeSortedList(t, passwords, trailingNewline)
+		list, err := NewBreachedList(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name     string
+			password string
+			want     bool
+		}{
+			{"first", sorted[0], true},
+			{"second", sorted[1], true},
+			{"middle", sorted[len(sorted)/2], true},
+			{"last", sorted[len(sorted)-1], true},
+			{"missing", "not in the list", false},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/trailing newline %t", tt.name, trailingNewline), func(t *testing.T) {
+				got, err := list.Contains(tt.password)
+				if err != nil {
+					t.Fatalf("Contains() error = %v", err)
+				}
+				if got != tt.want {
+					t.Fatalf("Contains(%q) = %t, want %t", tt.password, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestBreachedListSortedFileZeroCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(sha1Hex("padding")+":0\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := list.Contains("padding"); err != nil || got {
+		t.Fatalf("Contains() = %t, %v; want false for a zero count", got, err)
+	}
+}
+
+func TestLineFrom(t *testing.T) {
+	const content = "AAA:1\nBBB:2\r\nCCC:3"
+
+	tests := []struct {
+		name     string
+		offset   int64
+		wantLine string
+		wantNext int64
+	}{
+		{"start of file", 0, "AAA:1", 6},
+		{"inside the first line", 2, "BBB:2", 13},
+		{"start of a line", 6, "BBB:2", 13},
+		{"at a carriage return", 11, "CCC:3", 19},
+		{"last line without newline", 13, "CCC:3", 19},
+		{"inside the last line", 15, "", 0},
+		{"end of file", int64(len(content)), "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, next, err := lineFrom(strings.NewReader(content), tt.offset)
+			if err != nil {
+				t.Fatalf("lineFrom() error = %v", err)
+			}
+			if line != tt.wantLine || (line != "" && next != tt.wantNext) {
+				t.Fatalf("lineFrom(%d) = %q, %d; want %q, %d", tt.offset, line, next, tt.wantLine, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestLineFromRefusesLongLines(t *testing.T) {
+	content := strings.Repeat("A", 3*maxLineLength)
+	if _, _, err := lineFrom(strings.NewReader(content), 1); err == nil {
+		t.Fatal("lineFrom() accepted a line longer than maxLineLength")
+	}
+}
+
+func TestBreachedListRangeDirectory(t *testing.T) {
+	breached := sha1Hex("hunter2")
+	prefix, suffix := breached[:hashPrefixLength], breached[hashPrefixLength:]
+
+	tests := []struct {
+		name     string
+		file     string
+		password string
+		want     bool
+	}{
+		{"range file", prefix, "hunter2", true},
+		{"range file with .txt", prefix + ".txt", "hunter2", true},
+		{"other suffix in the range", prefix, "hunter3", false},
+		{"no range file", "00000", "hunter2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			content := "0000000000000000000000000000000000A:3\n" + suffix + ":42\n"
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			list, err := NewBreachedList(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := list.Contains(tt.password)
+			if err != nil {
+				t.Fatalf("Contains() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Contains(%q) = %t, want %t", tt.password, got, tt.want)
+			}
+		})
+	}
+}
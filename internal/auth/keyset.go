@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// SigningKey is one key of a KeySet. Keys without a private half can only
+// verify tokens; they are kept around while tokens they signed are alive.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	private any
+	public  any
+}
+
+// KeySet signs tokens with its active key and verifies them with any of its
+// keys, chosen by the kid header. Rotating means adding a new key, making it
+// active, and removing the old one once the tokens it signed have expired.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// legacy verifies HS256 tokens without a kid, as issued before
+	// asymmetric signing was configured.
+	legacy []byte
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a shared
+// HS256 secret. Its tokens carry no kid, and it publishes no keys.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		active: &SigningKey{Method: jwt.SigningMethodHS256, private: secret, public: secret},
+		keys:   map[string]*SigningKey{},
+		legacy: secret,
+	}
+}
+
+// LoadKeySet reads every *.pem file in dir as a key whose id is the file
+// name without extension. Files may hold a private key (PKCS#8, PKCS#1 or
+// SEC 1) or, for retired keys, just the public key. activeID names the key
+// used for signing. If legacySecret is set, HS256 tokens without a kid are
+// still accepted.
+func LoadKeySet(dir, activeID string, legacySecret []byte) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("listing signing keys: %w", err)
+	}
+
+	set := &KeySet{keys: make(map[string]*SigningKey, len(paths)), legacy: legacySecret}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading signing key: %w", err)
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := parseSigningKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", id, err)
+		}
+		set.keys[id] = key
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: active key %q not found in %s", ErrUnknownSigningKey, activeID, dir)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// Sign signs claims with the active key. typ goes into the typ header, so
+// that verifiers holding the published keys can tell tokens meant for
+// different uses apart before looking at the claims.
+func (s *KeySet) Sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["typ"] = typ
+	if s.active.ID != "" {
+		token.Header["kid"] = s.active.ID
+	}
+	return token.SignedString(s.active.private)
+}
+
+// Keyfunc picks the verification key for a token. A key only verifies
+// tokens of its own algorithm, which rules out algorithm confusion.
+func (s *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if s.legacy != nil && token.Method == jwt.SigningMethodHS256 {
+			return s.legacy, nil
+		}
+		return nil, ErrUnknownSigningKey
+	}
+
+	key, ok := s.keys[kid]
+	if !ok || token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnknownSigningKey
+	}
+	return key.public, nil
+}
+
+// Methods lists the algorithms the set can verify.
+func (s *KeySet) Methods() []string {
+	methods := make([]string, 0, len(s.keys)+1)
+	if s.legacy != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	for _, key := range s.keys {
+		methods = append(methods, key.Method.Alg())
+	}
+	return methods
+}
+
+// JWKS returns the public keys of the set, sorted by id.
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk, err := NewJWK(key.ID, key.Method.Alg(), key.public)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func parseSigningKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private, public any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM type %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if private != nil {
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, private)
+		}
+		public = signer.Public()
+	}
+
+	method, err := signingMethod(public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{ID: id, Method: method, private: private, public: public}, nil
+}
+
+func signingMethod(public any) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%w: RSA keys must be at least 2048 bits", ErrUnsupportedKey)
+		}
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().Name {
+		case "P-256":
+			return jwt.SigningMethodES256, nil
+		case "P-384":
+			return jwt.SigningMethodES384, nil
+		case "P-521":
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("%w: curve %s", ErrUnsupportedKey, key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+}
@@ -1,19 +1,230 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Upper bounds of the argon2id parameters. Stored hashes above them are
+// refused rather than verified, since a tampered hash could otherwise make
+// a single login take minutes or gigabytes.
+const (
+	maxArgon2Memory      = 1 << 20 // 1 GiB in KiB
+	maxArgon2Time        = 16
+	maxArgon2Parallelism = 16
+	maxArgon2SaltLength  = 64
+	maxArgon2KeyLength   = 64
+)
+
+const bcryptMaxPasswordBytes = 72
+
+var (
+	ErrPasswordMismatch     = errors.New("password does not match")
+	ErrUnknownPasswordHash  = errors.New("unknown password hash format")
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hash algorithm")
+)
+
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Time        uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordHashOptions select the algorithm and cost new hashes are made
+// with.
+type PasswordHashOptions struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+// PasswordHasher hashes passwords with the current policy and verifies
+// hashes made under any earlier one. Argon2id hashes use the PHC string
+// format, which records the parameters next to the salt:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+//
+// bcrypt hashes keep their usual $2a$/$2b$ format.
+type PasswordHasher struct {
+	opts PasswordHashOptions
+
+	dummyOnce sync.Once
+	dummy     string
+}
+
+func NewPasswordHasher(opts PasswordHashOptions) (*PasswordHasher, error) {
+	switch opts.Algorithm {
+	case AlgorithmArgon2id:
+		if opts.Argon2.SaltLength == 0 {
+			opts.Argon2.SaltLength = 16
+		}
+		if opts.Argon2.KeyLength == 0 {
+			opts.Argon2.KeyLength = 32
+		}
+		if !validArgon2(opts.Argon2) {
+			return nil, fmt.Errorf("invalid argon2id parameters %+v", opts.Argon2)
+		}
+	case AlgorithmBcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", opts.BcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, opts.Algorithm)
+	}
+
+	return &PasswordHasher{opts: opts}, nil
+}
+
+// DefaultPasswordHasher uses argon2id with the OWASP recommended minimum
+// parameters.
+func DefaultPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{opts: PasswordHashOptions{
+		Algorithm:  AlgorithmArgon2id,
+		Argon2:     Argon2Params{Memory: 19456, Time: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		BcryptCost: bcrypt.DefaultCost,
+	}}
+}
+
+// MaxPasswordBytes is the longest password in bytes that Hash accepts, or
+// zero if there is no limit. bcrypt refuses passwords over 72 bytes.
+func (h *PasswordHasher) MaxPasswordBytes() int {
+	if h.opts.Algorithm == AlgorithmBcrypt {
+		return bcryptMaxPasswordBytes
+	}
+	return 0
+}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.opts.Algorithm == AlgorithmBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("hashing password err: %w", err)
+		}
+		return string(hashed), nil
+	}
+
+	p := h.opts.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("hashing password err: %w", err)
 	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Parallelism, p.KeyLength)
+
+	return encodeArgon2(p, salt, key), nil
+}
+
+// Verify checks password against hashed. needsRehash reports that the
+// password matched but hashed was not made with the current policy.
+func (h *PasswordHasher) Verify(hashed, password string) (needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		p, salt, key, err := decodeArgon2(hashed)
+		if err != nil {
+			return false, err
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, ErrPasswordMismatch
+		}
+		want := h.opts.Argon2
+		return h.opts.Algorithm != AlgorithmArgon2id ||
+			p.Memory != want.Memory || p.Time != want.Time || p.Parallelism != want.Parallelism ||
+			uint32(len(salt)) != want.SaltLength || uint32(len(key)) != want.KeyLength, nil
+	case strings.HasPrefix(hashed, "$2"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrPasswordMismatch
+			}
+			return false, err
+		}
+		cost, err := bcrypt.Cost([]byte(hashed))
+		if err != nil {
+			return false, err
+		}
+		return h.opts.Algorithm != AlgorithmBcrypt || cost < h.opts.BcryptCost, nil
+	case hashed == "":
+		// Users created through single sign-on have no password. They take
+		// as long to refuse as anyone else, so timing does not tell them
+		// apart.
+		h.VerifyMissing(password)
+		return false, ErrPasswordMismatch
+	default:
+		return false, ErrUnknownPasswordHash
+	}
+}
+
+// VerifyMissing takes as long as verifying password against a hash made
+// with the current policy, and fails. Logins for unknown accounts call it so
+// they are not answered measurably faster than wrong passwords.
+func (h *PasswordHasher) VerifyMissing(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = h.Hash("")
+	})
+	if h.dummy != "" {
+		_, _ = h.Verify(h.dummy, password)
+	}
+}
+
+var b64Salt = base64.RawStdEncoding
+
+func encodeArgon2(p Argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Parallelism, b64Salt.EncodeToString(salt), b64Salt.EncodeToString(key))
+}
+
+func decodeArgon2(hashed string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := b64Salt.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := b64Salt.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	if !validArgon2(p) {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
 
-	return string(hashedPassword), nil
+	return p, salt, key, nil
 }
 
-func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func validArgon2(p Argon2Params) bool {
+	return p.Time >= 1 && p.Time <= maxArgon2Time &&
+		p.Parallelism >= 1 && p.Parallelism <= maxArgon2Parallelism &&
+		p.Memory >= 8*uint32(p.Parallelism) && p.Memory <= maxArgon2Memory &&
+		p.SaltLength >= 8 && p.SaltLength <= maxArgon2SaltLength &&
+		p.KeyLength >= 16 && p.KeyLength <= maxArgon2KeyLength
 }
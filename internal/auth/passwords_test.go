@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRefusesExcessiveArgon2Parameters(t *testing.T) {
+	h := DefaultPasswordHasher()
+	hashed, err := h.Hash("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Verify(hashed, "correct horse battery"); err != nil {
+		t.Fatalf("Verify() of a fresh hash error = %v", err)
+	}
+
+	parts := strings.Split(hashed, "$")
+	tests := []struct {
+		name   string
+		params string
+	}{
+		{"memory", "m=4194304,t=2,p=1"},
+		{"time", "m=19456,t=1000,p=1"},
+		{"parallelism", "m=19456,t=2,p=255"},
+		{"memory below parallelism", "m=8,t=2,p=4"},
+		{"zero time", "m=19456,t=0,p=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := strings.Join([]string{"", parts[1], parts[2], tt.params, parts[4], parts[5]}, "$")
+			if _, err := h.Verify(tampered, "correct horse battery"); !errors.Is(err, ErrUnknownPasswordHash) {
+				t.Fatalf("Verify() error = %v, want %v", err, ErrUnknownPasswordHash)
+			}
+		})
+	}
+}
+
+func TestNewPasswordHasherRefusesExcessiveArgon2Parameters(t *testing.T) {
+	_, err := NewPasswordHasher(PasswordHashOptions{
+		Algorithm: AlgorithmArgon2id,
+		Argon2:    Argon2Params{Memory: 4 << 20, Time: 2, Parallelism: 1},
+	})
+	if err == nil {
+		t.Fatal("NewPasswordHasher() accepted 4 GiB of memory")
+	}
+}
+
+func TestVerifyMissingAndEmptyHashFail(t *testing.T) {
+	h := DefaultPasswordHasher()
+	h.VerifyMissing("anything")
+
+	if _, err := h.Verify("", "anything"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("Verify() of an empty hash error = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
+
+func TestPolicyBoundsPasswordsToBcryptLimit(t *testing.T) {
+	h, err := NewPasswordHasher(PasswordHashOptions{Algorithm: AlgorithmBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultPasswordHasher().MaxPasswordBytes(); got != 0 {
+		t.Fatalf("argon2id MaxPasswordBytes() = %d, want 0", got)
+	}
+	policy := NewPasswordPolicy(PasswordPolicyOptions{MinLength: 8, MaxLength: 128, MaxBytes: h.MaxPasswordBytes()}, nil)
+
+	// 40 characters, but 80 bytes.
+	long := strings.Repeat("é", 40)
+	var policyErr *PolicyError
+	if err := policy.Check(long, "", ""); !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != RuleMaxLength {
+		t.Fatalf("Check() error = %v, want a %s violation", err, RuleMaxLength)
+	}
+
+	fits := strings.Repeat("é", 36)
+	if err := policy.Check(fits, "", ""); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if _, err := h.Hash(fits); err != nil {
+		t.Fatalf("Hash() of a password within the policy error = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleCharacterClasses = "character_classes"
+	RuleContainsEmail    = "contains_email"
+	RuleContainsUsername = "contains_username"
+	RuleBreached         = "breached"
+)
+
+// minIdentityLength keeps very short usernames or address parts from ruling
+// out every password that happens to contain them.
+const minIdentityLength = 3
+
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password broke. It matches
+// ErrPasswordPolicy.
+type PolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+type PasswordPolicyOptions struct {
+	// MinLength and MaxLength count characters, not bytes.
+	MinLength int
+	MaxLength int
+	// MaxBytes bounds the UTF-8 length, for hashes that only take so many
+	// bytes; see PasswordHasher.MaxPasswordBytes. Zero means no bound.
+	MaxBytes int
+	// MinClasses is how many of lower case, upper case, digits and other
+	// characters a password has to mix.
+	MinClasses int
+	// DisallowIdentity rejects passwords containing the username or the
+	// local part of the email address.
+	DisallowIdentity bool
+}
+
+// PasswordPolicy decides whether a password may be set.
+type PasswordPolicy struct {
+	opts     PasswordPolicyOptions
+	breached *BreachedList
+}
+
+// NewPasswordPolicy returns a policy enforcing opts. breached may be nil to
+// skip the breached password check.
+func NewPasswordPolicy(opts PasswordPolicyOptions, breached *BreachedList) *PasswordPolicy {
+	return &PasswordPolicy{opts: opts, breached: breached}
+}
+
+// DefaultPasswordPolicy only bounds the length.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return NewPasswordPolicy(PasswordPolicyOptions{MinLength: 8, MaxLength: 128}, nil)
+}
+
+// Check returns a *PolicyError if password breaks any rule for the user with
+// the given email and username.
+func (p *PasswordPolicy) Check(password, email, username string) error {
+	var violations []PolicyViolation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, PolicyViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.opts.MinLength {
+		add(RuleMinLength, "must be at least %d characters long", p.opts.MinLength)
+	}
+	if p.opts.MaxLength > 0 && length > p.opts.MaxLength {
+		add(RuleMaxLength, "must be at most %d characters long", p.opts.MaxLength)
+	} else if p.opts.MaxBytes > 0 && len(password) > p.opts.MaxBytes {
+		add(RuleMaxLength, "must be at most %d bytes long", p.opts.MaxBytes)
+	}
+	if classes := characterClasses(password); classes < p.opts.MinClasses {
+		add(RuleCharacterClasses, "must mix at least %d of lower case letters, upper case letters, digits and symbols", p.opts.MinClasses)
+	}
+
+	if p.opts.DisallowIdentity {
+		lower := strings.ToLower(password)
+		local, _, _ := strings.Cut(strings.ToLower(email), "@")
+		if len(local) >= minIdentityLength && strings.Contains(lower, local) {
+			add(RuleContainsEmail, "must not contain your email address")
+		}
+		name := strings.ToLower(strings.TrimSpace(username))
+		if len(name) >= minIdentityLength && strings.Contains(lower, name) {
+			add(RuleContainsUsername, "must not contain your username")
+		}
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("breached password check: %w", err)
+		}
+		if breached {
+			add(RuleBreached, "appears in a known data breach; choose a different password")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	dir := t.TempDir()
+	breached := sha1Hex("Tr0ub4dor&3")
+	if err := os.WriteFile(filepath.Join(dir, breached[:hashPrefixLength]), []byte(breached[hashPrefixLength:]+":7\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewBreachedList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewPasswordPolicy(PasswordPolicyOptions{
+		MinLength:        8,
+		MaxLength:        20,
+		MaxBytes:         24,
+		MinClasses:       3,
+		DisallowIdentity: true,
+	}, list)
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     []string
+	}{
+		{"acceptable", "Correct-Horse-9", "alice", nil},
+		{"too short", "Ab1!", "alice", []string{RuleMinLength}},
+		{"too long", "Correct-Horse-Battery-9", "alice", []string{RuleMaxLength}},
+		{"too many bytes", "Ünïcödé-Pässwörd-99", "alice", []string{RuleMaxLength}},
+		{"too few classes", "correcthorse", "alice", []string{RuleCharacterClasses}},
+		{"contains the email", "Carol-Secret-9", "alice", []string{RuleContainsEmail}},
+		{"contains the username", "My-ALICE-pass-9", "alice", []string{RuleContainsUsername}},
+		{"short username allowed", "My-al-pass-9", "al", nil},
+		{"breached", "Tr0ub4dor&3", "alice", []string{RuleBreached}},
+		{"several rules", "carol", "alice", []string{RuleMinLength, RuleCharacterClasses, RuleContainsEmail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password, "carol@example.com", tt.username)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+				return
+			}
+
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) || !errors.Is(err, ErrPasswordPolicy) {
+				t.Fatalf("Check() error = %v, want a *PolicyError", err)
+			}
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Fatalf("violated rules = %v, want %v", rules, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+const recoveryCodeBytes = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes returns n random recovery codes formatted as four groups
+// of four characters, e.g. "k3jd-9xq2-mm4a-p7rt".
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	buf := make([]byte, recoveryCodeBytes)
+	for i := 0; i < n; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generating recovery code err: %w", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(buf))
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the formatting users may or may not type, so
+// that the stored hash matches either way.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
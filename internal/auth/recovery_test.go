@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q is not four groups of four", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q returned twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	want := "k3jd9xq2mm4ap7rt"
+	for _, code := range []string{
+		"k3jd-9xq2-mm4a-p7rt",
+		"K3JD-9XQ2-MM4A-P7RT",
+		"  k3jd 9xq2 mm4a p7rt ",
+		"k3jd9xq2mm4ap7rt",
+	} {
+		if got := NormalizeRecoveryCode(code); got != want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
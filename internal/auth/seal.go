@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnsealFailed   = errors.New("sealed value could not be opened")
+	ErrInvalidSealKey = errors.New("invalid seal key")
+	ErrNoSealKey      = errors.New("no seal key is configured")
+)
+
+// DeriveKey derives a 32 byte key for one purpose from an application secret.
+func DeriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Seal encrypts plaintext with AES-GCM under a 32 byte key.
+func Seal(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce err: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(key []byte, sealed string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return "", ErrUnsealFailed
+	}
+
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrUnsealFailed
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher creation err: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// SealKeys seals values with its active key and opens them with whichever
+// key they name. Sealed values are prefixed with the key id, as in
+// "2:<ciphertext>". Values without an id were sealed before keys had ids and
+// are opened with the legacy key. Rotating means adding a new key, making it
+// active, and removing the old one once nothing sealed with it is left.
+type SealKeys struct {
+	activeID string
+	keys     map[string][]byte
+	legacy   []byte
+}
+
+// NewSealKeys returns the key set for keys, which maps ids to 32 byte keys.
+// legacy may be nil.
+func NewSealKeys(activeID string, keys map[string][]byte, legacy []byte) (*SealKeys, error) {
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: bad key id %q", ErrInvalidSealKey, id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%w: key %q must be 32 bytes", ErrInvalidSealKey, id)
+		}
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q not found", ErrInvalidSealKey, activeID)
+	}
+
+	return &SealKeys{activeID: activeID, keys: keys, legacy: legacy}, nil
+}
+
+// NewLegacySealKeys returns a key set that seals and opens values without
+// key ids under a single key. With a nil key nothing can be sealed or opened.
+func NewLegacySealKeys(key []byte) *SealKeys {
+	return &SealKeys{keys: map[string][]byte{}, legacy: key}
+}
+
+// ParseSealKey decodes a base64 encoded 32 byte key.
+func ParseSealKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%w: expected 32 base64 encoded bytes", ErrInvalidSealKey)
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext under the active key.
+func (k *SealKeys) Seal(plaintext string) (string, error) {
+	if k.activeID == "" {
+		if k.legacy == nil {
+			return "", ErrNoSealKey
+		}
+		return Seal(k.legacy, plaintext)
+	}
+
+	sealed, err := Seal(k.keys[k.activeID], plaintext)
+	if err != nil {
+		return "", err
+	}
+	return k.activeID + ":" + sealed, nil
+}
+
+// Open decrypts a value produced by Seal with any key of the set.
+func (k *SealKeys) Open(sealed string) (string, error) {
+	id, value, ok := strings.Cut(sealed, ":")
+	if !ok {
+		if k.legacy == nil {
+			return "", ErrUnsealFailed
+		}
+		return Open(k.legacy, sealed)
+	}
+
+	key, known := k.keys[id]
+	if !known {
+		return "", ErrUnsealFailed
+	}
+	return Open(key, value)
+}
+
+// Current reports whether sealed was sealed with the active key, i.e.
+// whether it does not need to be sealed again after a rotation.
+func (k *SealKeys) Current(sealed string) bool {
+	id, _, ok := strings.Cut(sealed, ":")
+	if !ok {
+		return k.activeID == ""
+	}
+	return id == k.activeID
+}
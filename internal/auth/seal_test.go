@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	key := testKey(1)
+
+	sealed, err := Seal(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatal("sealed value contains the plaintext")
+	}
+
+	opened, err := Open(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Open() = %q, want the sealed plaintext", opened)
+	}
+
+	again, err := Seal(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same value; the nonce is not random")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	sealed, err := Seal(testKey(1), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawStdEncoding.DecodeString(sealed)
+	raw[len(raw)-1] ^= 1
+	tampered := base64.RawStdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name   string
+		key    []byte
+		sealed string
+	}{
+		{"wrong key", testKey(2), sealed},
+		{"tampered", testKey(1), tampered},
+		{"not base64", testKey(1), "!!!"},
+		{"too short", testKey(1), "AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.key, tt.sealed); !errors.Is(err, ErrUnsealFailed) {
+				t.Errorf("Open() error = %v, want ErrUnsealFailed", err)
+			}
+		})
+	}
+}
+
+func TestSealKeysRotation(t *testing.T) {
+	legacy := testKey(9)
+	legacySealed, err := Seal(legacy, "from before key ids")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := NewSealKeys("1", map[string][]byte{"1": testKey(1)}, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldSealed, err := old.Seal("sealed with key 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(oldSealed, "1:") {
+		t.Errorf("sealed value %q does not name its key", oldSealed)
+	}
+
+	rotated, err := NewSealKeys("2", map[string][]byte{"1": testKey(1), "2": testKey(2)}, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSealed, err := rotated.Seal("sealed with key 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for sealed, want := range map[string]string{
+		legacySealed: "from before key ids",
+		oldSealed:    "sealed with key 1",
+		newSealed:    "sealed with key 2",
+	} {
+		got, err := rotated.Open(sealed)
+		if err != nil {
+			t.Errorf("Open(%q) error: %v", sealed, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Open(%q) = %q, want %q", sealed, got, want)
+		}
+	}
+
+	if rotated.Current(legacySealed) || rotated.Current(oldSealed) {
+		t.Error("value sealed with a retired key reported as current")
+	}
+	if !rotated.Current(newSealed) {
+		t.Error("value sealed with the active key reported as outdated")
+	}
+}
+
+func TestSealKeysUnknownKey(t *testing.T) {
+	keys, err := NewSealKeys("2", map[string][]byte{"2": testKey(2)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := Seal(testKey(1), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"1:" + sealed, sealed} {
+		if _, err := keys.Open(value); !errors.Is(err, ErrUnsealFailed) {
+			t.Errorf("Open(%q) error = %v, want ErrUnsealFailed", value, err)
+		}
+	}
+}
+
+func TestNewSealKeysInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		active string
+		keys   map[string][]byte
+	}{
+		{"missing active key", "2", map[string][]byte{"1": testKey(1)}},
+		{"short key", "1", map[string][]byte{"1": []byte("short")}},
+		{"colon in id", "a:b", map[string][]byte{"a:b": testKey(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSealKeys(tt.active, tt.keys, nil); !errors.Is(err, ErrInvalidSealKey) {
+				t.Errorf("NewSealKeys() error = %v, want ErrInvalidSealKey", err)
+			}
+		})
+	}
+}
+
+func TestParseSealKey(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(3))
+	key, err := ParseSealKey(" " + encoded + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, testKey(3)) {
+		t.Error("ParseSealKey() returned a different key")
+	}
+
+	if _, err := ParseSealKey(base64.StdEncoding.EncodeToString([]byte("too short"))); !errors.Is(err, ErrInvalidSealKey) {
+		t.Errorf("ParseSealKey() error = %v, want ErrInvalidSealKey", err)
+	}
+}
+
+func TestLegacySealKeysWithoutKey(t *testing.T) {
+	keys := NewLegacySealKeys(nil)
+
+	if _, err := keys.Seal("secret"); !errors.Is(err, ErrNoSealKey) {
+		t.Errorf("Seal() error = %v, want ErrNoSealKey", err)
+	}
+	sealed, err := Seal(testKey(1), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Open(sealed); !errors.Is(err, ErrUnsealFailed) {
+		t.Errorf("Open() error = %v, want ErrUnsealFailed", err)
+	}
+}
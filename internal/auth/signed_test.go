@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("link secret")
+	now := time.Now()
+	values := []string{"0b7c5d1e-0000-4000-8000-000000000000", "alice@example.com"}
+	token := Sign(secret, "verify-email", values, now.Add(time.Hour))
+
+	encoded, sig, _ := strings.Cut(token, ".")
+	tampered := Sign(secret, "verify-email", []string{values[0], "mallory@example.com"}, now.Add(time.Hour))
+	tamperedPayload, _, _ := strings.Cut(tampered, ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		purpose string
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", secret, "verify-email", token, now, nil},
+		{"at the expiry", secret, "verify-email", token, now.Add(time.Hour), nil},
+		{"expired", secret, "verify-email", token, now.Add(time.Hour + time.Second), ErrSignatureExpired},
+		{"other purpose", secret, "change-email", token, now, ErrInvalidSignature},
+		{"other secret", []byte("another secret"), "verify-email", token, now, ErrInvalidSignature},
+		{"payload swapped", secret, "verify-email", tamperedPayload + "." + sig, now, ErrInvalidSignature},
+		{"signature missing", secret, "verify-email", encoded, now, ErrInvalidSignature},
+		{"signature not base64", secret, "verify-email", encoded + ".!!", now, ErrInvalidSignature},
+		{"empty", secret, "verify-email", "", now, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Verify(tt.secret, tt.purpose, tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, values) {
+				t.Fatalf("Verify() = %q, want %q", got, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const opaqueTokenBytes = 32
+
+// NewOpaqueToken returns a random URL-safe token together with the hash that
+// should be stored in its place.
+func NewOpaqueToken() (token, hash string, err error) {
+	buf := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generating token err: %w", err)
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a high-entropy token for storage. A fast hash is enough
+// here because the input is random, unlike a password.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PersonalTokenPrefix marks personal access tokens, which tells them apart
+// from JWTs and makes leaked tokens easy to find with secret scanners.
+const PersonalTokenPrefix = "todo_pat_"
+
+// NewPersonalToken returns a new personal access token and its hash.
+func NewPersonalToken() (token, hash string, err error) {
+	raw, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	token = PersonalTokenPrefix + raw
+	return token, HashToken(token), nil
+}
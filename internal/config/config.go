@@ -1,18 +1,34 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"os"
 	"time"
 )
 
 type Config struct {
-	Env         string         `env:"ENV" env-default:"local"`
-	Port        string         `env:"PORT" env-default:"80"`
-	Timeout     time.Duration  `env:"TIMEOUT"`
-	IdleTimeout time.Duration  `env:"IDLE_TIMEOUT"`
-	DbConfig    DatabaseConfig `env-prefix:"DB_"`
-	JWTConfig   JWTConfig
+	Env           string         `env:"ENV" env-default:"local"`
+	Port          string         `env:"PORT" env-default:"80"`
+	Timeout       time.Duration  `env:"TIMEOUT"`
+	IdleTimeout   time.Duration  `env:"IDLE_TIMEOUT"`
+	DbConfig      DatabaseConfig `env-prefix:"DB_"`
+	JWTConfig     JWTConfig
+	TaskConfig    TaskConfig    `env-prefix:"TASK_"`
+	MailConfig    MailConfig    `env-prefix:"MAIL_"`
+	OIDCConfig    OIDCConfig    `env-prefix:"OIDC_"`
+	LoginConfig   LoginConfig   `env-prefix:"LOGIN_"`
+	HashConfig    HashConfig    `env-prefix:"PASSWORD_HASH_"`
+	PolicyConfig  PolicyConfig  `env-prefix:"PASSWORD_POLICY_"`
+	AccountConfig AccountConfig `env-prefix:"ACCOUNT_"`
+	PublicURL     string        `env:"PUBLIC_URL" env-default:"http://localhost"`
+	AdminEmail    string        `env:"ADMIN_EMAIL"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies,
+	// separated by commas. Requests from them are attributed to the client
+	// named in X-Forwarded-For.
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
 }
 
 type DatabaseConfig struct {
@@ -24,8 +40,118 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret   string        `env:"SECRET_KEY"`
-	TokenTTL time.Duration `env:"TOKEN_TTL"`
+	Secret               string        `env:"SECRET_KEY"`
+	TokenTTL             time.Duration `env:"TOKEN_TTL" env-default:"15m"`
+	RefreshTTL           time.Duration `env:"REFRESH_TOKEN_TTL" env-default:"720h"`
+	SessionTouchInterval time.Duration `env:"SESSION_TOUCH_INTERVAL" env-default:"1m"`
+	RevocationSync       time.Duration `env:"REVOCATION_SYNC_INTERVAL" env-default:"30s"`
+	RoleSync             time.Duration `env:"ROLE_SYNC_INTERVAL" env-default:"30s"`
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	PasswordResetResend  time.Duration `env:"PASSWORD_RESET_RESEND_INTERVAL" env-default:"5m"`
+	VerificationTTL      time.Duration `env:"EMAIL_VERIFICATION_TTL" env-default:"48h"`
+	VerificationResend   time.Duration `env:"EMAIL_VERIFICATION_RESEND_INTERVAL" env-default:"5m"`
+	RequireVerifiedLogin bool          `env:"REQUIRE_VERIFIED_LOGIN" env-default:"false"`
+	RequireVerifiedTasks bool          `env:"REQUIRE_VERIFIED_TASKS" env-default:"false"`
+	TOTPIssuer           string        `env:"TOTP_ISSUER" env-default:"Todo"`
+	ChallengeTTL         time.Duration `env:"TWO_FACTOR_CHALLENGE_TTL" env-default:"5m"`
+	TokenIssuer          string        `env:"TOKEN_ISSUER" env-default:"todo"`
+	TokenAudience        string        `env:"TOKEN_AUDIENCE" env-default:"todo-api"`
+
+	// LinkSecret signs the links mailed to users, such as email
+	// verification links. It is kept apart from SECRET_KEY, which is empty
+	// when tokens are signed with SIGNING_KEYS_DIR.
+	LinkSecret string `env:"LINK_SECRET" env-required:"true"`
+
+	// TOTPEncryptionKey is the base64 encoded 32 byte key TOTP secrets are
+	// encrypted with, and TOTPEncryptionKeyID names it. Retired keys are
+	// listed as id:key pairs separated by commas until every secret sealed
+	// with them has been sealed again, which happens on its next use.
+	// Without a key, secrets are encrypted with one derived from SECRET_KEY,
+	// and two-factor enrolment is unavailable if that is empty too.
+	TOTPEncryptionKey     string `env:"TOTP_ENCRYPTION_KEY"`
+	TOTPEncryptionKeyID   string `env:"TOTP_ENCRYPTION_KEY_ID" env-default:"1"`
+	TOTPRetiredEncryption string `env:"TOTP_RETIRED_ENCRYPTION_KEYS"`
+
+	// SigningKeysDir holds PEM keys for asymmetric token signing, named
+	// <kid>.pem. Tokens are signed with the HMAC secret when it is empty.
+	// AcceptHMACTokens keeps tokens signed with the secret valid after
+	// switching; it is meant to be on only for one TOKEN_TTL after the
+	// switch, since anyone holding the secret can mint tokens while it is.
+	SigningKeysDir   string `env:"SIGNING_KEYS_DIR"`
+	SigningKeyID     string `env:"SIGNING_KEY_ID"`
+	AcceptHMACTokens bool   `env:"ACCEPT_HMAC_TOKENS" env-default:"false"`
+}
+
+// LoginConfig throttles failed password logins per account and per client
+// address.
+type LoginConfig struct {
+	MaxAccountFailures int           `env:"MAX_ACCOUNT_FAILURES" env-default:"10"`
+	MaxIPFailures      int           `env:"MAX_IP_FAILURES" env-default:"50"`
+	BackoffAfter       int           `env:"BACKOFF_AFTER" env-default:"3"`
+	BackoffBase        time.Duration `env:"BACKOFF_BASE" env-default:"1s"`
+	BackoffMax         time.Duration `env:"BACKOFF_MAX" env-default:"1m"`
+	LockoutDuration    time.Duration `env:"LOCKOUT_DURATION" env-default:"15m"`
+	FailureWindow      time.Duration `env:"FAILURE_WINDOW" env-default:"15m"`
+}
+
+// HashConfig selects how new passwords are hashed. Stored hashes made with
+// other settings are upgraded when their user logs in.
+type HashConfig struct {
+	Algorithm         string `env:"ALGORITHM" env-default:"argon2id"`
+	Argon2Memory      uint32 `env:"ARGON2_MEMORY_KIB" env-default:"19456"`
+	Argon2Time        uint32 `env:"ARGON2_TIME" env-default:"2"`
+	Argon2Parallelism uint8  `env:"ARGON2_PARALLELISM" env-default:"1"`
+	BcryptCost        int    `env:"BCRYPT_COST" env-default:"10"`
+}
+
+type PolicyConfig struct {
+	MinLength        int  `env:"MIN_LENGTH" env-default:"8"`
+	MaxLength        int  `env:"MAX_LENGTH" env-default:"128"`
+	MinClasses       int  `env:"MIN_CLASSES" env-default:"0"`
+	DisallowIdentity bool `env:"DISALLOW_IDENTITY" env-default:"true"`
+	// BreachedList is a directory of SHA-1 range files or a single sorted
+	// hash file; see auth.BreachedList. The check is off when it is empty.
+	BreachedList string `env:"BREACHED_LIST"`
+}
+
+type AccountConfig struct {
+	DeletionGracePeriod   time.Duration `env:"DELETION_GRACE_PERIOD" env-default:"720h"`
+	DeletionCheckInterval time.Duration `env:"DELETION_CHECK_INTERVAL" env-default:"1h"`
+	ReauthWindow          time.Duration `env:"REAUTH_WINDOW" env-default:"10m"`
+}
+
+type TaskConfig struct {
+	SubtaskPolicy string `env:"SUBTASK_POLICY" env-default:"orphan"`
+	MaxPageSize   int    `env:"MAX_PAGE_SIZE" env-default:"100"`
+}
+
+type MailConfig struct {
+	Driver       string `env:"DRIVER" env-default:"log"`
+	From         string `env:"FROM" env-default:"todo@localhost"`
+	Dir          string `env:"DIR" env-default:"mail"`
+	SMTPHost     string `env:"SMTP_HOST" env-default:"localhost"`
+	SMTPPort     string `env:"SMTP_PORT" env-default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+}
+
+// OIDCConfig lists the OpenID Connect providers users can log in with. The
+// providers are read from a JSON file holding an array of OIDCProvider.
+type OIDCConfig struct {
+	ProvidersFile string `env:"PROVIDERS_FILE"`
+	Providers     []OIDCProvider
+}
+
+type OIDCProvider struct {
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// ClientSecretEnv names an environment variable holding the client
+	// secret, which keeps it out of the providers file.
+	ClientSecretEnv string   `json:"client_secret_env"`
+	RedirectURL     string   `json:"redirect_url"`
+	Scopes          []string `json:"scopes"`
 }
 
 func MustLoad() (*Config, error) {
@@ -34,9 +160,40 @@ func MustLoad() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.JWTConfig.LinkSecret == "" {
+		return nil, errors.New("LINK_SECRET must not be empty")
+	}
+	if err := cfg.OIDCConfig.load(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+func (c *OIDCConfig) load() error {
+	if c.ProvidersFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.ProvidersFile)
+	if err != nil {
+		return fmt.Errorf("reading oidc providers: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.Providers); err != nil {
+		return fmt.Errorf("parsing oidc providers: %w", err)
+	}
+
+	for i, provider := range c.Providers {
+		if provider.Name == "" || provider.Issuer == "" || provider.ClientID == "" {
+			return fmt.Errorf("oidc provider %d: name, issuer and client_id are required", i)
+		}
+		if provider.ClientSecret == "" && provider.ClientSecretEnv != "" {
+			c.Providers[i].ClientSecret = os.Getenv(provider.ClientSecretEnv)
+		}
+	}
+
+	return nil
+}
+
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?parseTime=true",
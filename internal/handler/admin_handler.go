@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/devvdark0/todo/internal/middleware"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	admin *service.AdminService
+	roles *service.RoleService
+	log   *zap.Logger
+}
+
+func NewAdminHandler(admin *service.AdminService, roles *service.RoleService, log *zap.Logger) *AdminHandler {
+	return &AdminHandler{admin: admin, roles: roles, log: log}
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding list users request", zap.String("path", r.URL.Path))
+
+	query := r.URL.Query()
+	var limit, offset int
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.log.Error("failed to parse limit", zap.Error(err))
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if raw := query.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.log.Error("failed to parse offset", zap.Error(err))
+			http.Error(w, "offset must be a number", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	page, err := h.admin.ListUsers(query.Get("q"), limit, offset)
+	if err != nil {
+		h.writeError(w, "failed to list users", err)
+		return
+	}
+
+	h.writeJSON(w, page)
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding get user request", zap.String("path", r.URL.Path))
+
+	user, err := h.admin.GetUser(mux.Vars(r)["user_id"])
+	if err != nil {
+		h.writeError(w, "failed to get user", err)
+		return
+	}
+
+	h.writeJSON(w, user)
+}
+
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding disable user request", zap.String("path", r.URL.Path))
+
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.admin.DisableUser(adminID.String(), mux.Vars(r)["user_id"])
+	if err != nil {
+		h.writeError(w, "failed to disable user", err)
+		return
+	}
+
+	h.writeJSON(w, user)
+}
+
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding enable user request", zap.String("path", r.URL.Path))
+
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.admin.EnableUser(adminID.String(), mux.Vars(r)["user_id"])
+	if err != nil {
+		h.writeError(w, "failed to enable user", err)
+		return
+	}
+
+	h.writeJSON(w, user)
+}
+
+func (h *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding force password reset request", zap.String("path", r.URL.Path))
+
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.admin.ForcePasswordReset(adminID.String(), mux.Vars(r)["user_id"]); err != nil {
+		h.writeError(w, "failed to force password reset", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding set role request", zap.String("path", r.URL.Path))
+
+	adminID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.admin.SetRole(adminID.String(), mux.Vars(r)["user_id"], req)
+	if err != nil {
+		h.writeError(w, "failed to set role", err)
+		return
+	}
+
+	h.writeJSON(w, user)
+}
+
+func (h *AdminHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding list roles request", zap.String("path", r.URL.Path))
+
+	h.writeJSON(w, h.roles.List())
+}
+
+func (h *AdminHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding create role request", zap.String("path", r.URL.Path))
+
+	var req model.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roles.Create(req)
+	if err != nil {
+		h.writeError(w, "failed to create role", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(role); err != nil {
+		h.log.Error("failed to encode data", zap.Error(err))
+	}
+}
+
+func (h *AdminHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding update role request", zap.String("path", r.URL.Path))
+
+	var req model.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roles.Update(mux.Vars(r)["role"], req)
+	if err != nil {
+		h.writeError(w, "failed to update role", err)
+		return
+	}
+
+	h.writeJSON(w, role)
+}
+
+func (h *AdminHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding delete role request", zap.String("path", r.URL.Path))
+
+	if err := h.roles.Delete(mux.Vars(r)["role"]); err != nil {
+		h.writeError(w, "failed to delete role", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	h.log.Error(msg, zap.Error(err))
+
+	switch {
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrRoleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidAdminParam), errors.Is(err, service.ErrInvalidRoleParam):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrSelfAdministration),
+		errors.Is(err, service.ErrOutranked),
+		errors.Is(err, service.ErrBuiltinRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrRoleExists), errors.Is(err, service.ErrRoleInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.log.Error("failed to encode data", zap.Error(err))
+		return
+	}
+}
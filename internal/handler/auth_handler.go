@@ -3,10 +3,15 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/middleware"
 	"github.com/devvdark0/todo/internal/model"
 	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
@@ -32,12 +37,25 @@ func (j *JWTHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := j.JWTService.Register(req.Email, req.Username, req.Password); err != nil {
+	err := j.JWTService.Register(req.Email, req.Username, req.Password)
+	if errors.Is(err, service.ErrVerificationNotSent) {
+		// The account exists at this point, so the client is told it was
+		// created and can ask for the email again.
+		j.log.Warn("failed to send verification email", zap.Error(err))
+		err = nil
+	}
+	if err != nil {
 		if errors.Is(err, service.ErrEmailInUse) {
 			j.log.Error("email in use error", zap.Error(err))
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
+		var policyErr *auth.PolicyError
+		if errors.As(err, &policyErr) {
+			j.log.Error("password policy error", zap.Error(err))
+			writePolicyError(w, policyErr)
+			return
+		}
 
 		j.log.Error("failed to create user error", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -58,20 +76,31 @@ func (j *JWTHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := j.JWTService.Login(req.Email, req.Password)
+	resp, err := j.JWTService.Login(req.Email, req.Password, middleware.ClientInfo(r))
 	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			j.log.Warn("login throttled", zap.String("ip", middleware.ClientInfo(r).IP), zap.Duration("retry_after", throttled.RetryAfter))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			j.log.Error("invalid credentials err", zap.Error(err))
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, service.ErrEmailNotVerified) || errors.Is(err, service.ErrAccountDisabled) {
+			j.log.Error("login refused err", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 
 		j.log.Error("failed to login", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	resp := model.LoginResponse{Token: token}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		j.log.Error("failed to encode data", zap.Error(err))
@@ -79,3 +108,145 @@ func (j *JWTHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// JWKS publishes the public token signing keys so other services can verify
+// access tokens without sharing a secret.
+func (j *JWTHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	j.log.Info("start proceeding jwks request", zap.String("path", r.URL.Path))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(j.JWTService.JWKS()); err != nil {
+		j.log.Error("failed to encode data", zap.Error(err))
+		return
+	}
+}
+
+func (j *JWTHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	j.log.Info("start proceeding refresh token request", zap.String("path", r.URL.Path))
+
+	var req model.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		j.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := j.JWTService.Refresh(req.RefreshToken, middleware.ClientInfo(r))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) ||
+			errors.Is(err, service.ErrExpiredToken) ||
+			errors.Is(err, service.ErrTokenReuse) {
+			j.log.Error("refresh token rejected", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			j.log.Error("refresh refused for disabled account", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		j.log.Error("failed to refresh token", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		j.log.Error("failed to encode data", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	j.log.Info("start proceeding logout request", zap.String("path", r.URL.Path))
+
+	claims, ok := middleware.GetClaims(r)
+	if !ok {
+		j.log.Error("logout without a session")
+		http.Error(w, "only session tokens can log out; delete a personal access token instead", http.StatusBadRequest)
+		return
+	}
+
+	var req model.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			j.log.Error("failed to decode request body", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := j.JWTService.Logout(claims, req.RefreshToken); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			j.log.Error("invalid token claims", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		j.log.Error("failed to logout", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (j *JWTHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	j.log.Info("start proceeding logout everywhere request", zap.String("path", r.URL.Path))
+
+	userID := r.Context().Value("userId").(string)
+
+	if err := j.JWTService.LogoutAll(userID); err != nil {
+		j.log.Error("failed to logout everywhere", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (j *JWTHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start get sessions request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+	claims, _ := middleware.GetClaims(r)
+
+	sessions, err := j.JWTService.ListSessions(userID, claims)
+	if err != nil {
+		j.log.Error("failed to get sessions", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		j.log.Error("failed to encode sessions into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start delete session request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	sessionID := mux.Vars(r)["session_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := j.JWTService.TerminateSession(sessionID, userID); err != nil {
+		j.log.Error("failed to terminate session", zap.Error(err), zap.String("id", sessionID))
+		if errors.Is(err, service.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/middleware"
+	"github.com/devvdark0/todo/internal/oidc"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// oidcStateCookie binds the login state to the browser that started the
+// login, so a callback URL cannot be replayed in someone else's browser.
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	service *service.OIDCService
+	log     *zap.Logger
+}
+
+func NewOIDCHandler(service *service.OIDCService, log *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{service: service, log: log}
+}
+
+func (h *OIDCHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding get oidc providers request", zap.String("path", r.URL.Path))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.service.Providers()); err != nil {
+		h.log.Error("failed to encode response", zap.Error(err))
+		return
+	}
+}
+
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding oidc login request", zap.String("path", r.URL.Path))
+
+	redirectURL, state, err := h.service.BeginLogin(mux.Vars(r)["provider"])
+	if err != nil {
+		h.log.Error("failed to begin oidc login", zap.Error(err))
+		if errors.Is(err, service.ErrUnknownProvider) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding oidc callback request", zap.String("path", r.URL.Path))
+
+	query := r.URL.Query()
+	if idpErr := query.Get("error"); idpErr != "" {
+		h.log.Error("identity provider returned an error", zap.String("error", idpErr), zap.String("description", query.Get("error_description")))
+		http.Error(w, "login was not completed at the identity provider", http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, service.ErrInvalidOIDCState.Error(), http.StatusBadRequest)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	resp, err := h.service.FinishLogin(mux.Vars(r)["provider"], state, query.Get("code"), middleware.ClientInfo(r))
+	if err != nil {
+		h.log.Error("failed to finish oidc login", zap.Error(err))
+		switch {
+		case errors.Is(err, service.ErrUnknownProvider):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrOIDCEmailNotProven),
+			errors.Is(err, service.ErrOIDCAccountUnverified),
+			errors.Is(err, service.ErrAccountDisabled):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrEmailInUse):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, oidc.ErrInvalidIDToken), errors.Is(err, oidc.ErrExchangeFailed):
+			http.Error(w, "login could not be verified", http.StatusUnauthorized)
+		default:
+			http.Error(w, "failed to login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.Error("failed to encode response", zap.Error(err))
+		return
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"go.uber.org/zap"
+)
+
+type PasswordHandler struct {
+	resetService *service.PasswordResetService
+	log          *zap.Logger
+}
+
+func NewPasswordHandler(service *service.PasswordResetService, log *zap.Logger) *PasswordHandler {
+	return &PasswordHandler{resetService: service, log: log}
+}
+
+func (h *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding forgot password request", zap.String("path", r.URL.Path))
+
+	var req model.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.resetService.ForgotPassword(req); err != nil {
+		h.log.Error("failed to start password reset", zap.Error(err))
+		if errors.Is(err, service.ErrInvalidResetParams) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to start password reset", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding reset password request", zap.String("path", r.URL.Path))
+
+	var req model.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.resetService.ResetPassword(req); err != nil {
+		h.log.Error("failed to reset password", zap.Error(err))
+		var policyErr *auth.PolicyError
+		if errors.As(err, &policyErr) {
+			writePolicyError(w, policyErr)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPassword) || errors.Is(err, service.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type policyErrorResponse struct {
+	Error      string                 `json:"error"`
+	Violations []auth.PolicyViolation `json:"violations"`
+}
+
+// writePolicyError reports every password rule that was broken, so clients
+// can show them all at once.
+func writePolicyError(w http.ResponseWriter, policyErr *auth.PolicyError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(policyErrorResponse{
+		Error:      auth.ErrPasswordPolicy.Error(),
+		Violations: policyErr.Violations,
+	})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func (j *JWTHandler) GetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start get personal tokens request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	tokens, err := j.JWTService.ListPersonalTokens(userID)
+	if err != nil {
+		j.log.Error("failed to get personal tokens", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		j.log.Error("failed to encode personal tokens into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start create personal token request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.CreatePersonalTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		j.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := j.JWTService.CreatePersonalToken(userID, req)
+	if err != nil {
+		j.log.Error("failed to create personal token", zap.Error(err))
+		if errors.Is(err, service.ErrInvalidPersonalToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		j.log.Error("failed to encode personal token into json", zap.Error(err))
+		return
+	}
+}
+
+func (j *JWTHandler) DeletePersonalToken(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start delete personal token request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	tokenID := mux.Vars(r)["token_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := j.JWTService.DeletePersonalToken(tokenID, userID); err != nil {
+		j.log.Error("failed to delete personal token", zap.Error(err), zap.String("id", tokenID))
+		if errors.Is(err, service.ErrPersonalTokenNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
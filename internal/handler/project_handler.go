@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type ProjectHandler struct {
+	projectService *service.ProjectService
+	log            *zap.Logger
+}
+
+func NewProjectHandler(service *service.ProjectService, log *zap.Logger) *ProjectHandler {
+	return &ProjectHandler{projectService: service, log: log}
+}
+
+func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get projects request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+	includeArchived := r.URL.Query().Get("archived") == "true"
+
+	projects, err := h.projectService.ListProjects(userID, includeArchived)
+	if err != nil {
+		h.log.Error("failed to get projects", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(projects); err != nil {
+		h.log.Error("failed to encode projects into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get project request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	projectID := mux.Vars(r)["project_id"]
+	userID := r.Context().Value("userId").(string)
+
+	project, err := h.projectService.GetProject(projectID, userID)
+	if err != nil {
+		h.log.Error("failed to get project", zap.Error(err), zap.String("id", projectID))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		h.log.Error("failed to encode project into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start create project request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	project, err := h.projectService.CreateProject(userID, req)
+	if err != nil {
+		h.log.Error("failed to create project", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		h.log.Error("failed to encode project into json", zap.Error(err))
+		return
+	}
+}
+
+func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start update project request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	projectID := mux.Vars(r)["project_id"]
+	userID := r.Context().Value("userId").(string)
+
+	var req model.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.projectService.UpdateProject(projectID, userID, req); err != nil {
+		h.log.Error("failed to update project", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start delete project request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	projectID := mux.Vars(r)["project_id"]
+	userID := r.Context().Value("userId").(string)
+	mode := r.URL.Query().Get("tasks")
+
+	if err := h.projectService.DeleteProject(projectID, userID, mode); err != nil {
+		h.log.Error("failed to delete project", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProjectHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidProject):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/devvdark0/todo/internal/service"
+	"go.uber.org/zap"
+)
+
+type SearchHandler struct {
+	searchService *service.SearchService
+	log           *zap.Logger
+}
+
+func NewSearchHandler(service *service.SearchService, log *zap.Logger) *SearchHandler {
+	return &SearchHandler{searchService: service, log: log}
+}
+
+func (h *SearchHandler) SearchTasks(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start search tasks request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	query := r.URL.Query()
+	var limit int
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.log.Error("failed to parse limit", zap.Error(err))
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.searchService.SearchTasks(userID, query.Get("q"), limit)
+	if err != nil {
+		h.log.Error("failed to search tasks", zap.Error(err))
+		if errors.Is(err, service.ErrInvalidSearch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		h.log.Error("failed to encode search results into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
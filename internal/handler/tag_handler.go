@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type TagHandler struct {
+	tagService *service.TagService
+	log        *zap.Logger
+}
+
+func NewTagHandler(service *service.TagService, log *zap.Logger) *TagHandler {
+	return &TagHandler{tagService: service, log: log}
+}
+
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get tags request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	tags, err := h.tagService.ListTags(userID)
+	if err != nil {
+		h.log.Error("failed to get tags", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		h.log.Error("failed to encode tags into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TagHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get tag request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	tagID := mux.Vars(r)["tag_id"]
+	userID := r.Context().Value("userId").(string)
+
+	tag, err := h.tagService.GetTag(tagID, userID)
+	if err != nil {
+		h.log.Error("failed to get tag", zap.Error(err), zap.String("id", tagID))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		h.log.Error("failed to encode tag into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start create tag request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.tagService.CreateTag(userID, req)
+	if err != nil {
+		h.log.Error("failed to create tag", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		h.log.Error("failed to encode tag into json", zap.Error(err))
+		return
+	}
+}
+
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start update tag request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	tagID := mux.Vars(r)["tag_id"]
+	userID := r.Context().Value("userId").(string)
+
+	var req model.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tagService.UpdateTag(tagID, userID, req); err != nil {
+		h.log.Error("failed to update tag", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start delete tag request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	tagID := mux.Vars(r)["tag_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := h.tagService.DeleteTag(tagID, userID); err != nil {
+		h.log.Error("failed to delete tag", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TagHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTag):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTagExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
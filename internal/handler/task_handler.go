@@ -2,7 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -29,14 +33,26 @@ func (h *TodoHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 
 	userID := r.Context().Value("userId").(string)
 
-	tasks, err := h.todoService.ListTasks(userID)
+	req, err := parseListTasksRequest(r.URL.Query())
+	if err != nil {
+		h.log.Error("failed to parse query", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.todoService.ListTasks(userID, req)
 	if err != nil {
 		h.log.Error("failed to get tasks", zap.Error(err))
+		if isValidationErr(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		h.log.Error("failed to encode tasks into json", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -52,7 +68,15 @@ func (h *TodoHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	taskID := mux.Vars(r)["task_id"]
 	userID := r.Context().Value("userId").(string)
 
-	task, err := h.todoService.GetTaskByID(taskID, userID)
+	var (
+		task any
+		err  error
+	)
+	if r.URL.Query().Get("subtree") == "true" {
+		task, err = h.todoService.GetTaskTree(taskID, userID)
+	} else {
+		task, err = h.todoService.GetTaskByID(taskID, userID)
+	}
 	if err != nil {
 		h.log.Error("failed to get task with such id", zap.Error(err), zap.String("id", taskID))
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -90,6 +114,10 @@ func (h *TodoHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.todoService.CreateTask(req); err != nil {
 		h.log.Error("failed to create user", zap.Error(err))
+		if isValidationErr(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -119,7 +147,16 @@ func (h *TodoHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.todoService.UpdateTask(taskID, userID, req); err != nil {
 		h.log.Error("failed to update task", zap.Error(err))
+		if isValidationErr(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrOpenSubtasks) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusNotFound)
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
@@ -140,8 +177,146 @@ func (h *TodoHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.todoService.DeleteTask(taskID, userID); err != nil {
 		h.log.Error("failed to delete task", zap.Error(err))
+		if errors.Is(err, service.ErrHasSubtasks) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func isValidationErr(err error) bool {
+	return errors.Is(err, service.ErrInvalidTask) ||
+		errors.Is(err, service.ErrInvalidFilter) ||
+		errors.Is(err, service.ErrInvalidDueDate) ||
+		errors.Is(err, service.ErrInvalidTag) ||
+		errors.Is(err, service.ErrInvalidProject) ||
+		errors.Is(err, service.ErrInvalidQuery)
+}
+
+func (h *TodoHandler) GetOccurrences(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get occurrences request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	taskID := mux.Vars(r)["task_id"]
+	userID := r.Context().Value("userId").(string)
+
+	count := 5
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.log.Error("failed to parse count", zap.Error(err))
+			http.Error(w, "count must be a number", http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+
+	occurrences, err := h.todoService.PreviewOccurrences(taskID, userID, count)
+	if err != nil {
+		h.log.Error("failed to preview occurrences", zap.Error(err))
+		h.writeRecurrenceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(model.OccurrencesResponse{Occurrences: occurrences}); err != nil {
+		h.log.Error("failed to encode occurrences into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TodoHandler) SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start skip occurrence request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	taskID := mux.Vars(r)["task_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := h.todoService.SkipOccurrence(taskID, userID); err != nil {
+		h.log.Error("failed to skip occurrence", zap.Error(err))
+		h.writeRecurrenceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TodoHandler) EndSeries(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start end series request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	taskID := mux.Vars(r)["task_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := h.todoService.EndSeries(taskID, userID); err != nil {
+		h.log.Error("failed to end series", zap.Error(err))
+		h.writeRecurrenceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TodoHandler) writeRecurrenceError(w http.ResponseWriter, err error) {
+	switch {
+	case isValidationErr(err), errors.Is(err, service.ErrNotRecurring):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrSeriesEnded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+func parseListTasksRequest(query url.Values) (model.ListTasksRequest, error) {
+	req := model.ListTasksRequest{
+		Due:             query.Get("due"),
+		Timezone:        query.Get("tz"),
+		Sort:            query.Get("sort"),
+		Order:           query.Get("order"),
+		TagMatch:        query.Get("tag_match"),
+		ProjectID:       query.Get("project"),
+		CreatedAfter:    query.Get("created_after"),
+		CreatedBefore:   query.Get("created_before"),
+		UpdatedAfter:    query.Get("updated_after"),
+		UpdatedBefore:   query.Get("updated_before"),
+		CompletedAfter:  query.Get("completed_after"),
+		CompletedBefore: query.Get("completed_before"),
+		Cursor:          query.Get("cursor"),
+		Query:           query.Get("q"),
+	}
+	if tags := query.Get("tags"); tags != "" {
+		req.Tags = strings.Split(tags, ",")
+	}
+	if within := query.Get("due_within"); within != "" {
+		days, err := strconv.Atoi(within)
+		if err != nil {
+			return req, errors.New("due_within must be a number of days")
+		}
+		req.DueWithin = days
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return req, errors.New("limit must be a number")
+		}
+		req.Limit = n
+	}
+	if done := query.Get("is_done"); done != "" {
+		isDone, err := strconv.ParseBool(done)
+		if err != nil {
+			return req, errors.New("is_done must be true or false")
+		}
+		req.IsDone = &isDone
+	}
+
+	return req, nil
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/devvdark0/todo/internal/middleware"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"go.uber.org/zap"
+)
+
+func (j *JWTHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	j.log.Info("start proceeding two-factor login request", zap.String("path", r.URL.Path))
+
+	var req model.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		j.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := j.JWTService.CompleteTwoFactorLogin(req, middleware.ClientInfo(r))
+	if err != nil {
+		j.log.Error("failed to complete two-factor login", zap.Error(err))
+		j.writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		j.log.Error("failed to encode data", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start enroll totp request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	resp, err := j.JWTService.EnrollTOTP(userID)
+	if err != nil {
+		j.log.Error("failed to enroll totp", zap.Error(err))
+		j.writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		j.log.Error("failed to encode data", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start confirm totp request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		j.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := j.JWTService.ConfirmTOTP(userID, req)
+	if err != nil {
+		j.log.Error("failed to confirm totp", zap.Error(err))
+		j.writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		j.log.Error("failed to encode data", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (j *JWTHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	j.log.Info(
+		"start disable totp request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.DisableTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		j.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := j.JWTService.DisableTOTP(userID, req); err != nil {
+		j.log.Error("failed to disable totp", zap.Error(err))
+		j.writeTwoFactorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (j *JWTHandler) writeTwoFactorError(w http.ResponseWriter, err error) {
+	var throttled *service.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrInvalidTwoFactorReq):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidToken),
+		errors.Is(err, service.ErrExpiredToken),
+		errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrAccountDisabled):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrTwoFactorEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrTwoFactorUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
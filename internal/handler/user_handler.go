@@ -2,41 +2,229 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/devvdark0/todo/internal/auth"
 	"github.com/devvdark0/todo/internal/middleware"
+	"github.com/devvdark0/todo/internal/model"
 	"github.com/devvdark0/todo/internal/service"
 	"go.uber.org/zap"
 )
 
 type UserHandler struct {
-	userStore service.UserStorage
-	log       *zap.Logger
+	profiles *service.ProfileService
+	accounts *service.AccountService
+	log      *zap.Logger
 }
 
-func NewUserHandler(store service.UserStorage) *UserHandler {
-	return &UserHandler{userStore: store}
+func NewUserHandler(profiles *service.ProfileService, accounts *service.AccountService, log *zap.Logger) *UserHandler {
+	return &UserHandler{profiles: profiles, accounts: accounts, log: log}
 }
 
 func (u *UserHandler) Profile(w http.ResponseWriter, r *http.Request) {
-	userId, ok := middleware.GetUserID(r)
+	u.log.Info("start proceeding get profile request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := u.profiles.GetProfile(userID.String())
+	if err != nil {
+		u.writeError(w, "failed to get profile", err)
+		return
+	}
+
+	u.writeJSON(w, profile)
+}
+
+func (u *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding update profile request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	user, err := u.userStore.GetByID(userId)
+	var req model.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		u.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := u.profiles.UpdateProfile(userID.String(), req)
 	if err != nil {
-		u.log.Error("failed to get user", zap.Error(err))
-		http.Error(w, "Not Found", http.StatusNotFound)
+		u.writeError(w, "failed to update profile", err)
+		return
+	}
+
+	u.writeJSON(w, profile)
+}
+
+func (u *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding change password request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	claims, _ := middleware.GetClaims(r)
 
+	var req model.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		u.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := u.profiles.ChangePassword(userID.String(), claims, req); err != nil {
+		u.writeError(w, "failed to change password", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u *UserHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding change email request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		u.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := u.profiles.RequestEmailChange(userID.String(), req); err != nil {
+		u.writeError(w, "failed to request email change", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (u *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding confirm email change request", zap.String("path", r.URL.Path))
+
+	if err := u.profiles.ConfirmEmailChange(r.URL.Query().Get("token")); err != nil {
+		u.writeError(w, "failed to confirm email change", err)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(user); err != nil {
+}
+
+// ExportData streams a ZIP archive of everything stored about the user.
+func (u *UserHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding export data request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := u.accounts.Export(userID.String())
+	if err != nil {
+		u.writeError(w, "failed to export data", err)
+		return
+	}
+
+	filename := "todo-export-" + export.ExportedAt.Format("20060102") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := export.WriteArchive(w); err != nil {
+		u.log.Error("failed to write export archive", zap.Error(err))
+		return
+	}
+}
+
+func (u *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding delete account request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		u.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims, _ := middleware.GetClaims(r)
+	resp, err := u.accounts.ScheduleDeletion(userID.String(), claims, req)
+	if err != nil {
+		u.writeError(w, "failed to schedule account deletion", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		u.log.Error("failed to encode data", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (u *UserHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	u.log.Info("start proceeding cancel account deletion request", zap.String("path", r.URL.Path))
+
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	if err := u.accounts.CancelDeletion(userID.String()); err != nil {
+		u.writeError(w, "failed to cancel account deletion", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u *UserHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	u.log.Error(msg, zap.Error(err))
+
+	var policyErr *auth.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		writePolicyError(w, policyErr)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, "current password is incorrect", http.StatusForbidden)
+	case errors.Is(err, service.ErrReauthRequired):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrEmailInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidProfileParam),
+		errors.Is(err, service.ErrInvalidEmailChange),
+		errors.Is(err, service.ErrInvalidDeletionParam):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNoPendingDeletion):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func (u *UserHandler) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		u.log.Error("failed to encode data", zap.Error(err))
+		return
+	}
 }
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"go.uber.org/zap"
+)
+
+type VerificationHandler struct {
+	verifier *service.EmailVerifier
+	log      *zap.Logger
+}
+
+func NewVerificationHandler(verifier *service.EmailVerifier, log *zap.Logger) *VerificationHandler {
+	return &VerificationHandler{verifier: verifier, log: log}
+}
+
+func (h *VerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding verify email request", zap.String("path", r.URL.Path))
+
+	if err := h.verifier.Verify(r.URL.Query().Get("token")); err != nil {
+		h.log.Error("failed to verify email", zap.Error(err))
+		if errors.Is(err, service.ErrInvalidVerification) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *VerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	h.log.Info("start proceeding resend verification request", zap.String("path", r.URL.Path))
+
+	var req model.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verifier.Resend(req); err != nil {
+		h.log.Error("failed to resend verification", zap.Error(err))
+		if errors.Is(err, service.ErrInvalidVerificationParam) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to resend verification", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/service"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type ViewHandler struct {
+	viewService *service.ViewService
+	log         *zap.Logger
+}
+
+func NewViewHandler(service *service.ViewService, log *zap.Logger) *ViewHandler {
+	return &ViewHandler{viewService: service, log: log}
+}
+
+func (h *ViewHandler) GetViews(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get views request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	views, err := h.viewService.ListViews(userID)
+	if err != nil {
+		h.log.Error("failed to get views", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(views); err != nil {
+		h.log.Error("failed to encode views into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ViewHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get view request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	viewID := mux.Vars(r)["view_id"]
+	userID := r.Context().Value("userId").(string)
+
+	view, err := h.viewService.GetView(viewID, userID)
+	if err != nil {
+		h.log.Error("failed to get view", zap.Error(err), zap.String("id", viewID))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		h.log.Error("failed to encode view into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ViewHandler) CreateView(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start create view request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	userID := r.Context().Value("userId").(string)
+
+	var req model.CreateViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.viewService.CreateView(userID, req)
+	if err != nil {
+		h.log.Error("failed to create view", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		h.log.Error("failed to encode view into json", zap.Error(err))
+		return
+	}
+}
+
+func (h *ViewHandler) UpdateView(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start update view request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	viewID := mux.Vars(r)["view_id"]
+	userID := r.Context().Value("userId").(string)
+
+	var req model.UpdateViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.viewService.UpdateView(viewID, userID, req); err != nil {
+		h.log.Error("failed to update view", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ViewHandler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start delete view request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	viewID := mux.Vars(r)["view_id"]
+	userID := r.Context().Value("userId").(string)
+
+	if err := h.viewService.DeleteView(viewID, userID); err != nil {
+		h.log.Error("failed to delete view", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ViewHandler) GetViewTasks(w http.ResponseWriter, r *http.Request) {
+	h.log.Info(
+		"start get view tasks request",
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	)
+	viewID := mux.Vars(r)["view_id"]
+	userID := r.Context().Value("userId").(string)
+
+	page, err := parseListTasksRequest(r.URL.Query())
+	if err != nil {
+		h.log.Error("failed to parse query", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.viewService.ViewTasks(viewID, userID, page)
+	if err != nil {
+		h.log.Error("failed to get view tasks", zap.Error(err), zap.String("id", viewID))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		h.log.Error("failed to encode tasks into json", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *ViewHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidView), isValidationErr(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrViewNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type LogSender struct {
+	log *zap.Logger
+}
+
+func NewLogSender(log *zap.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+func (s *LogSender) Send(msg Message) error {
+	s.log.Info(
+		"mail",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// FileSender writes every message as an .eml file into a directory, which
+// lets local mail clients open what would have been sent.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mail dir creation err: %w", err)
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (s *FileSender) Send(msg Message) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(s.dir, name), compose(s.from, msg), 0o600); err != nil {
+		return fmt.Errorf("mail write err: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"fmt"
+
+	"github.com/devvdark0/todo/internal/config"
+	"go.uber.org/zap"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a plain text message.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSender builds the sender selected by cfg.Driver: "log" writes messages
+// to the application log, "file" stores them in cfg.Dir and "smtp" delivers
+// them through cfg.SMTPHost.
+func NewSender(cfg config.MailConfig, log *zap.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case "log":
+		return NewLogSender(log), nil
+	case "file":
+		return NewFileSender(cfg.Dir, cfg.From)
+	case "smtp":
+		return NewSMTPSender(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+type asyncSender struct {
+	sender Sender
+	log    *zap.Logger
+}
+
+// NewAsyncSender sends messages in the background so that callers do not
+// wait on, or leak the timing of, the delivery. Failures are logged.
+func NewAsyncSender(sender Sender, log *zap.Logger) Sender {
+	return &asyncSender{sender: sender, log: log}
+}
+
+func (s *asyncSender) Send(msg Message) error {
+	go func() {
+		if err := s.sender.Send(msg); err != nil {
+			s.log.Error("failed to send mail", zap.Error(err), zap.String("subject", msg.Subject))
+		}
+	}()
+	return nil
+}
@@ -2,14 +2,24 @@ package middleware
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
 
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
 	"github.com/devvdark0/todo/internal/service"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
-func AuthMiddleware(authService service.JWTService) func(http.Handler) http.Handler {
+// AuthMiddleware accepts access tokens and personal access tokens. Both set
+// the user id and scopes in the request context; only access tokens carry
+// claims, which GetClaims returns.
+func AuthMiddleware(authService service.JWTService, log *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -26,6 +36,20 @@ func AuthMiddleware(authService service.JWTService) func(http.Handler) http.Hand
 
 			tokenString := parts[1]
 
+			if strings.HasPrefix(tokenString, auth.PersonalTokenPrefix) {
+				token, err := authService.AuthenticatePersonalToken(tokenString)
+				if err != nil {
+					http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), "userId", token.UserID.String())
+				ctx = context.WithValue(ctx, "scopes", token.Scopes)
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			claims, err := authService.ValidateToken(tokenString)
 			if err != nil {
 				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
@@ -44,7 +68,15 @@ func AuthMiddleware(authService service.JWTService) func(http.Handler) http.Hand
 				return
 			}
 
+			// A failed touch only leaves the session's last-seen info stale,
+			// so the request goes ahead.
+			if err := authService.TouchSession(claims, ClientInfo(r)); err != nil {
+				log.Warn("failed to touch session", zap.String("path", r.URL.Path), zap.Error(err))
+			}
+
 			ctx := context.WithValue(r.Context(), "userId", userId.String())
+			ctx = context.WithValue(ctx, "claims", claims)
+			ctx = context.WithValue(ctx, "scopes", model.SessionScopes)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -52,7 +84,131 @@ func AuthMiddleware(authService service.JWTService) func(http.Handler) http.Hand
 
 }
 
+// RequireScope rejects requests whose credentials were not granted scope.
+// It must run after AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, _ := r.Context().Value("scopes").([]string)
+			if !slices.Contains(scopes, scope) {
+				http.Error(w, "token is missing the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission rejects requests whose user's role does not grant
+// permission. The role is read from the access token, so personal access
+// tokens never pass. It must run after AuthMiddleware.
+func RequirePermission(roles *service.RoleService, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := GetClaims(r)
+			role, _ := claims["role"].(string)
+			if !roles.HasPermission(role, permission) {
+				http.Error(w, "missing the "+permission+" permission", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail rejects requests from users who have not verified
+// their email address yet. It must run after AuthMiddleware.
+func RequireVerifiedEmail(verifier *service.EmailVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value("userId").(string)
+
+			verified, err := verifier.IsVerified(userID)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if !verified {
+				http.Error(w, service.ErrEmailNotVerified.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientInfo describes the client of r. The address is taken from the
+// connection, so behind a proxy it is the proxy's address unless RealIP
+// replaced it.
+func ClientInfo(r *http.Request) model.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return model.ClientInfo{IP: ip, UserAgent: r.UserAgent()}
+}
+
+// GetUserID returns the id AuthMiddleware stored for the authenticated user.
 func GetUserID(r *http.Request) (uuid.UUID, bool) {
-	userID, ok := r.Context().Value("userId").(uuid.UUID)
-	return userID, ok
+	raw, ok := r.Context().Value("userId").(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// GetClaims returns the access token claims AuthMiddleware stored. There are
+// none for requests made with a personal access token.
+func GetClaims(r *http.Request) (jwt.MapClaims, bool) {
+	claims, ok := r.Context().Value("claims").(jwt.MapClaims)
+	return claims, ok
+}
+
+// RealIP replaces the remote address of requests that come through one of
+// the trusted proxies with the client address they forwarded. The
+// X-Forwarded-For header is read from the right, skipping trusted proxies,
+// so a client cannot pick its address by sending the header itself.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(raw string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(raw))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		return slices.ContainsFunc(trusted, func(prefix netip.Prefix) bool {
+			return prefix.Contains(addr)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil || !isTrusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := strings.TrimSpace(hops[i])
+				if hop == "" || isTrusted(hop) {
+					continue
+				}
+				if addr, err := netip.ParseAddr(hop); err == nil {
+					r.RemoteAddr = addr.Unmap().String()
+				}
+				break
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditLoginLockout     = "login_lockout"
+	AuditDeletionRequest  = "account_deletion_scheduled"
+	AuditDeletionCanceled = "account_deletion_canceled"
+	AuditAccountDeleted   = "account_deleted"
+	AuditAccountDisabled  = "account_disabled"
+	AuditAccountEnabled   = "account_enabled"
+	AuditPasswordReset    = "password_reset_forced"
+	AuditRoleChanged      = "role_changed"
+)
+
+// AuditEntry records a security relevant event. Subject names what the event
+// is about, such as an email address or IP address; UserID is set when the
+// event can be tied to a user.
+type AuditEntry struct {
+	ID        uuid.UUID
+	UserID    *uuid.UUID
+	Event     string
+	Subject   string
+	IP        string
+	Detail    string
+	CreatedAt time.Time
+}
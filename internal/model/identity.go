@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExternalIdentity links a user to an account at an OpenID Connect provider,
+// identified by the provider's subject.
+type ExternalIdentity struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
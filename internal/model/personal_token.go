@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeProfileRead = "profile:read"
+	// ScopeAccount covers managing the account itself: sessions, two-factor
+	// authentication and access tokens. Only interactive logins have it; it
+	// can never be granted to a personal access token.
+	ScopeAccount = "account"
+)
+
+// SessionScopes are the scopes of a token obtained by logging in.
+var SessionScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeProfileRead, ScopeAccount}
+
+// PersonalAccessToken lets scripts call the API as a user with a limited set
+// of scopes. Only the hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type CreatePersonalTokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=tasks:read tasks:write profile:read"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type PersonalTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Token is only set in the response to creating the token.
+	Token string `json:"token,omitempty"`
+}
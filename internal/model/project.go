@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Project struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	Description string
+	Color       string
+	Archived    bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CreateProjectRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description"`
+	Color       string `json:"color" validate:"omitempty,hexcolor"`
+}
+
+type UpdateProjectRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=255"`
+	Description *string `json:"description"`
+	Color       *string `json:"color" validate:"omitempty,hexcolor"`
+	Archived    *bool   `json:"archived"`
+}
+
+const (
+	ProjectDeleteMoveToInbox = "inbox"
+	ProjectDeleteTasks       = "delete"
+)
+
+// InboxProject is accepted wherever a project id is expected and refers to
+// tasks that do not belong to any project.
+const InboxProject = "inbox"
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	PermissionUsersRead   = "users:read"
+	PermissionUsersManage = "users:manage"
+	PermissionRolesManage = "roles:manage"
+)
+
+// Permissions lists every permission a role can grant. The admin role
+// always has all of them. Since roles:manage allows handing out any role,
+// it should only be granted to fully trusted users.
+var Permissions = []string{PermissionUsersRead, PermissionUsersManage, PermissionRolesManage}
+
+// Role is a named set of permissions. The built-in user and admin roles
+// cannot be changed or removed.
+type Role struct {
+	Name        string
+	Permissions []string
+	Builtin     bool
+	CreatedAt   time.Time
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=64,lowercase,excludesall=0x2C0x20"`
+	Permissions []string `json:"permissions" validate:"dive,oneof=users:read users:manage roles:manage"`
+}
+
+type UpdateRoleRequest struct {
+	Permissions []string `json:"permissions" validate:"dive,oneof=users:read users:manage roles:manage"`
+}
+
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type TaskCounts struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+}
+
+// AdminUser is a user as administrators see them.
+type AdminUser struct {
+	User
+	Tasks TaskCounts
+}
+
+type AdminUserResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	EmailVerified       bool       `json:"email_verified"`
+	Username            string     `json:"username"`
+	Role                string     `json:"role"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	Tasks               TaskCounts `json:"tasks"`
+}
+
+type AdminUserPage struct {
+	Users  []AdminUserResponse `json:"users"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
@@ -0,0 +1,8 @@
+package model
+
+type SearchResult struct {
+	Task           Task    `json:"task"`
+	Score          float64 `json:"score"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
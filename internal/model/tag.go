@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Tag struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" validate:"required,max=64,excludesall=0x2C"`
+}
+
+type UpdateTagRequest struct {
+	Name string `json:"name" validate:"required,max=64,excludesall=0x2C"`
+}
@@ -11,20 +11,207 @@ type Task struct {
 	Title       string
 	Description string
 	IsDone      bool
+	Priority    Priority
 	UserId      uuid.UUID
+	ParentID    *uuid.UUID
+	ProjectID   *uuid.UUID
+	DueAt       *time.Time
+	DueAllDay   bool
+	DueTimezone string
+	Recurrence  string
+	Occurrence  int
+	Tags        []Tag
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// TaskCompletion is what changes together with a task being marked done:
+// the open subtasks the cascade policy closes and the next task of a
+// recurring series.
+type TaskCompletion struct {
+	Subtasks []uuid.UUID
+	Next     *Task
 }
 
 type CreateTaskRequest struct {
-	Title       string `json:"title" validate:"required,max=255"`
-	Description string `json:"description"`
-	IsDone      bool   `json:"is_done"`
+	Title       string   `json:"title" validate:"required,max=255"`
+	Description string   `json:"description"`
+	IsDone      bool     `json:"is_done"`
+	Priority    Priority `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	DueDate     string   `json:"due_date"`
+	DueTimezone string   `json:"due_timezone" validate:"omitempty,timezone"`
+	TagIDs      []string `json:"tag_ids" validate:"omitempty,dive,uuid"`
+	ParentID    string   `json:"parent_id" validate:"omitempty,uuid"`
+	ProjectID   string   `json:"project_id" validate:"omitempty,uuid|eq=inbox"`
+	Recurrence  string   `json:"recurrence"`
 	UserID      string
 }
 
 type UpdateTaskRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	IsDone      *bool   `json:"is_done"`
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	IsDone      *bool     `json:"is_done"`
+	Priority    *Priority `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	DueDate     *string   `json:"due_date"`
+	DueTimezone *string   `json:"due_timezone" validate:"omitempty,timezone"`
+	TagIDs      *[]string `json:"tag_ids" validate:"omitempty,dive,uuid"`
+	ParentID    *string   `json:"parent_id"`
+	ProjectID   *string   `json:"project_id"`
+	Recurrence  *string   `json:"recurrence"`
+}
+
+type OccurrencesResponse struct {
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+type TaskTree struct {
+	Task
+	Subtasks []TaskTree
+}
+
+type Priority string
+
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+var priorityRanks = map[Priority]int{
+	PriorityNone:   0,
+	PriorityLow:    1,
+	PriorityMedium: 2,
+	PriorityHigh:   3,
+	PriorityUrgent: 4,
+}
+
+func (p Priority) Rank() int {
+	return priorityRanks[p]
+}
+
+func PriorityFromRank(rank int) Priority {
+	for p, r := range priorityRanks {
+		if r == rank {
+			return p
+		}
+	}
+	return PriorityNone
+}
+
+const (
+	SortPriority  = "priority"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortTitle     = "title"
+	SortIsDone    = "is_done"
+)
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+const (
+	DueOverdue = "overdue"
+	DueToday   = "today"
+)
+
+type ListTasksRequest struct {
+	Due       string `validate:"omitempty,oneof=overdue today"`
+	DueWithin int    `validate:"min=0"`
+	Timezone  string `validate:"omitempty,timezone"`
+	Sort      string `validate:"omitempty,oneof=priority created_at updated_at title is_done"`
+	Order     string `validate:"omitempty,oneof=asc desc"`
+	Tags      []string
+	TagMatch  string `validate:"omitempty,oneof=any all"`
+	ProjectID string
+	IsDone    *bool
+
+	CreatedAfter  string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedAfter  string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedBefore string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+
+	CompletedAfter  string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CompletedBefore string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+
+	Limit  int `validate:"min=0"`
+	Cursor string
+	Query  string `validate:"max=1024"`
+}
+
+type TaskFilter struct {
+	DueFrom  *time.Time
+	DueTo    *time.Time
+	IsDone   *bool
+	Tags     []string
+	TagMatch string
+	// ProjectID restricts the list to one project; uuid.Nil selects the
+	// inbox, i.e. tasks without a project.
+	ProjectID *uuid.UUID
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+
+	CompletedFrom *time.Time
+	CompletedTo   *time.Time
+
+	// Condition is an additional parameterised SQL condition, compiled from a
+	// task query, with its arguments in ConditionArgs.
+	Condition     string
+	ConditionArgs []any
+
+	Sort  string
+	Order string
+	Limit int
+	// After continues a keyset-paginated listing from the given position.
+	After *TaskCursor
+}
+
+type TaskCursor struct {
+	Sort     string    `json:"s"`
+	Desc     bool      `json:"d,omitempty"`
+	Values   []any     `json:"v"`
+	ID       uuid.UUID `json:"id"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+type PageInfo struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type TaskPage struct {
+	Tasks []Task   `json:"tasks"`
+	Page  PageInfo `json:"page"`
+}
+
+// TaskExport is a task as it appears in a data export.
+type TaskExport struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	IsDone      bool       `json:"is_done"`
+	Priority    Priority   `json:"priority"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	DueAllDay   bool       `json:"due_all_day"`
+	DueTimezone string     `json:"due_timezone,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Tags        []string   `json:"tags"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestPriorityRanks(t *testing.T) {
+	ordered := []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+	for i, p := range ordered {
+		if got := p.Rank(); got != i {
+			t.Fatalf("%s.Rank() = %d, want %d", p, got, i)
+		}
+		if got := PriorityFromRank(i); got != p {
+			t.Fatalf("PriorityFromRank(%d) = %s, want %s", i, got, p)
+		}
+	}
+
+	// Tasks stored without a priority, or with a rank from a newer
+	// version, read back as none.
+	if got := Priority("").Rank(); got != 0 {
+		t.Fatalf("empty priority rank = %d, want 0", got)
+	}
+	if got := PriorityFromRank(9); got != PriorityNone {
+		t.Fatalf("PriorityFromRank(9) = %s, want %s", got, PriorityNone)
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a single link in a rotation chain. Every refresh token
+// issued from the same login shares a FamilyID, which lets a replayed token
+// revoke the whole chain.
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenRevocation invalidates access tokens before they expire. With a JTI
+// it targets a single token and with a SessionID every token of that session;
+// with neither it covers every token the user was issued before RevokedAt.
+type TokenRevocation struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	JTI       string
+	SessionID *uuid.UUID
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// PasswordResetToken lets the holder set a new password once, until it
+// expires.
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTP is a user's authenticator enrolment. Secret is stored sealed and is
+// only in effect once ConfirmedAt is set. FailedAttempts counts codes tried
+// since the last correct one; too many lock the second factor until
+// LockedUntil.
+type TOTP struct {
+	UserID         uuid.UUID
+	Secret         string
+	ConfirmedAt    *time.Time
+	LastStep       int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+	CreatedAt      time.Time
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code"`
+}
@@ -1,12 +1,24 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
-	ID       uuid.UUID
-	Username string
-	Email    string
-	Password string
+	ID                  uuid.UUID
+	Username            string
+	Email               string
+	Password            string
+	EmailVerified       bool
+	VerificationSentAt  *time.Time
+	DisplayName         string
+	Timezone            string
+	Locale              string
+	DeletionScheduledAt *time.Time
+	Role                string
+	DisabledAt          *time.Time
 }
 
 type RegisterRequest struct {
@@ -20,8 +32,18 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// LoginResponse carries either a token pair or, for accounts with two-factor
+// authentication, a challenge token to be exchanged at /api/login/2fa.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token             string `json:"token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	ExpiresIn         int64  `json:"expires_in,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
 }
 
 type UserResponse struct {
@@ -29,3 +51,46 @@ type UserResponse struct {
 	Username string `json:"username"`
 	Task Task `json:"task"`
 }
+
+// ProfileResponse is what a user sees of their own account.
+type ProfileResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	EmailVerified       bool       `json:"email_verified"`
+	Username            string     `json:"username"`
+	DisplayName         string     `json:"display_name"`
+	Timezone            string     `json:"timezone"`
+	Locale              string     `json:"locale"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// UpdateProfileRequest changes the fields that are set and leaves the others
+// alone. Usernames are display handles, and accounts created through single
+// sign-on get the local part of their address, which is often short; 3 to
+// 64 characters fits both and a line of the UI.
+type UpdateProfileRequest struct {
+	Username    *string `json:"username" validate:"omitempty,min=3,max=64"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
+	Timezone    *string `json:"timezone" validate:"omitempty,len=0|timezone"`
+	Locale      *string `json:"locale" validate:"omitempty,len=0|bcp47_language_tag"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+// DeleteAccountRequest confirms a deletion with the user's password. Users
+// without one leave it empty and must have logged in recently instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"max=1024"`
+}
+
+type AccountDeletionResponse struct {
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
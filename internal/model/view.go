@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SavedView struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+	Sort      string
+	Order     string
+	Pinned    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CreateViewRequest struct {
+	Name   string `json:"name" validate:"required,max=255"`
+	Query  string `json:"query" validate:"max=1024"`
+	Sort   string `json:"sort" validate:"omitempty,oneof=priority created_at updated_at title is_done"`
+	Order  string `json:"order" validate:"omitempty,oneof=asc desc"`
+	Pinned bool   `json:"pinned"`
+}
+
+type UpdateViewRequest struct {
+	Name   *string `json:"name" validate:"omitempty,max=255"`
+	Query  *string `json:"query" validate:"omitempty,max=1024"`
+	Sort   *string `json:"sort" validate:"omitempty,oneof=priority created_at updated_at title is_done"`
+	Order  *string `json:"order" validate:"omitempty,oneof=asc desc"`
+	Pinned *bool   `json:"pinned"`
+}
+
+// SmartList is a built-in view available to every user.
+type SmartList struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type ViewsResponse struct {
+	SmartLists []SmartList `json:"smart_lists"`
+	Views      []SavedView `json:"views"`
+}
@@ -0,0 +1,335 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// by recurring tasks: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL,
+// BYDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxEmptyPeriods bounds the search for the next occurrence so that rules
+// which can never match (e.g. BYDAY=5MO with FREQ=MONTHLY in a short
+// window) terminate.
+const maxEmptyPeriods = 1000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry. N selects the nth weekday of the month, or of
+// the year for YEARLY rules (negative counts from the end), and is zero when
+// every matching weekday is meant.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed recurrence rule. A date-only UNTIL is kept as midnight
+// UTC of that date with UntilDate set, since it means the end of that day
+// wherever the series takes place.
+type Rule struct {
+	Freq      Frequency
+	Interval  int
+	ByDay     []WeekdayNum
+	Count     int
+	Until     *time.Time
+	UntilDate bool
+}
+
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq := Frequency(strings.ToUpper(val))
+			switch freq {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = freq
+			default:
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRule)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive integer", ErrInvalidRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, dateOnly, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+			rule.UntilDate = dateOnly
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(day)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+	for _, wd := range rule.ByDay {
+		if wd.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, fmt.Errorf("%w: numbered BYDAY is only allowed with MONTHLY or YEARLY", ErrInvalidRule)
+		}
+		if rule.Freq == Monthly && (wd.N < -5 || wd.N > 5) {
+			return nil, fmt.Errorf("%w: numbered BYDAY must be between -5 and 5 with MONTHLY", ErrInvalidRule)
+		}
+	}
+
+	return &rule, nil
+}
+
+func parseUntil(val string) (until time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102", val); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%w: UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ", ErrInvalidRule)
+}
+
+func parseWeekdayNum(val string) (WeekdayNum, error) {
+	val = strings.ToUpper(strings.TrimSpace(val))
+	if len(val) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRule, val)
+	}
+
+	day, ok := weekdays[val[len(val)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRule, val)
+	}
+
+	var n int
+	if prefix := val[:len(val)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("%w: bad BYDAY %q", ErrInvalidRule, val)
+		}
+	}
+
+	return WeekdayNum{N: n, Day: day}, nil
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			days = append(days, wd.String())
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil && r.UntilDate {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	} else if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (w WeekdayNum) String() string {
+	name := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return name
+	}
+	return strconv.Itoa(w.N) + name
+}
+
+// After returns up to n occurrences strictly after start, treating start as
+// the first occurrence of the series. COUNT is not applied here because it
+// depends on how many occurrences have already happened; UNTIL is, with a
+// date-only UNTIL ending at midnight in start's location.
+func (r *Rule) After(start time.Time, n int) []time.Time {
+	until := r.until(start.Location())
+	result := make([]time.Time, 0, n)
+	empty := 0
+	for period := 0; len(result) < n && empty < maxEmptyPeriods; period++ {
+		candidates := r.expand(start, period)
+		found := false
+		for _, c := range candidates {
+			if !c.After(start) {
+				continue
+			}
+			if until != nil && c.After(*until) {
+				return result
+			}
+			result = append(result, c)
+			found = true
+			if len(result) == n {
+				break
+			}
+		}
+		if found {
+			empty = 0
+		} else {
+			empty++
+		}
+	}
+	return result
+}
+
+func (r *Rule) until(loc *time.Location) *time.Time {
+	if r.Until == nil || !r.UntilDate {
+		return r.Until
+	}
+	end := time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+	return &end
+}
+
+// expand lists the candidate occurrences inside the period-th period of the
+// series, in chronological order.
+func (r *Rule) expand(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+	var candidates []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, step)
+		if r.matchesWeekday(day.Weekday()) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		base := start.AddDate(0, 0, 7*step)
+		if len(r.ByDay) == 0 {
+			return []time.Time{base}
+		}
+		monday := base.AddDate(0, 0, -((int(base.Weekday()) + 6) % 7))
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			if r.matchesWeekday(day.Weekday()) {
+				candidates = append(candidates, day)
+			}
+		}
+	case Monthly:
+		first := firstOfMonth(start).AddDate(0, step, 0)
+		candidates = r.inMonth(start, first)
+	case Yearly:
+		if len(r.ByDay) == 0 {
+			candidates = r.inMonth(start, firstOfMonth(start).AddDate(step, 0, 0))
+			break
+		}
+		first := time.Date(start.Year()+step, time.January, 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		candidates = r.matchByDay(first, first.AddDate(1, 0, -1).YearDay())
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return slices.CompactFunc(candidates, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+func (r *Rule) inMonth(start, first time.Time) []time.Time {
+	if len(r.ByDay) == 0 {
+		day := withDay(first, start.Day())
+		if day.Month() != first.Month() {
+			return nil
+		}
+		return []time.Time{day}
+	}
+
+	return r.matchByDay(first, first.AddDate(0, 1, -1).Day())
+}
+
+// matchByDay picks the BYDAY matches among the n days starting at first.
+// Numbered entries count within those days.
+func (r *Rule) matchByDay(first time.Time, n int) []time.Time {
+	var days []time.Time
+	for _, wd := range r.ByDay {
+		var matches []time.Time
+		for d := 1; d <= n; d++ {
+			day := withDay(first, d)
+			if day.Weekday() == wd.Day {
+				matches = append(matches, day)
+			}
+		}
+		switch {
+		case wd.N == 0:
+			days = append(days, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			days = append(days, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			days = append(days, matches[len(matches)+wd.N])
+		}
+	}
+	return days
+}
+
+func (r *Rule) matchesWeekday(day time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+}
+
+// withDay returns the day-th day counting from first, which need not lie in
+// first's month.
+func withDay(first time.Time, day int) time.Time {
+	return time.Date(first.Year(), first.Month(), first.Day()+day-1, first.Hour(), first.Minute(), first.Second(), 0, first.Location())
+}
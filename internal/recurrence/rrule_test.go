@@ -0,0 +1,248 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"daily", "FREQ=DAILY", "FREQ=DAILY"},
+		{"prefix and case", "RRULE:freq=weekly;interval=2;byday=mo,fr", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"monthly last friday", "FREQ=MONTHLY;BYDAY=-1FR", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"yearly week number", "FREQ=YEARLY;BYDAY=20MO", "FREQ=YEARLY;BYDAY=20MO"},
+		{"count", "FREQ=DAILY;COUNT=3", "FREQ=DAILY;COUNT=3"},
+		{"until date stays a date", "FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231"},
+		{"until time", "FREQ=DAILY;UNTIL=20261231T120000Z", "FREQ=DAILY;UNTIL=20261231T120000Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"FREQ=DAILY;UNTIL=2026-12-31",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=YEARLY;BYDAY=54MO",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=DAILY;BYMONTH=1",
+		"FREQ=DAILY;",
+	}
+
+	for _, value := range tests {
+		if _, err := Parse(value); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", value, err)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		n     int
+		want  []string
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY",
+			start: utc("2026-01-30 09:00"),
+			n:     3,
+			want:  []string{"2026-01-31 09:00", "2026-02-01 09:00", "2026-02-02 09:00"},
+		},
+		{
+			name:  "daily interval with weekdays",
+			rule:  "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			start: utc("2026-01-02 09:00"),
+			n:     2,
+			want:  []string{"2026-01-05 09:00", "2026-01-06 09:00"},
+		},
+		{
+			name:  "weekly",
+			rule:  "FREQ=WEEKLY;INTERVAL=2",
+			start: utc("2026-01-01 09:00"),
+			n:     2,
+			want:  []string{"2026-01-15 09:00", "2026-01-29 09:00"},
+		},
+		{
+			name:  "weekly on several days",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH",
+			start: utc("2026-01-05 09:00"),
+			n:     3,
+			want:  []string{"2026-01-08 09:00", "2026-01-12 09:00", "2026-01-15 09:00"},
+		},
+		{
+			name:  "monthly skips short months",
+			rule:  "FREQ=MONTHLY",
+			start: utc("2026-01-31 09:00"),
+			n:     3,
+			want:  []string{"2026-03-31 09:00", "2026-05-31 09:00", "2026-07-31 09:00"},
+		},
+		{
+			name:  "monthly last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: utc("2026-01-30 09:00"),
+			n:     2,
+			want:  []string{"2026-02-27 09:00", "2026-03-27 09:00"},
+		},
+		{
+			name:  "yearly",
+			rule:  "FREQ=YEARLY",
+			start: utc("2024-02-29 09:00"),
+			n:     2,
+			want:  []string{"2028-02-29 09:00", "2032-02-29 09:00"},
+		},
+		{
+			name:  "yearly by day covers the whole year",
+			rule:  "FREQ=YEARLY;BYDAY=MO",
+			start: utc("2026-01-26 09:00"),
+			n:     2,
+			want:  []string{"2026-02-02 09:00", "2026-02-09 09:00"},
+		},
+		{
+			name:  "yearly numbered by day counts within the year",
+			rule:  "FREQ=YEARLY;BYDAY=20MO",
+			start: utc("2026-01-01 09:00"),
+			n:     2,
+			want:  []string{"2026-05-18 09:00", "2027-05-17 09:00"},
+		},
+		{
+			name:  "yearly last sunday",
+			rule:  "FREQ=YEARLY;BYDAY=-1SU",
+			start: utc("2026-01-01 09:00"),
+			n:     1,
+			want:  []string{"2026-12-27 09:00"},
+		},
+		{
+			name:  "until time is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260103T090000Z",
+			start: utc("2026-01-01 09:00"),
+			n:     5,
+			want:  []string{"2026-01-02 09:00", "2026-01-03 09:00"},
+		},
+		{
+			name:  "until date includes that whole day",
+			rule:  "FREQ=DAILY;UNTIL=20260103",
+			start: utc("2026-01-01 23:30"),
+			n:     5,
+			want:  []string{"2026-01-02 23:30", "2026-01-03 23:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rule.After(tt.start, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("After() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if want := utc(tt.want[i]); !got[i].Equal(want) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAfterUntilDateUsesSeriesTimezone(t *testing.T) {
+	loc := mustLocation(t, "America/New_York")
+	rule, err := Parse("FREQ=DAILY;UNTIL=20261105")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 22:00 in New York is already the next day in UTC, so reading UNTIL in
+	// UTC would drop the occurrence on the 5th.
+	start := time.Date(2026, time.November, 3, 22, 0, 0, 0, loc)
+	got := rule.After(start, 5)
+
+	want := []time.Time{
+		time.Date(2026, time.November, 4, 22, 0, 0, 0, loc),
+		time.Date(2026, time.November, 5, 22, 0, 0, 0, loc),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("After() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAfterKeepsWallClockAcrossDST(t *testing.T) {
+	loc := mustLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+	}{
+		{"daily over fall back", "FREQ=DAILY", time.Date(2026, time.October, 23, 9, 0, 0, 0, loc)},
+		{"daily over spring forward", "FREQ=DAILY", time.Date(2026, time.March, 27, 9, 0, 0, 0, loc)},
+		{"weekly over fall back", "FREQ=WEEKLY", time.Date(2026, time.October, 20, 9, 0, 0, 0, loc)},
+		{"monthly over fall back", "FREQ=MONTHLY;BYDAY=-1SU", time.Date(2026, time.September, 27, 9, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, occurrence := range rule.After(tt.start, 4) {
+				local := occurrence.In(loc)
+				if local.Hour() != 9 || local.Minute() != 0 {
+					t.Errorf("occurrence %v is at %s local time, want 09:00", occurrence, local.Format("15:04"))
+				}
+			}
+		})
+	}
+}
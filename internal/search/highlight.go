@@ -0,0 +1,112 @@
+// Package search holds backend-independent helpers for task search results.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// Terms splits a free-text query into lower-cased words, dropping
+// punctuation and duplicates.
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Highlight HTML-escapes text and wraps every case-insensitive occurrence of
+// any term in <mark> tags.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	marks := matchMask(runes, terms)
+
+	var b strings.Builder
+	inMark := false
+	start := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(string(runes[start:end])))
+		start = end
+	}
+	for i := range runes {
+		if marks[i] != inMark {
+			flush(i)
+			if marks[i] {
+				b.WriteString(markOpen)
+			} else {
+				b.WriteString(markClose)
+			}
+			inMark = marks[i]
+		}
+	}
+	flush(len(runes))
+	if inMark {
+		b.WriteString(markClose)
+	}
+	return b.String()
+}
+
+// Snippet returns a highlighted excerpt of at most about width runes centred
+// on the first matching term, or the beginning of text if nothing matches.
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return Highlight(text, terms)
+	}
+
+	first := -1
+	for i, marked := range matchMask(runes, terms) {
+		if marked {
+			first = i
+			break
+		}
+	}
+
+	start := 0
+	if first > width/2 {
+		start = first - width/2
+	}
+	end := min(start+width, len(runes))
+
+	snippet := Highlight(string(runes[start:end]), terms)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func matchMask(runes []rune, terms []string) []bool {
+	lower := []rune(strings.ToLower(string(runes)))
+	marks := make([]bool, len(runes))
+	if len(lower) != len(runes) {
+		return marks
+	}
+
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marks[j] = true
+				}
+			}
+		}
+	}
+	return marks
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"milk", []string{"milk"}},
+		{"Buy milk, buy EGGS!", []string{"buy", "milk", "eggs"}},
+		{"tax-return 2026", []string{"tax", "return", "2026"}},
+		{"Übung über", []string{"übung", "über"}},
+		{"  ,;  ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"no terms", "Buy milk", nil, "Buy milk"},
+		{"one match", "Buy milk", []string{"milk"}, "Buy <mark>milk</mark>"},
+		{"ignores case", "Buy MILK", []string{"milk"}, "Buy <mark>MILK</mark>"},
+		{"every occurrence", "milk and milk", []string{"milk"}, "<mark>milk</mark> and <mark>milk</mark>"},
+		{"adjacent matches merge", "milkshake", []string{"milk", "shake"}, "<mark>milkshake</mark>"},
+		{"overlapping matches merge", "banana", []string{"ana"}, "b<mark>anana</mark>"},
+		{"escapes HTML", "<b>milk</b> & 'eggs'", []string{"milk"}, "&lt;b&gt;<mark>milk</mark>&lt;/b&gt; &amp; &#39;eggs&#39;"},
+		{"escapes inside a match", "a<b", []string{"a", "b"}, "<mark>a</mark>&lt;<mark>b</mark>"},
+		{"multibyte text", "Übung macht", []string{"übung"}, "<mark>Übung</mark> macht"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms); got != tt.want {
+				t.Fatalf("Highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{"short text", "Buy milk", []string{"milk"}, 20, "Buy <mark>milk</mark>"},
+		{"centred on the match", "aaaaaaaaaa milk bbbbbbbbbb", []string{"milk"}, 10, "…aaaa <mark>milk</mark> …"},
+		{"match near the start", "milk bbbbbbbbbbbbbbbbbbbb", []string{"milk"}, 10, "<mark>milk</mark> bbbbb…"},
+		{"match near the end", "aaaaaaaaaaaaaaaaaaaa milk", []string{"milk"}, 10, "…aaaa <mark>milk</mark>"},
+		{"no match", "aaaaaaaaaaaaaaaaaaaa", []string{"milk"}, 10, "aaaaaaaaaa…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, tt.width); got != tt.want {
+				t.Fatalf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
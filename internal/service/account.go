@@ -0,0 +1,481 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/mail"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/storage"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidDeletionParam = errors.New("invalid account deletion request")
+	ErrNoPendingDeletion    = errors.New("account is not pending deletion")
+	// ErrReauthRequired is returned to users without a password whose
+	// session is too old to confirm the request with.
+	ErrReauthRequired = errors.New("log in again to confirm this request")
+)
+
+// exportPageSize is how many tasks an export reads at a time.
+const exportPageSize = 500
+
+type AccountStorage interface {
+	GetByID(id uuid.UUID) (*model.User, error)
+	ScheduleDeletion(id uuid.UUID, at *time.Time) error
+	ListDueDeletions(now time.Time) ([]uuid.UUID, error)
+	Delete(id uuid.UUID, now time.Time) (bool, error)
+}
+
+type ExportTaskStorage interface {
+	List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error)
+}
+
+type AccountOptions struct {
+	// GracePeriod is how long a deletion can still be canceled.
+	GracePeriod time.Duration
+	// ReauthWindow is how recently users without a password, who cannot
+	// confirm a deletion with one, must have logged in. It defaults to ten
+	// minutes.
+	ReauthWindow time.Duration
+	Hasher       *auth.PasswordHasher
+}
+
+// AccountService exports a user's data and deletes accounts on request.
+// Deletion happens in two steps: the user schedules it, and once the grace
+// period has passed PurgeDueAccounts removes the user and, through the
+// foreign keys, everything they own.
+type AccountService struct {
+	users        AccountStorage
+	tasks        ExportTaskStorage
+	sessions     *JWTService
+	sender       mail.Sender
+	audit        AuditStorage
+	gracePeriod  time.Duration
+	reauthWindow time.Duration
+	hasher       *auth.PasswordHasher
+}
+
+func NewAccountService(users AccountStorage, tasks ExportTaskStorage, sessions *JWTService, sender mail.Sender, audit AuditStorage, opts AccountOptions) *AccountService {
+	hasher := opts.Hasher
+	if hasher == nil {
+		hasher = auth.DefaultPasswordHasher()
+	}
+	reauthWindow := opts.ReauthWindow
+	if reauthWindow == 0 {
+		reauthWindow = 10 * time.Minute
+	}
+
+	return &AccountService{
+		users:        users,
+		tasks:        tasks,
+		sessions:     sessions,
+		sender:       sender,
+		audit:        audit,
+		gracePeriod:  opts.GracePeriod,
+		reauthWindow: reauthWindow,
+		hasher:       hasher,
+	}
+}
+
+// AccountExport is everything stored about a user.
+type AccountExport struct {
+	ExportedAt time.Time
+	Profile    model.ProfileResponse
+
+	userID uuid.UUID
+	tasks  ExportTaskStorage
+}
+
+// Export looks up the user whose data is exported, so that a missing
+// account is reported before any of the archive has been written. The
+// tasks are read while WriteArchive writes them.
+func (s *AccountService) Export(userID string) (*AccountExport, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.users.GetByID(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("export account service: %w", err)
+	}
+
+	return &AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    *toProfileResponse(user),
+		userID:     uuidUserID,
+		tasks:      s.tasks,
+	}, nil
+}
+
+// WriteArchive streams the export to w as a ZIP archive holding
+// profile.json, tasks.json and tasks.csv. Tasks are read a page at a time,
+// once for each file, so memory use does not grow with their number. An
+// error leaves the archive without its central directory, which unzip tools
+// report as a damaged file.
+func (e *AccountExport) WriteArchive(w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	profile := struct {
+		ExportedAt time.Time             `json:"exported_at"`
+		Profile    model.ProfileResponse `json:"profile"`
+	}{e.ExportedAt, e.Profile}
+
+	if err := e.writeJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+	if err := e.writeTasksJSON(archive, "tasks.json"); err != nil {
+		return err
+	}
+	if err := e.writeCSV(archive, "tasks.csv"); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// eachTask calls fn for every task of the user, in creation order.
+func (e *AccountExport) eachTask(fn func(model.TaskExport) error) error {
+	filter := model.TaskFilter{Limit: exportPageSize}
+	for {
+		tasks, err := e.tasks.List(e.userID, filter)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if err := fn(toTaskExport(task)); err != nil {
+				return err
+			}
+		}
+		if len(tasks) < exportPageSize {
+			return nil
+		}
+
+		cursor := storage.NewTaskCursor(tasks[len(tasks)-1], filter.Sort, filter.Order, false)
+		filter.After = &cursor
+	}
+}
+
+func (e *AccountExport) create(archive *zip.Writer, name string) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: e.ExportedAt})
+}
+
+func (e *AccountExport) writeJSON(archive *zip.Writer, name string, v any) error {
+	file, err := e.create(archive, name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeTasksJSON writes the tasks as one indented JSON array, one element
+// at a time.
+func (e *AccountExport) writeTasksJSON(archive *zip.Writer, name string) error {
+	file, err := e.create(archive, name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(file, "["); err != nil {
+		return err
+	}
+	separator := "\n  "
+	err = e.eachTask(func(task model.TaskExport) error {
+		data, err := json.MarshalIndent(task, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, separator); err != nil {
+			return err
+		}
+		separator = ",\n  "
+		_, err = file.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	closing := "\n]\n"
+	if separator == "\n  " {
+		closing = "]\n"
+	}
+	_, err = io.WriteString(file, closing)
+	return err
+}
+
+func (e *AccountExport) writeCSV(archive *zip.Writer, name string) error {
+	file, err := e.create(archive, name)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	header := []string{"id", "title", "description", "is_done", "priority", "parent_id", "project_id", "due_at", "due_all_day", "due_timezone", "recurrence", "tags", "created_at", "updated_at", "completed_at"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	err = e.eachTask(func(task model.TaskExport) error {
+		record := []string{
+			task.ID.String(),
+			csvText(task.Title),
+			csvText(task.Description),
+			strconv.FormatBool(task.IsDone),
+			string(task.Priority),
+			optionalID(task.ParentID),
+			optionalID(task.ProjectID),
+			optionalTime(task.DueAt),
+			strconv.FormatBool(task.DueAllDay),
+			task.DueTimezone,
+			task.Recurrence,
+			csvText(strings.Join(task.Tags, ";")),
+			task.CreatedAt.UTC().Format(time.RFC3339),
+			task.UpdatedAt.UTC().Format(time.RFC3339),
+			optionalTime(task.CompletedAt),
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ScheduleDeletion marks the account for deletion once the grace period is
+// over. The user has to confirm it, so that a stolen session alone cannot
+// delete the account: with their password or, for users who only log in
+// through an identity provider, by having logged in within the reauth
+// window.
+func (s *AccountService) ScheduleDeletion(userID string, claims jwt.MapClaims, req model.DeleteAccountRequest) (*model.AccountDeletionResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDeletionParam, err)
+	}
+
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.users.GetByID(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("schedule deletion service: %w", err)
+	}
+
+	if err := s.confirmIdentity(user, claims, req.Password); err != nil {
+		return nil, err
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return &model.AccountDeletionResponse{ScheduledFor: *user.DeletionScheduledAt}, nil
+	}
+
+	now := time.Now()
+	at := now.Add(s.gracePeriod)
+	if err := s.users.ScheduleDeletion(user.ID, &at); err != nil {
+		return nil, fmt.Errorf("schedule deletion service: %w", err)
+	}
+
+	if err := s.record(model.AuditDeletionRequest, user.ID, &user.ID, "deletion scheduled for "+at.UTC().Format(time.RFC3339), now); err != nil {
+		return nil, fmt.Errorf("schedule deletion service: %w", err)
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your account is scheduled for deletion",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour account and all of its tasks will be deleted on %s. Until then you can log in and cancel the deletion.\n",
+			user.Username, at.UTC().Format("2 January 2006 15:04 MST"),
+		),
+	}
+	if err := s.sender.Send(msg); err != nil {
+		return nil, fmt.Errorf("schedule deletion service: %w", err)
+	}
+
+	return &model.AccountDeletionResponse{ScheduledFor: at}, nil
+}
+
+func (s *AccountService) CancelDeletion(userID string) error {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	user, err := s.users.GetByID(uuidUserID)
+	if err != nil {
+		return fmt.Errorf("cancel deletion service: %w", err)
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrNoPendingDeletion
+	}
+
+	if err := s.users.ScheduleDeletion(user.ID, nil); err != nil {
+		return fmt.Errorf("cancel deletion service: %w", err)
+	}
+
+	if err := s.record(model.AuditDeletionCanceled, user.ID, &user.ID, "", time.Now()); err != nil {
+		return fmt.Errorf("cancel deletion service: %w", err)
+	}
+
+	return nil
+}
+
+// confirmIdentity checks the password of users who have one, and that
+// everyone else has just logged in.
+func (s *AccountService) confirmIdentity(user *model.User, claims jwt.MapClaims, password string) error {
+	if user.Password != "" {
+		if _, err := s.hasher.Verify(user.Password, password); err != nil {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+
+	recent, err := s.sessions.RecentLogin(user.ID, claims, s.reauthWindow)
+	if err != nil {
+		return fmt.Errorf("confirm identity: %w", err)
+	}
+	if !recent {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+// PurgeDueAccounts deletes every account whose grace period is over and
+// returns how many were deleted. An account that fails is left for the next
+// run and does not hold up the others; their errors are returned together.
+func (s *AccountService) PurgeDueAccounts(now time.Time) (int, error) {
+	ids, err := s.users.ListDueDeletions(now)
+	if err != nil {
+		return 0, fmt.Errorf("purge accounts service: %w", err)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, id := range ids {
+		ok, err := s.purge(id, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge accounts service: user %s: %w", id, err))
+		}
+		if ok {
+			deleted++
+		}
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// purge deletes one account and then revokes its tokens. Revoking last
+// means a failed delete leaves the user able to log in and cancel, while
+// tokens still in flight are cut off once the account is gone; revocations
+// are kept apart from the user row for that reason.
+func (s *AccountService) purge(id uuid.UUID, now time.Time) (bool, error) {
+	ok, err := s.users.Delete(id, now)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := s.sessions.LogoutAll(id.String()); err != nil {
+		return true, err
+	}
+	if err := s.record(model.AuditAccountDeleted, id, nil, "", now); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// RunDeletions purges due accounts every interval until ctx is done. Errors
+// are passed to onError and retried on the next run.
+func (s *AccountService) RunDeletions(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeDueAccounts(time.Now()); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *AccountService) record(event string, subject uuid.UUID, userID *uuid.UUID, detail string, at time.Time) error {
+	return s.audit.Create(model.AuditEntry{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Event:     event,
+		Subject:   subject.String(),
+		Detail:    detail,
+		CreatedAt: at,
+	})
+}
+
+func toTaskExport(task model.Task) model.TaskExport {
+	tags := make([]string, 0, len(task.Tags))
+	for _, tag := range task.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return model.TaskExport{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		IsDone:      task.IsDone,
+		Priority:    task.Priority,
+		ParentID:    task.ParentID,
+		ProjectID:   task.ProjectID,
+		DueAt:       task.DueAt,
+		DueAllDay:   task.DueAllDay,
+		DueTimezone: task.DueTimezone,
+		Recurrence:  task.Recurrence,
+		Tags:        tags,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+		CompletedAt: task.CompletedAt,
+	}
+}
+
+// csvText keeps spreadsheet programs from evaluating user text as a formula.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
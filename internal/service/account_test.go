@@ -0,0 +1,205 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// memoryTasks lists tasks in creation order and pages like the SQL store.
+type memoryTasks struct {
+	tasks []model.Task
+	reads int
+}
+
+func (m *memoryTasks) List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error) {
+	m.reads++
+
+	start := 0
+	if filter.After != nil {
+		start = slices.IndexFunc(m.tasks, func(task model.Task) bool { return task.ID == filter.After.ID }) + 1
+	}
+
+	var page []model.Task
+	for _, task := range m.tasks[start:] {
+		if filter.Limit > 0 && len(page) == filter.Limit {
+			break
+		}
+		if task.UserId == userID {
+			page = append(page, task)
+		}
+	}
+	return page, nil
+}
+
+func newTestAccounts(t *testing.T) (*AccountService, *testAuth, *memoryTasks) {
+	t.Helper()
+
+	a := newTestAuth(t)
+	tasks := &memoryTasks{}
+	s := NewAccountService(a.users, tasks, a.JWTService, &memoryMail{}, &memoryAudit{}, AccountOptions{
+		GracePeriod: time.Hour,
+		Hasher:      a.passwords,
+	})
+	return s, a, tasks
+}
+
+func TestExportStreamsEveryPage(t *testing.T) {
+	s, a, tasks := newTestAccounts(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	const count = 2*exportPageSize + 7
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < count; i++ {
+		tasks.tasks = append(tasks.tasks, model.Task{ID: uuid.New(), UserId: user.ID, Title: "task", CreatedAt: created.Add(time.Duration(i) * time.Second)})
+	}
+
+	export, err := s.Export(user.ID.String())
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := export.WriteArchive(&buf); err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	var exported []model.TaskExport
+	if err := json.Unmarshal(files["tasks.json"], &exported); err != nil {
+		t.Fatalf("tasks.json is not valid JSON: %v", err)
+	}
+	if len(exported) != count {
+		t.Errorf("tasks.json holds %d tasks, want %d", len(exported), count)
+	}
+	records, err := csv.NewReader(bytes.NewReader(files["tasks.csv"])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != count+1 {
+		t.Errorf("tasks.csv holds %d rows, want %d and a header", len(records), count)
+	}
+	// Three pages for each of the two task files.
+	if tasks.reads != 6 {
+		t.Errorf("tasks were read %d times, want 6", tasks.reads)
+	}
+}
+
+func TestExportWithoutTasks(t *testing.T) {
+	s, a, _ := newTestAccounts(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	export, err := s.Export(user.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := export.WriteArchive(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	r, _ := archive.Open("tasks.json")
+	data, _ := io.ReadAll(r)
+	if strings.TrimSpace(string(data)) != "[]" {
+		t.Fatalf("tasks.json = %q, want an empty array", data)
+	}
+}
+
+func TestScheduleDeletionWithoutPassword(t *testing.T) {
+	s, a, _ := newTestAccounts(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	a.users.users[user.ID].Password = ""
+
+	sessionID := uuid.New()
+	now := time.Now()
+	a.sessions.sessions[sessionID] = &model.Session{ID: sessionID, UserID: user.ID, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	claims := jwt.MapClaims{"sid": sessionID.String()}
+
+	if _, err := s.ScheduleDeletion(user.ID.String(), claims, model.DeleteAccountRequest{}); !errors.Is(err, ErrReauthRequired) {
+		t.Fatalf("ScheduleDeletion() with an old session error = %v, want %v", err, ErrReauthRequired)
+	}
+	if _, err := s.ScheduleDeletion(user.ID.String(), claims, model.DeleteAccountRequest{Password: "anything"}); !errors.Is(err, ErrReauthRequired) {
+		t.Fatalf("ScheduleDeletion() with a made-up password error = %v, want %v", err, ErrReauthRequired)
+	}
+
+	a.sessions.sessions[sessionID].CreatedAt = now.Add(-time.Minute)
+	if _, err := s.ScheduleDeletion(user.ID.String(), claims, model.DeleteAccountRequest{}); err != nil {
+		t.Fatalf("ScheduleDeletion() right after logging in error = %v", err)
+	}
+}
+
+func TestScheduleDeletionChecksPassword(t *testing.T) {
+	s, a, _ := newTestAccounts(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	if _, err := s.ScheduleDeletion(user.ID.String(), jwt.MapClaims{}, model.DeleteAccountRequest{Password: "wrong"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("ScheduleDeletion() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := s.ScheduleDeletion(user.ID.String(), jwt.MapClaims{}, model.DeleteAccountRequest{Password: "correct horse battery"}); err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+}
+
+// failingDelete fails to delete one user.
+type failingDelete struct {
+	*memoryUsers
+	broken uuid.UUID
+}
+
+func (f failingDelete) Delete(id uuid.UUID, now time.Time) (bool, error) {
+	if id == f.broken {
+		return false, errors.New("lock wait timeout")
+	}
+	return f.memoryUsers.Delete(id, now)
+}
+
+func TestPurgeDueAccountsContinuesPastFailures(t *testing.T) {
+	s, a, _ := newTestAccounts(t)
+	past := time.Now().Add(-time.Minute)
+	var ids []uuid.UUID
+	for _, email := range []string{"alice@example.com", "bob@example.com", "carol@example.com"} {
+		user := a.addUser(t, email, "correct horse battery")
+		a.users.users[user.ID].DeletionScheduledAt = &past
+		ids = append(ids, user.ID)
+	}
+	s.users = failingDelete{a.users, ids[1]}
+
+	deleted, err := s.PurgeDueAccounts(time.Now())
+	if err == nil {
+		t.Fatal("PurgeDueAccounts() reported no error")
+	}
+	if deleted != 2 {
+		t.Fatalf("PurgeDueAccounts() deleted %d, want 2", deleted)
+	}
+	if _, err := a.users.GetByID(ids[1]); err != nil {
+		t.Fatalf("the account that failed to delete is gone: %v", err)
+	}
+	// The account that survived can still log in and cancel.
+	if _, err := a.Login("bob@example.com", "correct horse battery", model.ClientInfo{}); err != nil {
+		t.Fatalf("Login() of the surviving account error = %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAdminParam  = errors.New("invalid admin request")
+	ErrSelfAdministration = errors.New("administrators cannot disable or change the role of their own account")
+	ErrAdminUnverified    = errors.New("the bootstrap admin has not verified their email address")
+	ErrOutranked          = errors.New("the user's role grants permissions your role lacks")
+)
+
+const maxAdminPageSize = 100
+
+type AdminStorage interface {
+	GetByEmail(email string) (*model.User, error)
+	GetAdminUser(id uuid.UUID) (*model.AdminUser, error)
+	Search(query string, limit, offset int) ([]model.AdminUser, error)
+	SetRole(id uuid.UUID, role string) error
+	SetDisabled(id uuid.UUID, at *time.Time) error
+}
+
+// AdminService lets administrators look up and manage user accounts. Every
+// change is recorded in the audit log together with who made it.
+type AdminService struct {
+	users    AdminStorage
+	roles    *RoleService
+	sessions *JWTService
+	resets   *PasswordResetService
+	audit    AuditStorage
+}
+
+func NewAdminService(users AdminStorage, roles *RoleService, sessions *JWTService, resets *PasswordResetService, audit AuditStorage) *AdminService {
+	return &AdminService{
+		users:    users,
+		roles:    roles,
+		sessions: sessions,
+		resets:   resets,
+		audit:    audit,
+	}
+}
+
+// ListUsers returns a page of users whose email or username contains query.
+// A limit of zero picks the default page size.
+func (s *AdminService) ListUsers(query string, limit, offset int) (*model.AdminUserPage, error) {
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 0 || limit > maxAdminPageSize || offset < 0 {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d and offset not negative", ErrInvalidAdminParam, maxAdminPageSize)
+	}
+
+	users, err := s.users.Search(strings.TrimSpace(query), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list users service: %w", err)
+	}
+
+	page := &model.AdminUserPage{
+		Users:  make([]model.AdminUserResponse, 0, len(users)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, user := range users {
+		page.Users = append(page.Users, toAdminUserResponse(&user))
+	}
+
+	return page, nil
+}
+
+func (s *AdminService) GetUser(userID string) (*model.AdminUserResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user service: %w", err)
+	}
+
+	resp := toAdminUserResponse(user)
+	return &resp, nil
+}
+
+// DisableUser blocks the user from logging in and ends every session and
+// personal access token they hold. Administrators can only disable users
+// whose role grants nothing beyond their own.
+func (s *AdminService) DisableUser(adminID, userID string) (*model.AdminUserResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("disable user service: %w", err)
+	}
+	if err := s.checkTarget(adminID, user); err != nil {
+		return nil, fmt.Errorf("disable user service: %w", err)
+	}
+
+	if user.DisabledAt == nil {
+		now := time.Now()
+		if err := s.users.SetDisabled(user.ID, &now); err != nil {
+			return nil, fmt.Errorf("disable user service: %w", err)
+		}
+		user.DisabledAt = &now
+
+		if err := s.sessions.LogoutAll(user.ID.String()); err != nil {
+			return nil, fmt.Errorf("disable user service: %w", err)
+		}
+		if err := s.sessions.RevokePersonalTokens(user.ID); err != nil {
+			return nil, fmt.Errorf("disable user service: %w", err)
+		}
+
+		if err := s.record(model.AuditAccountDisabled, adminID, user.ID, ""); err != nil {
+			return nil, fmt.Errorf("disable user service: %w", err)
+		}
+	}
+
+	resp := toAdminUserResponse(user)
+	return &resp, nil
+}
+
+func (s *AdminService) EnableUser(adminID, userID string) (*model.AdminUserResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("enable user service: %w", err)
+	}
+	if err := s.checkTarget(adminID, user); err != nil {
+		return nil, fmt.Errorf("enable user service: %w", err)
+	}
+
+	if user.DisabledAt != nil {
+		if err := s.users.SetDisabled(user.ID, nil); err != nil {
+			return nil, fmt.Errorf("enable user service: %w", err)
+		}
+		user.DisabledAt = nil
+
+		if err := s.record(model.AuditAccountEnabled, adminID, user.ID, ""); err != nil {
+			return nil, fmt.Errorf("enable user service: %w", err)
+		}
+	}
+
+	resp := toAdminUserResponse(user)
+	return &resp, nil
+}
+
+// ForcePasswordReset clears the user's password, logs them out everywhere,
+// revokes their personal access tokens and mails them a link to choose a new
+// one.
+func (s *AdminService) ForcePasswordReset(adminID, userID string) error {
+	user, err := s.user(userID)
+	if err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+	if err := s.checkTarget(adminID, user); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	if err := s.resets.ForceReset(user.ID); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	if err := s.record(model.AuditPasswordReset, adminID, user.ID, ""); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	return nil
+}
+
+// SetRole assigns a role to the user. Their access tokens are revoked so the
+// next refresh issues tokens that carry the new role. Neither the user's
+// current role nor the new one may grant more than the administrator's.
+func (s *AdminService) SetRole(adminID, userID string, req model.SetRoleRequest) (*model.AdminUserResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAdminParam, err)
+	}
+	if !s.roles.Exists(req.Role) {
+		return nil, ErrRoleNotFound
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("set role service: %w", err)
+	}
+	if err := s.checkTarget(adminID, user); err != nil {
+		return nil, fmt.Errorf("set role service: %w", err)
+	}
+	if err := s.checkRole(adminID, req.Role); err != nil {
+		return nil, fmt.Errorf("set role service: %w", err)
+	}
+
+	if user.Role != req.Role {
+		if err := s.changeRole(&user.User, req.Role, adminID); err != nil {
+			return nil, fmt.Errorf("set role service: %w", err)
+		}
+	}
+
+	resp := toAdminUserResponse(user)
+	return &resp, nil
+}
+
+// BootstrapAdmin makes the user with the given email an administrator, so a
+// fresh installation has someone who can hand out roles. It only acts while
+// there is no administrator yet, and only for a user who verified the
+// address, since anyone could have registered it first. It reports whether
+// the role changed; an unknown email is not an error since the user may not
+// have registered yet.
+func (s *AdminService) BootstrapAdmin(email string) (bool, error) {
+	admins, err := s.roles.CountUsers(model.RoleAdmin)
+	if err != nil {
+		return false, fmt.Errorf("bootstrap admin service: %w", err)
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	user, err := s.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("bootstrap admin service: %w", err)
+	}
+	if !user.EmailVerified {
+		return false, ErrAdminUnverified
+	}
+
+	if err := s.changeRole(user, model.RoleAdmin, "bootstrap"); err != nil {
+		return false, fmt.Errorf("bootstrap admin service: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *AdminService) changeRole(user *model.User, role, actor string) error {
+	previous := user.Role
+	if err := s.users.SetRole(user.ID, role); err != nil {
+		return err
+	}
+	user.Role = role
+
+	if err := s.sessions.ExpireAccessTokens(user.ID); err != nil {
+		return err
+	}
+
+	return s.record(model.AuditRoleChanged, actor, user.ID, previous+" -> "+role)
+}
+
+// checkTarget refuses changes an administrator makes to their own account or
+// to a user whose role grants permissions their own role lacks, so holders
+// of a custom role cannot lock out the administrators above them.
+func (s *AdminService) checkTarget(adminID string, user *model.AdminUser) error {
+	if user.ID.String() == adminID {
+		return ErrSelfAdministration
+	}
+	return s.checkRole(adminID, user.Role)
+}
+
+// checkRole refuses when the administrator's current role does not cover
+// role.
+func (s *AdminService) checkRole(adminID, role string) error {
+	actor, err := s.user(adminID)
+	if err != nil {
+		return err
+	}
+	if !s.roles.Covers(actor.Role, role) {
+		return ErrOutranked
+	}
+	return nil
+}
+
+func (s *AdminService) user(userID string) (*model.AdminUser, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.users.GetAdminUser(uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// record writes an audit entry about the user. actor is the id of the
+// administrator who made the change.
+func (s *AdminService) record(event, actor string, userID uuid.UUID, change string) error {
+	detail := "by " + actor
+	if change != "" {
+		detail += ": " + change
+	}
+
+	return s.audit.Create(model.AuditEntry{
+		ID:        uuid.New(),
+		UserID:    &userID,
+		Event:     event,
+		Subject:   userID.String(),
+		Detail:    detail,
+		CreatedAt: time.Now(),
+	})
+}
+
+func toAdminUserResponse(user *model.AdminUser) model.AdminUserResponse {
+	return model.AdminUserResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		EmailVerified:       user.EmailVerified,
+		Username:            user.Username,
+		Role:                user.Role,
+		DisabledAt:          user.DisabledAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		Tasks:               user.Tasks,
+	}
+}
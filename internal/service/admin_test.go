@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// memoryRoles is a role store shared by every RoleService built on it, like
+// the roles table is shared by every instance.
+type memoryRoles struct {
+	roles map[string]model.Role
+	users *memoryUsers
+}
+
+func (m *memoryRoles) List() ([]model.Role, error) {
+	roles := make([]model.Role, 0, len(m.roles))
+	for _, role := range m.roles {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (m *memoryRoles) Create(role model.Role) error {
+	m.roles[role.Name] = role
+	return nil
+}
+
+func (m *memoryRoles) Update(name string, permissions []string) error {
+	role, ok := m.roles[name]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	role.Permissions = permissions
+	m.roles[name] = role
+	return nil
+}
+
+func (m *memoryRoles) Delete(name string) error {
+	if _, ok := m.roles[name]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.roles, name)
+	return nil
+}
+
+func (m *memoryRoles) CountUsers(name string) (int, error) {
+	count := 0
+	for _, user := range m.users.users {
+		if user.Role == name {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newMemoryRoles(users *memoryUsers) *memoryRoles {
+	return &memoryRoles{
+		roles: map[string]model.Role{
+			model.RoleAdmin: {Name: model.RoleAdmin, Builtin: true},
+			model.RoleUser:  {Name: model.RoleUser, Builtin: true},
+			"auditor":       {Name: "auditor", Permissions: []string{model.PermissionUsersRead}},
+		},
+		users: users,
+	}
+}
+
+func newTestRoles(t *testing.T, store RoleStorage) *RoleService {
+	t.Helper()
+
+	roles := NewRoleService(store)
+	if err := roles.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return roles
+}
+
+func newTestAdmin(t *testing.T) (*AdminService, *testAuth, *memoryMail) {
+	t.Helper()
+
+	resets, a, sender := newTestResets(t)
+	roles := newTestRoles(t, newMemoryRoles(a.users))
+	return NewAdminService(a.users, roles, a.JWTService, resets, &memoryAudit{}), a, sender
+}
+
+func TestHasPermission(t *testing.T) {
+	roles := newTestRoles(t, newMemoryRoles(newMemoryUsers()))
+
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{model.RoleAdmin, model.PermissionRolesManage, true},
+		{model.RoleAdmin, model.PermissionUsersManage, true},
+		{"auditor", model.PermissionUsersRead, true},
+		{"auditor", model.PermissionUsersManage, false},
+		{model.RoleUser, model.PermissionUsersRead, false},
+		{"ghost", model.PermissionUsersRead, false},
+		{"", model.PermissionUsersRead, false},
+	}
+	for _, tt := range tests {
+		if got := roles.HasPermission(tt.role, tt.permission); got != tt.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestRoleChangesReachOtherInstances(t *testing.T) {
+	store := newMemoryRoles(newMemoryUsers())
+	first := newTestRoles(t, store)
+	second := newTestRoles(t, store)
+
+	req := model.UpdateRoleRequest{Permissions: []string{model.PermissionUsersManage}}
+	if _, err := first.Update("auditor", req); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !first.HasPermission("auditor", model.PermissionUsersManage) {
+		t.Fatal("the instance that made the change does not see it")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go second.RunSync(ctx, 10*time.Millisecond, func(err error) { t.Error(err) })
+
+	deadline := time.Now().Add(time.Second)
+	for second.HasPermission("auditor", model.PermissionUsersRead) {
+		if time.Now().After(deadline) {
+			t.Fatal("the other instance still grants the permission the role lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !second.HasPermission("auditor", model.PermissionUsersManage) {
+		t.Fatal("the other instance does not grant the permission the role gained")
+	}
+}
+
+func TestDisabledAccountsCannotSignIn(t *testing.T) {
+	const password = "correct horse battery"
+	client := model.ClientInfo{IP: "10.0.0.1"}
+
+	t.Run("login", func(t *testing.T) {
+		a := newTestAuth(t)
+		user := a.addUser(t, "alice@example.com", password)
+		disable(a, user)
+
+		if _, err := a.Login(user.Email, password, client); !errors.Is(err, ErrAccountDisabled) {
+			t.Fatalf("Login() error = %v, want %v", err, ErrAccountDisabled)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		a := newTestAuth(t)
+		user := a.addUser(t, "alice@example.com", password)
+		resp, err := a.Login(user.Email, password, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		disable(a, user)
+
+		if _, err := a.Refresh(resp.RefreshToken, client); !errors.Is(err, ErrAccountDisabled) {
+			t.Fatalf("Refresh() error = %v, want %v", err, ErrAccountDisabled)
+		}
+	})
+
+	t.Run("two-factor", func(t *testing.T) {
+		a := newTestAuth(t)
+		user := a.addUser(t, "alice@example.com", password)
+		secret := enrolTestTOTP(t, a, user)
+		resp, err := a.Login(user.Email, password, client)
+		if err != nil || !resp.TwoFactorRequired {
+			t.Fatalf("Login() = %+v, %v; want a two-factor challenge", resp, err)
+		}
+		disable(a, user)
+
+		code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = a.CompleteTwoFactorLogin(model.TwoFactorLoginRequest{ChallengeToken: resp.ChallengeToken, Code: code}, client)
+		if !errors.Is(err, ErrAccountDisabled) {
+			t.Fatalf("CompleteTwoFactorLogin() error = %v, want %v", err, ErrAccountDisabled)
+		}
+	})
+
+	t.Run("oidc", func(t *testing.T) {
+		a := newTestAuth(t)
+		identities := &memoryIdentities{}
+		s := newTestOIDC(t, a, identities)
+		user := a.addUser(t, "alice@example.com", password)
+
+		if _, err := loginAs(t, s, user.Email); err != nil {
+			t.Fatalf("FinishLogin() error = %v", err)
+		}
+		disable(a, user)
+
+		if _, err := loginAs(t, s, user.Email); !errors.Is(err, ErrAccountDisabled) {
+			t.Fatalf("FinishLogin() error = %v, want %v", err, ErrAccountDisabled)
+		}
+	})
+}
+
+// addWithRole stores a verified user holding role.
+func addWithRole(t *testing.T, a *testAuth, email, role string) *model.User {
+	t.Helper()
+
+	user := a.addUser(t, email, "correct horse battery")
+	a.users.users[user.ID].Role = role
+	user.Role = role
+	return user
+}
+
+func disable(a *testAuth, user *model.User) {
+	now := time.Now()
+	a.users.users[user.ID].DisabledAt = &now
+}
+
+func TestSetRoleExpiresAccessTokens(t *testing.T) {
+	s, a, _ := newTestAdmin(t)
+	admin := addWithRole(t, a, "admin@example.com", model.RoleAdmin)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SetRole(admin.ID.String(), user.ID.String(), model.SetRoleRequest{Role: "auditor"}); err != nil {
+		t.Fatalf("SetRole() error = %v", err)
+	}
+	if _, err := a.ValidateToken(resp.Token); !errors.Is(err, ErrRevokedToken) {
+		t.Fatalf("ValidateToken() with the old token error = %v, want %v", err, ErrRevokedToken)
+	}
+
+	// Tokens issued within the same microsecond as the cutoff count as
+	// revoked.
+	time.Sleep(time.Millisecond)
+	refreshed, err := a.Refresh(resp.RefreshToken, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err := a.ValidateToken(refreshed.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken() with the refreshed token error = %v", err)
+	}
+	if got := claims["role"]; got != "auditor" {
+		t.Fatalf("refreshed token role = %v, want auditor", got)
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	t.Run("verified", func(t *testing.T) {
+		s, a, _ := newTestAdmin(t)
+		user := a.addUser(t, "root@example.com", "correct horse battery")
+
+		promoted, err := s.BootstrapAdmin(user.Email)
+		if err != nil || !promoted {
+			t.Fatalf("BootstrapAdmin() = %v, %v; want true, nil", promoted, err)
+		}
+		if got := a.users.users[user.ID].Role; got != model.RoleAdmin {
+			t.Fatalf("role = %q, want %q", got, model.RoleAdmin)
+		}
+	})
+
+	t.Run("unverified", func(t *testing.T) {
+		s, a, _ := newTestAdmin(t)
+		user := a.addUser(t, "root@example.com", "correct horse battery")
+		a.users.users[user.ID].EmailVerified = false
+
+		promoted, err := s.BootstrapAdmin(user.Email)
+		if !errors.Is(err, ErrAdminUnverified) || promoted {
+			t.Fatalf("BootstrapAdmin() = %v, %v; want false, %v", promoted, err, ErrAdminUnverified)
+		}
+		if got := a.users.users[user.ID].Role; got != model.RoleUser {
+			t.Fatalf("role = %q, want %q", got, model.RoleUser)
+		}
+	})
+
+	t.Run("admin exists", func(t *testing.T) {
+		s, a, _ := newTestAdmin(t)
+		addWithRole(t, a, "admin@example.com", model.RoleAdmin)
+		user := a.addUser(t, "root@example.com", "correct horse battery")
+
+		promoted, err := s.BootstrapAdmin(user.Email)
+		if err != nil || promoted {
+			t.Fatalf("BootstrapAdmin() = %v, %v; want false, nil", promoted, err)
+		}
+		if got := a.users.users[user.ID].Role; got != model.RoleUser {
+			t.Fatalf("role = %q, want %q", got, model.RoleUser)
+		}
+	})
+}
+
+func TestForcePasswordResetRevokesPersonalTokens(t *testing.T) {
+	s, a, sender := newTestAdmin(t)
+	admin := addWithRole(t, a, "admin@example.com", model.RoleAdmin)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	id := uuid.New()
+	a.pats.tokens[id] = &model.PersonalAccessToken{ID: id, UserID: user.ID, TokenHash: "hash"}
+
+	if err := s.ForcePasswordReset(admin.ID.String(), user.ID.String()); err != nil {
+		t.Fatalf("ForcePasswordReset() error = %v", err)
+	}
+	if len(a.pats.tokens) != 0 {
+		t.Fatalf("personal tokens = %+v, want none", a.pats.tokens)
+	}
+	if a.users.users[user.ID].Password != "" {
+		t.Fatal("password was not cleared")
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sender.sent))
+	}
+}
+
+func TestAdministratorsCannotManageHigherRoles(t *testing.T) {
+	s, a, _ := newTestAdmin(t)
+	if _, err := s.roles.Create(model.RoleRequest{Name: "support", Permissions: []string{model.PermissionUsersRead, model.PermissionUsersManage}}); err != nil {
+		t.Fatal(err)
+	}
+	support := addWithRole(t, a, "support@example.com", "support")
+	admin := addWithRole(t, a, "admin@example.com", model.RoleAdmin)
+	auditor := addWithRole(t, a, "auditor@example.com", "auditor")
+	user := addWithRole(t, a, "alice@example.com", model.RoleUser)
+
+	actor := support.ID.String()
+	if _, err := s.DisableUser(actor, admin.ID.String()); !errors.Is(err, ErrOutranked) {
+		t.Fatalf("DisableUser() of an admin error = %v, want %v", err, ErrOutranked)
+	}
+	if err := s.ForcePasswordReset(actor, admin.ID.String()); !errors.Is(err, ErrOutranked) {
+		t.Fatalf("ForcePasswordReset() of an admin error = %v, want %v", err, ErrOutranked)
+	}
+	if a.users.users[admin.ID].DisabledAt != nil || a.users.users[admin.ID].Password == "" {
+		t.Fatal("the admin account was changed")
+	}
+
+	// Roles whose permissions are a subset of the actor's can be managed.
+	if _, err := s.DisableUser(actor, auditor.ID.String()); err != nil {
+		t.Fatalf("DisableUser() of an auditor error = %v", err)
+	}
+	if err := s.ForcePasswordReset(actor, user.ID.String()); err != nil {
+		t.Fatalf("ForcePasswordReset() of a user error = %v", err)
+	}
+
+	// Admins manage everyone but themselves.
+	if _, err := s.DisableUser(admin.ID.String(), support.ID.String()); err != nil {
+		t.Fatalf("DisableUser() by an admin error = %v", err)
+	}
+}
+
+func TestForcePasswordResetRefusesSelf(t *testing.T) {
+	s, a, sender := newTestAdmin(t)
+	admin := addWithRole(t, a, "admin@example.com", model.RoleAdmin)
+
+	if err := s.ForcePasswordReset(admin.ID.String(), admin.ID.String()); !errors.Is(err, ErrSelfAdministration) {
+		t.Fatalf("ForcePasswordReset() error = %v, want %v", err, ErrSelfAdministration)
+	}
+	if a.users.users[admin.ID].Password == "" || len(sender.sent) != 0 {
+		t.Fatal("the admin's password was reset")
+	}
+}
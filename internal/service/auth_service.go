@@ -4,10 +4,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/devvdark0/todo/internal/auth"
 	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/storage"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
@@ -17,27 +19,144 @@ var (
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrExpiredToken       = errors.New("token has expired")
 	ErrEmailInUse         = errors.New("email already in use")
+	ErrTokenReuse         = errors.New("refresh token reuse detected")
+	ErrRevokedToken       = errors.New("token has been revoked")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	// ErrVerificationNotSent means the account was created but its
+	// verification email could not be sent; the user can request another.
+	ErrVerificationNotSent = errors.New("verification email not sent")
 )
 
 type UserStorage interface {
 	Create(user model.User) error
 	GetByID(id uuid.UUID) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
+	ReplacePassword(id uuid.UUID, current, replacement string) (bool, error)
+}
+
+type JWTOptions struct {
+	Secret []byte
+	// Issuer is the iss of every token. Access tokens are issued for
+	// Audience, while login challenges are addressed to Issuer itself, so
+	// that services verifying access tokens against the published keys
+	// cannot be handed a challenge. They default to "todo" and "todo-api".
+	Issuer               string
+	Audience             string
+	TokenTTL             time.Duration
+	RefreshTTL           time.Duration
+	SessionTouchInterval time.Duration
+	// RequireVerifiedEmail refuses logins until the user has verified their
+	// email address.
+	RequireVerifiedEmail bool
+	TOTPIssuer           string
+	ChallengeTTL         time.Duration
+	// TOTPKeys encrypts TOTP secrets at rest. It defaults to a key derived
+	// from Secret; without either, enrolling in two-factor authentication
+	// fails with ErrTwoFactorUnavailable.
+	TOTPKeys *auth.SealKeys
+	// Keys signs and verifies tokens. It defaults to HS256 with Secret.
+	Keys *auth.KeySet
+	// Limiter throttles failed password logins; nil disables throttling.
+	Limiter *LoginLimiter
+	// Passwords hashes new passwords. It defaults to auth.DefaultPasswordHasher.
+	Passwords *auth.PasswordHasher
+	// Policy checks new passwords. It defaults to auth.DefaultPasswordPolicy.
+	Policy *auth.PasswordPolicy
+	// OnError receives errors that do not fail the request they happen in,
+	// such as a failed password hash upgrade. They are dropped if it is nil.
+	OnError func(error)
+}
+
+type AuthStores struct {
+	Users         UserStorage
+	RefreshTokens RefreshTokenStorage
+	Sessions      SessionStorage
+	TOTP          TOTPStorage
+	AccessTokens  PersonalTokenStorage
 }
 
 type JWTService struct {
-	secret    []byte
-	tokenTTL  time.Duration
-	userStore UserStorage
+	keys         *auth.KeySet
+	issuer       string
+	audience     string
+	tokenTTL     time.Duration
+	refreshTTL   time.Duration
+	userStore    UserStorage
+	refreshStore RefreshTokenStorage
+	sessionStore SessionStorage
+	revoker      *TokenRevoker
+	touches      *touchThrottle
+	verifier     *EmailVerifier
+	requireEmail bool
+	totpStore    TOTPStorage
+	totpKeys     *auth.SealKeys
+	totpIssuer   string
+	challengeTTL time.Duration
+	patStore     PersonalTokenStorage
+	patTouches   *touchThrottle
+	limiter      *LoginLimiter
+	passwords    *auth.PasswordHasher
+	policy       *auth.PasswordPolicy
+	onError      func(error)
 }
 
-func NewJWTService(
-	secret []byte, tokenTTL time.Duration, userStore UserStorage,
-) *JWTService {
+func NewJWTService(opts JWTOptions, stores AuthStores, revoker *TokenRevoker, verifier *EmailVerifier) *JWTService {
+	keys := opts.Keys
+	if keys == nil {
+		keys = auth.NewHMACKeySet(opts.Secret)
+	}
+	passwords := opts.Passwords
+	if passwords == nil {
+		passwords = auth.DefaultPasswordHasher()
+	}
+	policy := opts.Policy
+	if policy == nil {
+		policy = auth.DefaultPasswordPolicy()
+	}
+	totpKeys := opts.TOTPKeys
+	if totpKeys == nil {
+		var legacy []byte
+		if len(opts.Secret) > 0 {
+			legacy = auth.DeriveKey(opts.Secret, "totp-secret")
+		}
+		totpKeys = auth.NewLegacySealKeys(legacy)
+	}
+	onError := opts.OnError
+	if onError == nil {
+		onError = func(error) {}
+	}
+	issuer := opts.Issuer
+	if issuer == "" {
+		issuer = "todo"
+	}
+	audience := opts.Audience
+	if audience == "" {
+		audience = "todo-api"
+	}
+
 	return &JWTService{
-		secret:    secret,
-		tokenTTL:  tokenTTL,
-		userStore: userStore,
+		keys:         keys,
+		issuer:       issuer,
+		audience:     audience,
+		tokenTTL:     opts.TokenTTL,
+		refreshTTL:   opts.RefreshTTL,
+		userStore:    stores.Users,
+		refreshStore: stores.RefreshTokens,
+		sessionStore: stores.Sessions,
+		revoker:      revoker,
+		touches:      newTouchThrottle(opts.SessionTouchInterval),
+		verifier:     verifier,
+		requireEmail: opts.RequireVerifiedEmail,
+		totpStore:    stores.TOTP,
+		totpKeys:     totpKeys,
+		totpIssuer:   opts.TOTPIssuer,
+		challengeTTL: opts.ChallengeTTL,
+		patStore:     stores.AccessTokens,
+		patTouches:   newTouchThrottle(opts.SessionTouchInterval),
+		limiter:      opts.Limiter,
+		passwords:    passwords,
+		policy:       policy,
+		onError:      onError,
 	}
 }
 
@@ -51,7 +170,11 @@ func (j *JWTService) Register(email, username, password string) error {
 		return err
 	}
 
-	hashedPassword, err := auth.HashPassword(password)
+	if err := j.policy.Check(password, email, username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := j.passwords.Hash(password)
 	if err != nil {
 		return fmt.Errorf("err hashin pass: %w", err)
 	}
@@ -64,44 +187,160 @@ func (j *JWTService) Register(email, username, password string) error {
 	}
 
 	if err = j.userStore.Create(user); err != nil {
+		if errors.Is(err, storage.ErrDuplicateEmail) {
+			return ErrEmailInUse
+		}
 		return fmt.Errorf("user creation err: %w", err)
 	}
 
+	if err = j.verifier.SendVerification(&user); err != nil {
+		return fmt.Errorf("%w: %w", ErrVerificationNotSent, err)
+	}
+
 	return nil
 }
 
-func (j *JWTService) Login(email, password string) (string, error) {
+func (j *JWTService) Login(email, password string, client model.ClientInfo) (*model.LoginResponse, error) {
+	if j.limiter != nil {
+		if err := j.limiter.Begin(email, client.IP); err != nil {
+			return nil, err
+		}
+	}
+
 	user, err := j.userStore.GetByEmail(email)
 	if err != nil {
-		return "", ErrInvalidCredentials
+		// A hash is still checked, so unknown addresses take as long to
+		// refuse as wrong passwords.
+		j.passwords.VerifyMissing(password)
+		return nil, j.loginFailed(email, client, nil)
+	}
+
+	needsRehash, err := j.passwords.Verify(user.Password, password)
+	if err != nil {
+		return nil, j.loginFailed(email, client, &user.ID)
+	}
+	if j.limiter != nil {
+		j.limiter.Release(email, client.IP)
+	}
+	if needsRehash {
+		j.rehashPassword(user, password)
+	}
+
+	if j.requireEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	resp, err := j.completeLogin(user, client)
+	if err != nil {
+		return nil, err
+	}
+	// With a second factor pending the login has not succeeded yet;
+	// CompleteTwoFactorLogin reports it once it has.
+	if j.limiter != nil && !resp.TwoFactorRequired {
+		j.limiter.Success(email)
+	}
+
+	return resp, nil
+}
+
+// rehashPassword upgrades a password hash made under an older policy. The
+// login goes ahead if it fails, so the error is only reported; the next
+// login tries again.
+func (j *JWTService) rehashPassword(user *model.User, password string) {
+	hashed, err := j.passwords.Hash(password)
+	if err != nil {
+		j.onError(fmt.Errorf("rehash password: %w", err))
+		return
+	}
+	replaced, err := j.userStore.ReplacePassword(user.ID, user.Password, hashed)
+	if err != nil {
+		j.onError(fmt.Errorf("rehash password: %w", err))
+		return
+	}
+	if replaced {
+		user.Password = hashed
+	}
+}
+
+// loginFailed ends a password login begun with the limiter as failed and
+// returns the error to report for it.
+func (j *JWTService) loginFailed(email string, client model.ClientInfo, userID *uuid.UUID) error {
+	if j.limiter == nil {
+		return ErrInvalidCredentials
+	}
+	if err := j.limiter.Failure(email, client.IP, userID); err != nil {
+		return fmt.Errorf("login service: %w", err)
+	}
+	return ErrInvalidCredentials
+}
+
+// completeLogin finishes a login whose first factor has been checked: it
+// either asks for the second factor or starts a session.
+func (j *JWTService) completeLogin(user *model.User, client model.ClientInfo) (*model.LoginResponse, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	enabled, err := j.twoFactorEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return j.newChallenge(user)
+	}
+
+	sessionID, err := j.startSession(user.ID, client)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = auth.VerifyPassword(user.Password, password); err != nil {
-		return "", ErrInvalidCredentials
+	return j.issueTokens(user, sessionID)
+}
+
+// issueTokens returns a fresh access token and a refresh token for a session.
+// The session id doubles as the refresh token rotation family.
+func (j *JWTService) issueTokens(user *model.User, sessionID uuid.UUID) (*model.LoginResponse, error) {
+	token, err := j.generateToken(user, sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := j.generateToken(user)
+	refreshToken, err := j.newRefreshToken(user.ID, sessionID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return token, nil
+	return &model.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(j.tokenTTL.Seconds()),
+	}, nil
 }
 
-func (j *JWTService) generateToken(user *model.User) (string, error) {
-	expirationTime := time.Now().Add(j.tokenTTL)
+func (j *JWTService) generateToken(user *model.User, sessionID uuid.UUID) (string, error) {
+	now := time.Now()
+	expirationTime := now.Add(j.tokenTTL)
+
+	role := user.Role
+	if role == "" {
+		role = model.RoleUser
+	}
 
 	claims := jwt.MapClaims{
+		"jti":      uuid.NewString(),
+		"typ":      tokenTypeAccess,
+		"iss":      j.issuer,
+		"aud":      j.audience,
+		"sid":      sessionID.String(),
 		"sub":      user.ID.String(),
 		"username": user.Username,
 		"email":    user.Email,
+		"role":     role,
 		"exp":      expirationTime.Unix(),
-		"iat":      time.Now().Unix(),
+		"iat":      preciseTime(now),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString(j.secret)
+	tokenString, err := j.keys.Sign(claims, headerTypeAccess)
 	if err != nil {
 		return "", fmt.Errorf("token generation err: %w", err)
 	}
@@ -110,13 +349,34 @@ func (j *JWTService) generateToken(user *model.User) (string, error) {
 }
 
 func (j *JWTService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
+	claims, err := j.parseToken(tokenString, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := j.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
 
-		return j.secret, nil
-	})
+// parseToken verifies a token's signature, expiry, issuer and audience and
+// that it was issued as the given type, so that e.g. a login challenge cannot
+// be used as an access token.
+func (j *JWTService) parseToken(tokenString, typ string) (jwt.MapClaims, error) {
+	header, audience := headerTypeAccess, j.audience
+	if typ == tokenTypeChallenge {
+		header, audience = headerTypeChallenge, j.issuer
+	}
+
+	token, err := jwt.Parse(
+		tokenString,
+		j.keys.Keyfunc,
+		jwt.WithValidMethods(j.keys.Methods()),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(audience),
+	)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -126,9 +386,58 @@ func (j *JWTService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
 	}
 
-	return nil, ErrInvalidToken
+	if claimed, _ := claims["typ"].(string); claimed != typ {
+		return nil, ErrInvalidToken
+	}
+	if claimed, _ := token.Header["typ"].(string); claimed != header {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (j *JWTService) checkRevoked(claims jwt.MapClaims) error {
+	userID, err := subjectID(claims)
+	if err != nil {
+		return err
+	}
+
+	iat, ok := claimedIssuedAt(claims)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	if j.revoker.IsRevoked(jti, claimedSessionID(claims), userID, iat) {
+		return ErrRevokedToken
+	}
+
+	return nil
+}
+
+// preciseTime is t as a NumericDate with microsecond precision, which keeps
+// a token issued right after a revocation cutoff distinguishable from one
+// issued right before it.
+func preciseTime(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// claimedIssuedAt reads iat without rounding it to whole seconds the way
+// MapClaims.GetIssuedAt does.
+func claimedIssuedAt(claims jwt.MapClaims) (time.Time, bool) {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMicro(int64(math.Round(iat * 1e6))), true
+}
+
+// JWKS returns the public keys access tokens can be verified with.
+func (j *JWTService) JWKS() auth.JWKSet {
+	return j.keys.JWKS()
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestTokensAreBoundToTheirUse(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	access, err := a.generateToken(user, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := a.newChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.ValidateToken(access)
+	if err != nil {
+		t.Fatalf("ValidateToken(access) error = %v", err)
+	}
+	if aud, _ := claims.GetAudience(); len(aud) != 1 || aud[0] != "todo-api" {
+		t.Errorf("access token aud = %v, want [todo-api]", aud)
+	}
+	if iss, _ := claims.GetIssuer(); iss != "todo" {
+		t.Errorf("access token iss = %q, want todo", iss)
+	}
+	header := parseHeader(t, access)
+	if header["typ"] != headerTypeAccess {
+		t.Errorf("access token typ header = %v, want %s", header["typ"], headerTypeAccess)
+	}
+
+	if _, err := a.ValidateToken(challenge.ChallengeToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateToken(challenge) error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := a.parseToken(access, tokenTypeChallenge); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("parseToken(access, challenge) error = %v, want %v", err, ErrInvalidToken)
+	}
+	if header := parseHeader(t, challenge.ChallengeToken); header["typ"] != headerTypeChallenge {
+		t.Errorf("challenge typ header = %v, want %s", header["typ"], headerTypeChallenge)
+	}
+}
+
+func TestTokensFromAnotherIssuerAreRejected(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	other := newTestAuth(t)
+	other.issuer = "elsewhere"
+	token, err := other.generateToken(user, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ValidateToken() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func parseHeader(t *testing.T, token string) map[string]any {
+	t.Helper()
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Header
+}
+
+type failingReplace struct {
+	*memoryUsers
+}
+
+func (failingReplace) ReplacePassword(uuid.UUID, string, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestLoginReportsFailedRehash(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	a.userStore = failingReplace{a.users}
+	// The user's bcrypt hash is outdated once the policy asks for argon2id.
+	a.passwords = auth.DefaultPasswordHasher()
+
+	var reported []error
+	a.onError = func(err error) { reported = append(reported, err) }
+
+	if _, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("reported errors = %v, want the failed rehash", reported)
+	}
+}
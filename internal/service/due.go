@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+)
+
+var ErrInvalidDueDate = errors.New("invalid due date")
+
+const dueDateLayout = "2006-01-02"
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidDueDate, name)
+	}
+
+	return loc, nil
+}
+
+// parseDueDate accepts either a bare date (YYYY-MM-DD), which is treated as
+// due by the end of that day in the given timezone, or a full RFC 3339
+// timestamp. An empty value means the task has no due date.
+func parseDueDate(value, timezone string) (dueAt *time.Time, allDay bool, err error) {
+	if value == "" {
+		return nil, false, nil
+	}
+
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if day, err := time.ParseInLocation(dueDateLayout, value, loc); err == nil {
+		end := day.AddDate(0, 0, 1).Add(-time.Second).UTC()
+		return &end, true, nil
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: expected YYYY-MM-DD or RFC 3339, got %q", ErrInvalidDueDate, value)
+	}
+	at = at.UTC()
+
+	return &at, false, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func dueFilter(req model.ListTasksRequest, now time.Time) (model.TaskFilter, error) {
+	var filter model.TaskFilter
+
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return filter, err
+	}
+	now = now.In(loc)
+
+	switch req.Due {
+	case model.DueOverdue:
+		to := now.UTC()
+		open := false
+		filter.DueTo = &to
+		filter.IsDone = &open
+	case model.DueToday:
+		from := startOfDay(now).UTC()
+		to := startOfDay(now).AddDate(0, 0, 1).UTC()
+		filter.DueFrom = &from
+		filter.DueTo = &to
+	}
+
+	if req.DueWithin > 0 {
+		from := now.UTC()
+		to := now.AddDate(0, 0, req.DueWithin).UTC()
+		if filter.DueFrom == nil || filter.DueFrom.Before(from) {
+			filter.DueFrom = &from
+		}
+		if filter.DueTo == nil || filter.DueTo.After(to) {
+			filter.DueTo = &to
+		}
+	}
+
+	return filter, nil
+}
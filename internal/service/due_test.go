@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+)
+
+func TestParseDueDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		timezone   string
+		want       string
+		wantAllDay bool
+		wantErr    bool
+	}{
+		{"no due date", "", "", "", false, false},
+		{"bare date in UTC", "2026-03-14", "", "2026-03-14T23:59:59Z", true, false},
+		{"bare date in a timezone", "2026-03-14", "Europe/Berlin", "2026-03-14T22:59:59Z", true, false},
+		{"timestamp", "2026-03-14T09:30:00+02:00", "", "2026-03-14T07:30:00Z", false, false},
+		{"timestamp ignores the timezone", "2026-03-14T09:30:00Z", "Asia/Tokyo", "2026-03-14T09:30:00Z", false, false},
+		{"unknown timezone", "2026-03-14", "Mars/Olympus", "", false, true},
+		{"garbage", "next tuesday", "", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dueAt, allDay, err := parseDueDate(tt.value, tt.timezone)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDueDate) {
+					t.Fatalf("parseDueDate() error = %v, want %v", err, ErrInvalidDueDate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDueDate() error = %v", err)
+			}
+
+			var got string
+			if dueAt != nil {
+				got = dueAt.Format(time.RFC3339)
+			}
+			if got != tt.want || allDay != tt.wantAllDay {
+				t.Fatalf("parseDueDate() = %q, %t; want %q, %t", got, allDay, tt.want, tt.wantAllDay)
+			}
+		})
+	}
+}
+
+func TestDueFilter(t *testing.T) {
+	// 23:30 in UTC is already the next day in Berlin.
+	now := time.Date(2026, 3, 14, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      model.ListTasksRequest
+		wantFrom string
+		wantTo   string
+		wantOpen bool
+	}{
+		{"no filter", model.ListTasksRequest{}, "", "", false},
+		{"overdue", model.ListTasksRequest{Due: model.DueOverdue}, "", "2026-03-14T23:30:00Z", true},
+		{"today in UTC", model.ListTasksRequest{Due: model.DueToday}, "2026-03-14T00:00:00Z", "2026-03-15T00:00:00Z", false},
+		{"today in a timezone", model.ListTasksRequest{Due: model.DueToday, Timezone: "Europe/Berlin"}, "2026-03-14T23:00:00Z", "2026-03-15T23:00:00Z", false},
+		{"within days", model.ListTasksRequest{DueWithin: 7}, "2026-03-14T23:30:00Z", "2026-03-21T23:30:00Z", false},
+		{"today narrowed to within", model.ListTasksRequest{Due: model.DueToday, DueWithin: 1}, "2026-03-14T23:30:00Z", "2026-03-15T00:00:00Z", false},
+		{"overdue and within never overlap", model.ListTasksRequest{Due: model.DueOverdue, DueWithin: 3}, "2026-03-14T23:30:00Z", "2026-03-14T23:30:00Z", true},
+	}
+
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := dueFilter(tt.req, now)
+			if err != nil {
+				t.Fatalf("dueFilter() error = %v", err)
+			}
+			if got := format(filter.DueFrom); got != tt.wantFrom {
+				t.Fatalf("DueFrom = %q, want %q", got, tt.wantFrom)
+			}
+			if got := format(filter.DueTo); got != tt.wantTo {
+				t.Fatalf("DueTo = %q, want %q", got, tt.wantTo)
+			}
+			if open := filter.IsDone != nil && !*filter.IsDone; open != tt.wantOpen {
+				t.Fatalf("only open tasks = %t, want %t", open, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestDueFilterUnknownTimezone(t *testing.T) {
+	_, err := dueFilter(model.ListTasksRequest{Due: model.DueToday, Timezone: "Mars/Olympus"}, time.Now())
+	if !errors.Is(err, ErrInvalidDueDate) {
+		t.Fatalf("dueFilter() error = %v, want %v", err, ErrInvalidDueDate)
+	}
+}
+
+func TestListTasksExplicitIsDoneWinsOverOverdue(t *testing.T) {
+	implied, requested := false, true
+	if got := mergeIsDone(&implied, &requested); got == nil || !*got {
+		t.Fatalf("mergeIsDone() = %v, want true", got)
+	}
+	if got := mergeIsDone(&implied, nil); got == nil || *got {
+		t.Fatalf("mergeIsDone() = %v, want false", got)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LoginThrottledError is returned while logins for an account or from an
+// address are held back. It matches ErrTooManyAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	// Locked is set for a lockout, as opposed to the backoff between
+	// attempts.
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "login temporarily locked after too many failed attempts"
+	}
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+type AuditStorage interface {
+	Create(entry model.AuditEntry) error
+}
+
+type LoginLimitOptions struct {
+	// MaxAccountFailures and MaxIPFailures are the failed attempts that lock
+	// an account or address. Zero disables the lockout.
+	MaxAccountFailures int
+	MaxIPFailures      int
+	// BackoffAfter failures, each further attempt has to wait BackoffBase,
+	// doubling per failure up to BackoffMax.
+	BackoffAfter    int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures are remembered without new ones.
+	FailureWindow time.Duration
+}
+
+// LoginLimiter tracks failed logins per account and per client address in
+// memory. Repeated failures slow down further attempts, and passing a
+// threshold locks the account or address for a while.
+//
+// A password login is bracketed by Begin and one of Failure or Release.
+// Begin counts the attempt as pending, and pending attempts hold back
+// further ones just like failures do, so guesses sent in parallel cannot
+// all get past the check before the first of them has failed.
+type LoginLimiter struct {
+	opts  LoginLimitOptions
+	audit AuditStorage
+
+	mu        sync.Mutex
+	counters  map[string]*failureCounter
+	lastPrune time.Time
+}
+
+type failureCounter struct {
+	failures    int
+	pending     int
+	lastFailure time.Time
+	// lastBegin is when the latest attempt began. It stands in for
+	// lastFailure only while attempts are pending, so released ones leave
+	// neither the backoff nor the failure window behind.
+	lastBegin   time.Time
+	lockedUntil time.Time
+}
+
+type limitKey struct {
+	id          string
+	subject     string
+	kind        string
+	maxFailures int
+}
+
+func NewLoginLimiter(opts LoginLimitOptions, audit AuditStorage) *LoginLimiter {
+	return &LoginLimiter{
+		opts:     opts,
+		audit:    audit,
+		counters: make(map[string]*failureCounter),
+	}
+}
+
+// Begin starts a login attempt for email from ip. It returns a
+// *LoginThrottledError if the attempt has to wait; otherwise the caller
+// must end it with Failure or Release.
+func (l *LoginLimiter) Begin(email, ip string) error {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := l.keys(email, ip)
+	var throttled *LoginThrottledError
+	for _, key := range keys {
+		counter, ok := l.counters[key.id]
+		if !ok {
+			continue
+		}
+		wait, locked := l.wait(counter, now)
+		if wait > 0 && (throttled == nil || wait > throttled.RetryAfter) {
+			throttled = &LoginThrottledError{RetryAfter: wait, Locked: locked}
+		}
+	}
+	if throttled != nil {
+		return throttled
+	}
+
+	for _, key := range keys {
+		counter, ok := l.counters[key.id]
+		if !ok || l.forgotten(counter, now) {
+			counter = &failureCounter{}
+			l.counters[key.id] = counter
+		}
+		counter.pending++
+		// Pending attempts count from now, so the backoff they cause
+		// outlasts the window in which they could be answered.
+		counter.lastBegin = now
+	}
+
+	return nil
+}
+
+// Failure ends an attempt started with Begin as failed and writes an audit
+// entry for every lockout it causes. userID is set if email belongs to a
+// user.
+func (l *LoginLimiter) Failure(email, ip string, userID *uuid.UUID) error {
+	return l.fail(l.keys(email, ip), ip, userID, true)
+}
+
+// SecondFactorFailure records a wrong second factor against the account of
+// email. It is not bracketed by Begin: the password was right, and the
+// attempt counts against the account alone.
+func (l *LoginLimiter) SecondFactorFailure(email string, userID *uuid.UUID) error {
+	return l.fail(l.keys(email, ""), "", userID, false)
+}
+
+// Release ends an attempt started with Begin without judging it, once the
+// password has been accepted. Whether the login succeeds is only known
+// once a session has been issued; see Success.
+func (l *LoginLimiter) Release(email, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range l.keys(email, ip) {
+		if counter, ok := l.counters[key.id]; ok && counter.pending > 0 {
+			counter.pending--
+		}
+	}
+}
+
+// Success forgets the failures of an account once a session has been
+// issued for it. Address failures are kept, so one valid account cannot be
+// used to reset an attacker's address.
+func (l *LoginLimiter) Success(email string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if counter, ok := l.counters[accountKey(email)]; ok {
+		counter.failures = 0
+		counter.lockedUntil = time.Time{}
+	}
+}
+
+func (l *LoginLimiter) fail(keys []limitKey, ip string, userID *uuid.UUID, begun bool) error {
+	now := time.Now()
+
+	var lockouts []model.AuditEntry
+	l.mu.Lock()
+	l.prune(now)
+	for _, key := range keys {
+		counter, ok := l.counters[key.id]
+		if !ok || l.forgotten(counter, now) {
+			counter = &failureCounter{}
+			l.counters[key.id] = counter
+		}
+		if begun && counter.pending > 0 {
+			counter.pending--
+		}
+		counter.failures++
+		counter.lastFailure = now
+
+		if key.maxFailures > 0 && counter.failures >= key.maxFailures && !now.Before(counter.lockedUntil) {
+			counter.lockedUntil = now.Add(l.opts.LockoutDuration)
+			entry := model.AuditEntry{
+				ID:        uuid.New(),
+				Event:     model.AuditLoginLockout,
+				Subject:   key.subject,
+				IP:        ip,
+				Detail:    fmt.Sprintf("%s locked for %s after %d failed attempts", key.kind, l.opts.LockoutDuration, counter.failures),
+				CreatedAt: now,
+			}
+			if key.kind == "account" {
+				entry.UserID = userID
+			}
+			lockouts = append(lockouts, entry)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, entry := range lockouts {
+		if err := l.audit.Create(entry); err != nil {
+			return fmt.Errorf("login limiter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *LoginLimiter) keys(email, ip string) []limitKey {
+	keys := []limitKey{{
+		id:          accountKey(email),
+		subject:     normalizeEmail(email),
+		kind:        "account",
+		maxFailures: l.opts.MaxAccountFailures,
+	}}
+	if ip != "" {
+		keys = append(keys, limitKey{
+			id:          "ip:" + ip,
+			subject:     ip,
+			kind:        "ip",
+			maxFailures: l.opts.MaxIPFailures,
+		})
+	}
+	return keys
+}
+
+func (l *LoginLimiter) wait(counter *failureCounter, now time.Time) (time.Duration, bool) {
+	if now.Before(counter.lockedUntil) {
+		return counter.lockedUntil.Sub(now), true
+	}
+	attempts := counter.failures + counter.pending
+	if l.opts.BackoffAfter <= 0 || attempts < l.opts.BackoffAfter || l.forgotten(counter, now) {
+		return 0, false
+	}
+
+	backoff := l.opts.BackoffBase
+	for i := l.opts.BackoffAfter; i < attempts && backoff < l.opts.BackoffMax; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, l.opts.BackoffMax)
+
+	if next := l.lastAttempt(counter).Add(backoff); now.Before(next) {
+		return next.Sub(now), false
+	}
+	return 0, false
+}
+
+// lastAttempt is the time further attempts are held back from: the last
+// failure, or the last begun attempt while any are pending.
+func (l *LoginLimiter) lastAttempt(counter *failureCounter) time.Time {
+	if counter.pending > 0 && counter.lastBegin.After(counter.lastFailure) {
+		return counter.lastBegin
+	}
+	return counter.lastFailure
+}
+
+func (l *LoginLimiter) forgotten(counter *failureCounter, now time.Time) bool {
+	return counter.pending == 0 && !now.Before(counter.lockedUntil) && now.Sub(counter.lastFailure) > l.opts.FailureWindow
+}
+
+// prune drops forgotten counters, at most once a minute.
+func (l *LoginLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for id, counter := range l.counters {
+		if l.forgotten(counter, now) {
+			delete(l.counters, id)
+		}
+	}
+}
+
+func accountKey(email string) string {
+	return "account:" + normalizeEmail(email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+)
+
+type memoryAudit struct {
+	entries []model.AuditEntry
+}
+
+func (m *memoryAudit) Create(entry model.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func newTestLimiter() *LoginLimiter {
+	return NewLoginLimiter(LoginLimitOptions{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      50,
+		BackoffAfter:       3,
+		BackoffBase:        time.Minute,
+		BackoffMax:         time.Hour,
+		LockoutDuration:    time.Hour,
+		FailureWindow:      time.Hour,
+	}, &memoryAudit{})
+}
+
+func TestLoginLimiterHoldsBackParallelAttempts(t *testing.T) {
+	l := newTestLimiter()
+
+	// Nothing has failed yet, but three attempts are still in flight.
+	for i := 0; i < 3; i++ {
+		if err := l.Begin("alice@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Begin() #%d error = %v", i+1, err)
+		}
+	}
+	if err := l.Begin("alice@example.com", "10.0.0.2"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Begin() with three pending attempts error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	// Attempts whose password was right do not count.
+	l2 := newTestLimiter()
+	for i := 0; i < 5; i++ {
+		if err := l2.Begin("bob@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Begin() #%d error = %v", i+1, err)
+		}
+		l2.Release("bob@example.com", "10.0.0.1")
+	}
+}
+
+func TestReleasedAttemptsDoNotRearmTheBackoff(t *testing.T) {
+	l := newTestLimiter()
+	const ip = "10.0.0.1"
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := l.Begin(email, ip); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Failure(email, ip, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The address has waited out its backoff; logins that get past the
+	// password check must not start it again.
+	counter := l.counters["ip:"+ip]
+	counter.lastFailure = counter.lastFailure.Add(-2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		if err := l.Begin("alice@example.com", ip); err != nil {
+			t.Fatalf("Begin() #%d error = %v", i+1, err)
+		}
+		l.Release("alice@example.com", ip)
+		l.Success("alice@example.com")
+	}
+
+	// Once the failure window has passed, the failures are forgotten.
+	counter.lastFailure = counter.lastFailure.Add(-2 * time.Hour)
+	if err := l.Begin("alice@example.com", ip); err != nil {
+		t.Fatal(err)
+	}
+	l.Release("alice@example.com", ip)
+	if got := l.counters["ip:"+ip]; got.failures != 0 {
+		t.Fatalf("%d failures remembered after the window, want 0", got.failures)
+	}
+}
+
+func TestLoginSucceedsOnlyOnceASessionIsIssued(t *testing.T) {
+	a := newTestAuth(t)
+	a.limiter = newTestLimiter()
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	enrolTestTOTP(t, a, user)
+
+	client := model.ClientInfo{IP: "10.0.0.1"}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Login(user.Email, "wrong", client); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+		}
+	}
+
+	resp, err := a.Login(user.Email, "correct horse battery", client)
+	if err != nil || !resp.TwoFactorRequired {
+		t.Fatalf("Login() = %+v, %v; want a two-factor challenge", resp, err)
+	}
+
+	// The password was right but the second factor was not: the earlier
+	// failures still stand and the wrong code adds to them.
+	_, err = a.CompleteTwoFactorLogin(model.TwoFactorLoginRequest{ChallengeToken: resp.ChallengeToken, Code: "000000"}, client)
+	if !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("CompleteTwoFactorLogin() error = %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+	if _, err := a.Login(user.Email, "correct horse battery", client); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() after three failures error = %v, want %v", err, ErrTooManyAttempts)
+	}
+}
+
+func TestWrongSecondFactorWhenDisablingCountsAgainstAccount(t *testing.T) {
+	a := newTestAuth(t)
+	a.limiter = newTestLimiter()
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	enrolTestTOTP(t, a, user)
+
+	for i := 0; i < 3; i++ {
+		err := a.DisableTOTP(user.ID.String(), model.DisableTOTPRequest{Password: "wrong", Code: "000000"})
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("DisableTOTP() error = %v, want %v", err, ErrInvalidCredentials)
+		}
+	}
+
+	if _, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrTooManyAttempts)
+	}
+}
+
+// enrolTestTOTP gives user a confirmed TOTP enrolment.
+func enrolTestTOTP(t *testing.T, a *testAuth, user *model.User) string {
+	t.Helper()
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := a.totpKeys.Seal(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a.totp.enrolments[user.ID] = &model.TOTP{UserID: user.ID, Secret: sealed, ConfirmedAt: &now}
+	return secret
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Logout ends the session the access token described by claims belongs to.
+// Tokens issued before sessions existed carry no sid; for those the token
+// itself and, when given, the refresh token family are revoked instead.
+func (j *JWTService) Logout(claims jwt.MapClaims, refreshToken string) error {
+	userID, err := subjectID(claims)
+	if err != nil {
+		return err
+	}
+
+	if sessionID := claimedSessionID(claims); sessionID != uuid.Nil {
+		if err := j.endSession(sessionID, userID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("logout service: %w", err)
+		}
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti != "" {
+		exp, err := claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return ErrInvalidToken
+		}
+		if err := j.revoker.RevokeToken(jti, userID, exp.Time); err != nil {
+			return fmt.Errorf("logout service: %w", err)
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := j.refreshStore.GetByHash(auth.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("logout service: %w", err)
+	}
+	if stored.UserID != userID {
+		return nil
+	}
+
+	if err := j.refreshStore.RevokeFamily(stored.FamilyID, time.Now()); err != nil {
+		return fmt.Errorf("logout service: %w", err)
+	}
+
+	return nil
+}
+
+// LogoutAll ends every session and revokes every access and refresh token
+// the user holds. Personal access tokens are not sessions and are kept; see
+// RevokePersonalTokens.
+func (j *JWTService) LogoutAll(userID string) error {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("logout all service: %w", err)
+	}
+
+	if err := j.sessionStore.RevokeUser(uuidUserID, time.Now()); err != nil {
+		return fmt.Errorf("logout all service: %w", err)
+	}
+
+	if err := j.refreshStore.RevokeUser(uuidUserID, time.Now()); err != nil {
+		return fmt.Errorf("logout all service: %w", err)
+	}
+
+	if err := j.revoker.RevokeUser(uuidUserID); err != nil {
+		return fmt.Errorf("logout all service: %w", err)
+	}
+
+	return nil
+}
+
+// LogoutOthers ends every session of the user except the one claims belong
+// to. Without claims, as for personal access tokens, every session ends.
+func (j *JWTService) LogoutOthers(userID string, claims jwt.MapClaims) error {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("logout others service: %w", err)
+	}
+
+	sessions, err := j.sessionStore.ListActive(uuidUserID, time.Now())
+	if err != nil {
+		return fmt.Errorf("logout others service: %w", err)
+	}
+
+	current := claimedSessionID(claims)
+	for _, session := range sessions {
+		if session.ID == current {
+			continue
+		}
+		if err := j.endSession(session.ID, uuidUserID); err != nil {
+			return fmt.Errorf("logout others service: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ExpireAccessTokens revokes the user's access tokens but keeps their
+// sessions, so clients pick up changed claims with their next refresh.
+func (j *JWTService) ExpireAccessTokens(userID uuid.UUID) error {
+	if err := j.revoker.RevokeUser(userID); err != nil {
+		return fmt.Errorf("expire access tokens service: %w", err)
+	}
+
+	return nil
+}
+
+func subjectID(claims jwt.MapClaims) (uuid.UUID, error) {
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return userID, nil
+}
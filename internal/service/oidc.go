@@ -0,0 +1,277 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/oidc"
+	"github.com/devvdark0/todo/internal/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownProvider    = errors.New("unknown identity provider")
+	ErrInvalidOIDCState   = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotProven = errors.New("identity provider did not return a verified email address")
+	// ErrOIDCAccountUnverified is returned when the provider's address
+	// belongs to a local account that never proved it owns that address.
+	ErrOIDCAccountUnverified = errors.New("an account with this email address exists but has not verified it; log in with its password and verify the address first")
+)
+
+// oidcLoginTTL is how long a user has to complete a login at the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// Provisioned usernames are held to the bounds of UpdateProfileRequest, so
+// that a user can save their profile without renaming themselves first.
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 64
+)
+
+type IdentityStorage interface {
+	Create(identity model.ExternalIdentity) error
+	GetBySubject(provider, subject string) (*model.ExternalIdentity, error)
+}
+
+// OIDCService logs users in through OpenID Connect providers. Provider
+// accounts are linked to local users by subject; the first login links by
+// verified email address or, failing that, creates a new user. Linking needs
+// the address to be verified on both sides, so that an account registered
+// with someone else's address cannot be taken over once they sign in.
+type OIDCService struct {
+	providers  map[string]*oidc.Provider
+	identities IdentityStorage
+	users      UserStorage
+	auth       *JWTService
+	pending    *pendingLogins
+}
+
+func NewOIDCService(providers []*oidc.Provider, identities IdentityStorage, users UserStorage, auth *JWTService) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCService{
+		providers:  byName,
+		identities: identities,
+		users:      users,
+		auth:       auth,
+		pending:    &pendingLogins{logins: make(map[string]pendingLogin)},
+	}
+}
+
+func (s *OIDCService) Providers() model.OIDCProvidersResponse {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return model.OIDCProvidersResponse{Providers: names}
+}
+
+// BeginLogin returns the provider URL to redirect the user to and the state
+// that has to come back with the callback.
+func (s *OIDCService) BeginLogin(providerName string) (redirectURL, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err = oidc.RandomString()
+	if err != nil {
+		return "", "", fmt.Errorf("begin oidc login service: %w", err)
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", fmt.Errorf("begin oidc login service: %w", err)
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", fmt.Errorf("begin oidc login service: %w", err)
+	}
+
+	redirectURL, err = provider.AuthCodeURL(state, nonce, challenge)
+	if err != nil {
+		return "", "", fmt.Errorf("begin oidc login service: %w", err)
+	}
+
+	s.pending.put(state, pendingLogin{
+		provider:  providerName,
+		nonce:     nonce,
+		verifier:  verifier,
+		expiresAt: time.Now().Add(oidcLoginTTL),
+	})
+
+	return redirectURL, state, nil
+}
+
+// FinishLogin completes a login from the provider's callback.
+func (s *OIDCService) FinishLogin(providerName, state, code string, client model.ClientInfo) (*model.LoginResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	login, ok := s.pending.take(state)
+	if !ok || login.provider != providerName {
+		return nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := provider.Exchange(code, login.verifier)
+	if err != nil {
+		return nil, fmt.Errorf("finish oidc login service: %w", err)
+	}
+
+	idToken, err := provider.VerifyIDToken(rawIDToken, login.nonce)
+	if err != nil {
+		return nil, fmt.Errorf("finish oidc login service: %w", err)
+	}
+
+	user, err := s.resolveUser(providerName, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.auth.completeLogin(user, client)
+}
+
+func (s *OIDCService) resolveUser(providerName string, idToken *oidc.IDToken) (*model.User, error) {
+	identity, err := s.identities.GetBySubject(providerName, idToken.Subject)
+	if err == nil {
+		user, err := s.users.GetByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("resolve oidc user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("resolve oidc user: %w", err)
+	}
+
+	// Linking by email is only safe when the provider vouches for it;
+	// otherwise anyone could claim an existing user's address.
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, ErrOIDCEmailNotProven
+	}
+
+	user, err := s.users.GetByEmail(idToken.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if user, err = s.provision(idToken); err != nil {
+			return nil, fmt.Errorf("resolve oidc user: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("resolve oidc user: %w", err)
+	case !user.EmailVerified:
+		return nil, ErrOIDCAccountUnverified
+	}
+
+	identity = &model.ExternalIdentity{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Provider:  providerName,
+		Subject:   idToken.Subject,
+		Email:     idToken.Email,
+		CreatedAt: time.Now(),
+	}
+	if err := s.identities.Create(*identity); err != nil {
+		return nil, fmt.Errorf("resolve oidc user: %w", err)
+	}
+
+	return user, nil
+}
+
+// provision creates a user for a first-time provider login. The user has no
+// password and can only log in through the provider until they reset it.
+func (s *OIDCService) provision(idToken *oidc.IDToken) (*model.User, error) {
+	user := model.User{
+		ID:            uuid.New(),
+		Email:         idToken.Email,
+		EmailVerified: true,
+	}
+	user.Username = provisionedUsername(idToken, user.ID)
+
+	if err := s.users.Create(user); err != nil {
+		// Another login for the same address got there first.
+		if errors.Is(err, storage.ErrDuplicateEmail) {
+			return nil, ErrEmailInUse
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// provisionedUsername picks the first of the provider's preferred username,
+// the user's name and the local part of their address that is still long
+// enough once control characters and surrounding space are dropped and it
+// is cut to maxUsernameLength. If none is, it makes one up from the user ID.
+func provisionedUsername(idToken *oidc.IDToken, userID uuid.UUID) string {
+	localPart, _, _ := strings.Cut(idToken.Email, "@")
+	for _, candidate := range []string{idToken.PreferredUsername, idToken.Name, localPart} {
+		username := strings.TrimSpace(strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, candidate))
+		if runes := []rune(username); len(runes) > maxUsernameLength {
+			username = strings.TrimSpace(string(runes[:maxUsernameLength]))
+		}
+		if utf8.RuneCountInString(username) >= minUsernameLength {
+			return username
+		}
+	}
+
+	return "user-" + userID.String()[:8]
+}
+
+type pendingLogin struct {
+	provider  string
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// pendingLogins keeps the nonce and PKCE verifier of logins in progress on
+// the server, keyed by state. Each state can be redeemed once.
+type pendingLogins struct {
+	mu     sync.Mutex
+	logins map[string]pendingLogin
+}
+
+func (p *pendingLogins) put(state string, login pendingLogin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, pending := range p.logins {
+		if now.After(pending.expiresAt) {
+			delete(p.logins, key)
+		}
+	}
+	p.logins[state] = login
+}
+
+func (p *pendingLogins) take(state string) (pendingLogin, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	login, ok := p.logins[state]
+	if !ok {
+		return pendingLogin{}, false
+	}
+	delete(p.logins, state)
+
+	return login, time.Now().Before(login.expiresAt)
+}
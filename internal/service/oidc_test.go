@@ -0,0 +1,195 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/oidc"
+	"github.com/devvdark0/todo/internal/oidc/mockoidc"
+	"github.com/google/uuid"
+)
+
+type memoryIdentities struct {
+	identities []model.ExternalIdentity
+}
+
+func (m *memoryIdentities) Create(identity model.ExternalIdentity) error {
+	m.identities = append(m.identities, identity)
+	return nil
+}
+
+func (m *memoryIdentities) GetBySubject(provider, subject string) (*model.ExternalIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+const testProvider = "mock"
+
+// newTestOIDC returns an OIDC service that logs in through a mock provider.
+func newTestOIDC(t *testing.T, a *testAuth, identities IdentityStorage) *OIDCService {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + srv.Listener.Addr().String()
+	provider, err := mockoidc.New(mockoidc.Options{Issuer: issuer, ClientID: "todo", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = provider
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return NewOIDCService(
+		[]*oidc.Provider{oidc.NewProvider(oidc.Config{
+			Name:         testProvider,
+			Issuer:       issuer,
+			ClientID:     "todo",
+			ClientSecret: "secret",
+			RedirectURL:  "http://app.test/callback",
+			Scopes:       []string{"openid", "email", "profile"},
+		}, srv.Client())},
+		identities,
+		a.users,
+		a.JWTService,
+	)
+}
+
+// loginAs walks through the provider as email and finishes the login.
+func loginAs(t *testing.T, s *OIDCService, email string) (*model.LoginResponse, error) {
+	t.Helper()
+
+	redirectURL, state, err := s.BeginLogin(testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(redirectURL + "&login_hint=" + url.QueryEscape(email))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	callback, err := resp.Location()
+	if err != nil {
+		t.Fatalf("provider did not redirect back: %v", err)
+	}
+	if got := callback.Query().Get("state"); got != state {
+		t.Fatalf("callback state = %q, want %q", got, state)
+	}
+
+	return s.FinishLogin(testProvider, state, callback.Query().Get("code"), model.ClientInfo{})
+}
+
+func TestOIDCLoginLinksVerifiedAccount(t *testing.T) {
+	a := newTestAuth(t)
+	identities := &memoryIdentities{}
+	s := newTestOIDC(t, a, identities)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	resp, err := loginAs(t, s, "alice@example.com")
+	if err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+	if resp.Token == "" {
+		t.Fatal("FinishLogin() returned no access token")
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != user.ID {
+		t.Fatalf("identities = %+v, want one linked to %s", identities.identities, user.ID)
+	}
+
+	// The second login finds the user by subject.
+	if _, err := loginAs(t, s, "alice@example.com"); err != nil {
+		t.Fatalf("second FinishLogin() error = %v", err)
+	}
+	if len(identities.identities) != 1 {
+		t.Fatalf("second login created another identity: %+v", identities.identities)
+	}
+}
+
+func TestOIDCLoginRefusesUnverifiedAccount(t *testing.T) {
+	a := newTestAuth(t)
+	identities := &memoryIdentities{}
+	s := newTestOIDC(t, a, identities)
+	user := a.addUser(t, "bob@example.com", "correct horse battery")
+	a.users.users[user.ID].EmailVerified = false
+
+	_, err := loginAs(t, s, "bob@example.com")
+	if !errors.Is(err, ErrOIDCAccountUnverified) {
+		t.Fatalf("FinishLogin() error = %v, want %v", err, ErrOIDCAccountUnverified)
+	}
+	if len(identities.identities) != 0 {
+		t.Fatalf("identities = %+v, want none", identities.identities)
+	}
+}
+
+func TestOIDCLoginProvisionsUnknownEmail(t *testing.T) {
+	a := newTestAuth(t)
+	identities := &memoryIdentities{}
+	s := newTestOIDC(t, a, identities)
+
+	if _, err := loginAs(t, s, "carol@example.com"); err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+
+	user, err := a.users.GetByEmail("carol@example.com")
+	if err != nil {
+		t.Fatalf("no user was provisioned: %v", err)
+	}
+	if !user.EmailVerified || user.Password != "" {
+		t.Fatalf("provisioned user = %+v, want verified and without password", user)
+	}
+	if len(identities.identities) != 1 || identities.identities[0].UserID != user.ID {
+		t.Fatalf("identities = %+v, want one linked to %s", identities.identities, user.ID)
+	}
+}
+
+func TestOIDCProvisionRacingLogin(t *testing.T) {
+	a := newTestAuth(t)
+	s := newTestOIDC(t, a, &memoryIdentities{})
+	a.addUser(t, "dave@example.com", "correct horse battery")
+
+	// A concurrent login created the user between the lookup and this one.
+	_, err := s.provision(&oidc.IDToken{Subject: "dave", Email: "dave@example.com", EmailVerified: true})
+	if !errors.Is(err, ErrEmailInUse) {
+		t.Fatalf("provision() error = %v, want %v", err, ErrEmailInUse)
+	}
+}
+
+func TestProvisionedUsername(t *testing.T) {
+	userID := uuid.MustParse("0b7c5d1e-0000-4000-8000-000000000000")
+	long := strings.Repeat("é", 70)
+
+	tests := []struct {
+		name  string
+		token oidc.IDToken
+		want  string
+	}{
+		{"preferred username", oidc.IDToken{PreferredUsername: "alice", Name: "Alice Smith", Email: "a@example.com"}, "alice"},
+		{"name when the username is too short", oidc.IDToken{PreferredUsername: "al", Name: "Alice Smith"}, "Alice Smith"},
+		{"local part of the address", oidc.IDToken{Email: "alice.smith@example.com"}, "alice.smith"},
+		{"control characters and space dropped", oidc.IDToken{PreferredUsername: " \tal\x00ice\n "}, "alice"},
+		{"cut to the maximum length", oidc.IDToken{PreferredUsername: long}, long[:2*maxUsernameLength]},
+		{"made up when nothing fits", oidc.IDToken{PreferredUsername: "  ", Name: "x", Email: "ab@example.com"}, "user-0b7c5d1e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := provisionedUsername(&tt.token, userID); got != tt.want {
+				t.Fatalf("provisionedUsername() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
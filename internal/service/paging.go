@@ -0,0 +1,97 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/storage"
+)
+
+const defaultPageSize = 20
+
+func encodeCursor(cursor model.TaskCursor) string {
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*model.TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+
+	var cursor model.TaskCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidFilter)
+	}
+
+	return &cursor, nil
+}
+
+func parseBound(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	t = t.UTC()
+
+	return &t, nil
+}
+
+func (s *TodoService) pageSize(requested int) int {
+	if requested <= 0 {
+		return min(defaultPageSize, s.maxPageSize)
+	}
+	return min(requested, s.maxPageSize)
+}
+
+// paginate fetches one page using keyset pagination. One extra row is
+// requested to find out whether another page exists in the walking
+// direction; rows fetched backwards are flipped back into display order.
+func (s *TodoService) paginate(list func(model.TaskFilter) ([]model.Task, error), filter model.TaskFilter) (*model.TaskPage, error) {
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	tasks, err := list(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(tasks) > limit
+	if more {
+		tasks = tasks[:limit]
+	}
+
+	backward := filter.After != nil && filter.After.Backward
+	if backward {
+		slices.Reverse(tasks)
+	}
+
+	page := &model.TaskPage{Tasks: tasks, Page: model.PageInfo{Limit: limit}}
+	if len(tasks) == 0 {
+		return page, nil
+	}
+
+	hasNext := (!backward && more) || backward
+	hasPrev := (backward && more) || (!backward && filter.After != nil)
+
+	if hasNext {
+		page.Page.NextCursor = encodeCursor(storage.NewTaskCursor(tasks[len(tasks)-1], filter.Sort, filter.Order, false))
+	}
+	if hasPrev {
+		page.Page.PrevCursor = encodeCursor(storage.NewTaskCursor(tasks[0], filter.Sort, filter.Order, true))
+	}
+
+	return page, nil
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/mail"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrInvalidResetParams = errors.New("invalid password reset request")
+)
+
+type PasswordResetStorage interface {
+	Create(token model.PasswordResetToken) error
+	GetByHash(hash string) (*model.PasswordResetToken, error)
+	Redeem(token model.PasswordResetToken, password string, at time.Time) (bool, error)
+	InvalidateUser(userID uuid.UUID, at time.Time) error
+}
+
+type PasswordUpdater interface {
+	GetByID(id uuid.UUID) (*model.User, error)
+	GetByEmail(email string) (*model.User, error)
+	UpdatePassword(id uuid.UUID, password string) error
+	ClaimResetSend(id uuid.UUID, at, notBefore time.Time) (bool, error)
+}
+
+type PasswordResetOptions struct {
+	TokenTTL time.Duration
+	// ResendInterval is how long ForgotPassword waits before mailing the
+	// same user another link.
+	ResendInterval time.Duration
+	// ResetURL is the page the emailed link points to; the token is appended
+	// as the "token" query parameter.
+	ResetURL string
+	// Hasher hashes the new password. It defaults to
+	// auth.DefaultPasswordHasher.
+	Hasher *auth.PasswordHasher
+	// Policy checks the new password. It defaults to
+	// auth.DefaultPasswordPolicy.
+	Policy *auth.PasswordPolicy
+}
+
+type PasswordResetService struct {
+	users    PasswordUpdater
+	tokens   PasswordResetStorage
+	sender   mail.Sender
+	sessions *JWTService
+	tokenTTL time.Duration
+	resend   time.Duration
+	resetURL string
+	hasher   *auth.PasswordHasher
+	policy   *auth.PasswordPolicy
+}
+
+func NewPasswordResetService(
+	users PasswordUpdater,
+	tokens PasswordResetStorage,
+	sender mail.Sender,
+	sessions *JWTService,
+	opts PasswordResetOptions,
+) *PasswordResetService {
+	hasher := opts.Hasher
+	if hasher == nil {
+		hasher = auth.DefaultPasswordHasher()
+	}
+	policy := opts.Policy
+	if policy == nil {
+		policy = auth.DefaultPasswordPolicy()
+	}
+
+	return &PasswordResetService{
+		users:    users,
+		tokens:   tokens,
+		sender:   sender,
+		sessions: sessions,
+		tokenTTL: opts.TokenTTL,
+		resend:   opts.ResendInterval,
+		resetURL: opts.ResetURL,
+		hasher:   hasher,
+		policy:   policy,
+	}
+}
+
+// ForgotPassword mails a reset link to the address if it belongs to a user
+// who was not sent one within the resend interval. Unknown and throttled
+// addresses succeed silently so the endpoint cannot be used to find out who
+// is registered or to flood a mailbox.
+func (s *PasswordResetService) ForgotPassword(req model.ForgotPasswordRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidResetParams, err)
+	}
+
+	user, err := s.users.GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("forgot password service: %w", err)
+	}
+
+	now := time.Now()
+	claimed, err := s.users.ClaimResetSend(user.ID, now, now.Add(-s.resend))
+	if err != nil {
+		return fmt.Errorf("forgot password service: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	intro := "Use the link below to choose a new password."
+	note := "If you did not ask for a password reset you can ignore this message."
+	if err := s.sendResetLink(user, "Reset your password", intro, note); err != nil {
+		return fmt.Errorf("forgot password service: %w", err)
+	}
+
+	return nil
+}
+
+// ForceReset clears the user's password, ends all their sessions and
+// personal access tokens and mails them a reset link. Until they follow it
+// they can only sign in through a linked identity provider.
+func (s *PasswordResetService) ForceReset(userID uuid.UUID) error {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	if err := s.users.UpdatePassword(user.ID, ""); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	if err := s.sessions.LogoutAll(user.ID.String()); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+	if err := s.sessions.RevokePersonalTokens(user.ID); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	intro := "An administrator has reset your password. Use the link below to choose a new one."
+	note := "You cannot sign in with your old password any more."
+	if err := s.sendResetLink(user, "Your password has been reset", intro, note); err != nil {
+		return fmt.Errorf("force password reset service: %w", err)
+	}
+
+	return nil
+}
+
+// sendResetLink replaces any outstanding reset tokens of the user with a new
+// one and mails it.
+func (s *PasswordResetService) sendResetLink(user *model.User, subject, intro, note string) error {
+	now := time.Now()
+	if err := s.tokens.InvalidateUser(user.ID, now); err != nil {
+		return err
+	}
+
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	token := model.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(s.tokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.tokens.Create(token); err != nil {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n%s It expires in %s.\n\n%s?token=%s\n\n%s\n",
+			user.Username, intro, s.tokenTTL, s.resetURL, raw, note,
+		),
+	}
+	return s.sender.Send(msg)
+}
+
+// ResetPassword sets a new password using a reset token and ends every
+// session and personal access token of the user, since whoever held the old
+// password may still be logged in or have created one.
+func (s *PasswordResetService) ResetPassword(req model.ResetPasswordRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPassword, err)
+	}
+
+	token, err := s.tokens.GetByHash(auth.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("reset password service: %w", err)
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil || now.After(token.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	// The policy is checked before the token is used up, so a rejected
+	// password can be corrected with the same link.
+	user, err := s.users.GetByID(token.UserID)
+	if err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+	if err := s.policy.Check(req.Password, user.Email, user.Username); err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+
+	hashed, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+
+	consumed, err := s.tokens.Redeem(*token, hashed, now)
+	if err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.sessions.LogoutAll(token.UserID.String()); err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+	if err := s.sessions.RevokePersonalTokens(token.UserID); err != nil {
+		return fmt.Errorf("reset password service: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memoryResetTokens struct {
+	tokens map[string]*model.PasswordResetToken
+}
+
+func (m *memoryResetTokens) Create(token model.PasswordResetToken) error {
+	m.tokens[token.TokenHash] = &token
+	return nil
+}
+
+func (m *memoryResetTokens) GetByHash(hash string) (*model.PasswordResetToken, error) {
+	token, ok := m.tokens[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *memoryResetTokens) Redeem(token model.PasswordResetToken, password string, at time.Time) (bool, error) {
+	stored, ok := m.tokens[token.TokenHash]
+	if !ok || stored.UsedAt != nil {
+		return false, nil
+	}
+	stored.UsedAt = &at
+	return true, nil
+}
+
+func (m *memoryResetTokens) InvalidateUser(userID uuid.UUID, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+func newTestResets(t *testing.T) (*PasswordResetService, *testAuth, *memoryMail) {
+	t.Helper()
+
+	a := newTestAuth(t)
+	sender := &memoryMail{}
+	s := NewPasswordResetService(
+		a.users,
+		&memoryResetTokens{tokens: make(map[string]*model.PasswordResetToken)},
+		sender,
+		a.JWTService,
+		PasswordResetOptions{
+			TokenTTL:       time.Hour,
+			ResendInterval: 5 * time.Minute,
+			ResetURL:       "http://app.test/reset",
+			Hasher:         a.passwords,
+		},
+	)
+	return s, a, sender
+}
+
+func TestForgotPasswordThrottlesPerUser(t *testing.T) {
+	s, a, sender := newTestResets(t)
+	alice := a.addUser(t, "alice@example.com", "correct horse battery")
+	a.addUser(t, "bob@example.com", "correct horse battery")
+
+	for i := 0; i < 3; i++ {
+		if err := s.ForgotPassword(model.ForgotPasswordRequest{Email: "alice@example.com"}); err != nil {
+			t.Fatalf("ForgotPassword() #%d error = %v", i+1, err)
+		}
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d messages to alice, want 1", len(sender.sent))
+	}
+
+	// Other users and unknown addresses are not affected.
+	if err := s.ForgotPassword(model.ForgotPasswordRequest{Email: "bob@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if err := s.ForgotPassword(model.ForgotPasswordRequest{Email: "carol@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() for an unknown address error = %v", err)
+	}
+	if len(sender.sent) != 2 || sender.sent[1].To != "bob@example.com" {
+		t.Fatalf("sent = %+v, want a second message to bob", sender.sent)
+	}
+
+	// Once the interval has passed alice can ask again.
+	a.users.resetSentAt[alice.ID] = time.Now().Add(-6 * time.Minute)
+	if err := s.ForgotPassword(model.ForgotPasswordRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if len(sender.sent) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(sender.sent))
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPersonalToken  = errors.New("invalid personal access token")
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+)
+
+type PersonalTokenStorage interface {
+	Create(token model.PersonalAccessToken) error
+	GetByHash(hash string) (*model.PersonalAccessToken, error)
+	List(userID uuid.UUID) ([]model.PersonalAccessToken, error)
+	Touch(id uuid.UUID, at time.Time) error
+	Delete(id, userID uuid.UUID) error
+	DeleteUser(userID uuid.UUID) error
+}
+
+// CreatePersonalToken issues a new personal access token. The token itself
+// is only part of this response; afterwards only its hash is known.
+func (j *JWTService) CreatePersonalToken(userID string, req model.CreatePersonalTokenRequest) (*model.PersonalTokenResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("create personal token service: %w", err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPersonalToken, err)
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidPersonalToken)
+	}
+
+	raw, hash, err := auth.NewPersonalToken()
+	if err != nil {
+		return nil, fmt.Errorf("create personal token service: %w", err)
+	}
+
+	token := model.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    uuidUserID,
+		Name:      req.Name,
+		TokenHash: hash,
+		Scopes:    uniqueScopes(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := j.patStore.Create(token); err != nil {
+		return nil, fmt.Errorf("create personal token service: %w", err)
+	}
+
+	resp := personalTokenResponse(token)
+	resp.Token = raw
+	return &resp, nil
+}
+
+func (j *JWTService) ListPersonalTokens(userID string) ([]model.PersonalTokenResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("list personal tokens service: %w", err)
+	}
+
+	tokens, err := j.patStore.List(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("list personal tokens service: %w", err)
+	}
+
+	resp := make([]model.PersonalTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		resp = append(resp, personalTokenResponse(token))
+	}
+
+	return resp, nil
+}
+
+func (j *JWTService) DeletePersonalToken(tokenID, userID string) error {
+	uuidTokenID, uuidUserID, err := parseIDPair(tokenID, userID)
+	if err != nil {
+		return ErrPersonalTokenNotFound
+	}
+
+	if err := j.patStore.Delete(uuidTokenID, uuidUserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPersonalTokenNotFound
+		}
+		return fmt.Errorf("delete personal token service: %w", err)
+	}
+
+	return nil
+}
+
+// RevokePersonalTokens deletes every personal access token of the user.
+func (j *JWTService) RevokePersonalTokens(userID uuid.UUID) error {
+	if err := j.patStore.DeleteUser(userID); err != nil {
+		return fmt.Errorf("revoke personal tokens service: %w", err)
+	}
+
+	return nil
+}
+
+// AuthenticatePersonalToken resolves a personal access token presented as a
+// bearer token. Last-used timestamps are written at most once per touch
+// interval.
+func (j *JWTService) AuthenticatePersonalToken(raw string) (*model.PersonalAccessToken, error) {
+	token, err := j.patStore.GetByHash(auth.HashToken(raw))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("authenticate personal token service: %w", err)
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	if j.patTouches.allow(token.ID, now) {
+		if err := j.patStore.Touch(token.ID, now); err != nil {
+			return nil, fmt.Errorf("authenticate personal token service: %w", err)
+		}
+	}
+
+	return token, nil
+}
+
+func personalTokenResponse(token model.PersonalAccessToken) model.PersonalTokenResponse {
+	return model.PersonalTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memoryPersonalTokens struct {
+	tokens map[uuid.UUID]*model.PersonalAccessToken
+}
+
+func (m *memoryPersonalTokens) Create(token model.PersonalAccessToken) error {
+	m.tokens[token.ID] = &token
+	return nil
+}
+
+func (m *memoryPersonalTokens) GetByHash(hash string) (*model.PersonalAccessToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryPersonalTokens) List(userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	var tokens []model.PersonalAccessToken
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *memoryPersonalTokens) Touch(id uuid.UUID, at time.Time) error {
+	if token, ok := m.tokens[id]; ok {
+		token.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (m *memoryPersonalTokens) Delete(id, userID uuid.UUID) error {
+	token, ok := m.tokens[id]
+	if !ok || token.UserID != userID {
+		return sql.ErrNoRows
+	}
+	delete(m.tokens, id)
+	return nil
+}
+
+func (m *memoryPersonalTokens) DeleteUser(userID uuid.UUID) error {
+	for id, token := range m.tokens {
+		if token.UserID == userID {
+			delete(m.tokens, id)
+		}
+	}
+	return nil
+}
+
+func TestCreatePersonalToken(t *testing.T) {
+	a := newTestAuth(t)
+	userID := uuid.NewString()
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		req     model.CreatePersonalTokenRequest
+		wantErr error
+	}{
+		{"read only", model.CreatePersonalTokenRequest{Name: "backup", Scopes: []string{model.ScopeTasksRead}}, nil},
+		{"expiring", model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{model.ScopeTasksWrite}, ExpiresAt: &future}, nil},
+		{"no scopes", model.CreatePersonalTokenRequest{Name: "ci"}, ErrInvalidPersonalToken},
+		{"account scope", model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{model.ScopeAccount}}, ErrInvalidPersonalToken},
+		{"unknown scope", model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{"admin"}}, ErrInvalidPersonalToken},
+		{"no name", model.CreatePersonalTokenRequest{Scopes: []string{model.ScopeTasksRead}}, ErrInvalidPersonalToken},
+		{"already expired", model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{model.ScopeTasksRead}, ExpiresAt: &past}, ErrInvalidPersonalToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := a.CreatePersonalToken(userID, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePersonalToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(resp.Token, auth.PersonalTokenPrefix) {
+				t.Fatalf("token %q lacks the %q prefix", resp.Token, auth.PersonalTokenPrefix)
+			}
+			if stored := a.pats.tokens[resp.ID]; stored.TokenHash == resp.Token || stored.TokenHash != auth.HashToken(resp.Token) {
+				t.Fatalf("stored %q, want only the hash of the token", stored.TokenHash)
+			}
+		})
+	}
+
+	// The token is shown once; listings never include it.
+	tokens, err := a.ListPersonalTokens(userID)
+	if err != nil {
+		t.Fatalf("ListPersonalTokens() error = %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("%d tokens listed, want 2", len(tokens))
+	}
+	for _, token := range tokens {
+		if token.Token != "" {
+			t.Fatalf("listed token %q exposes its secret", token.Name)
+		}
+	}
+}
+
+func TestCreatePersonalTokenDropsDuplicateScopes(t *testing.T) {
+	a := newTestAuth(t)
+	resp, err := a.CreatePersonalToken(uuid.NewString(), model.CreatePersonalTokenRequest{
+		Name:   "ci",
+		Scopes: []string{model.ScopeTasksRead, model.ScopeTasksWrite, model.ScopeTasksRead},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resp.Scopes, " "); got != "tasks:read tasks:write" {
+		t.Fatalf("scopes = %q, want %q", got, "tasks:read tasks:write")
+	}
+}
+
+func TestAuthenticatePersonalToken(t *testing.T) {
+	a := newTestAuth(t)
+	userID := uuid.New()
+	resp, err := a.CreatePersonalToken(userID.String(), model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{model.ScopeTasksRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := a.AuthenticatePersonalToken(resp.Token)
+	if err != nil {
+		t.Fatalf("AuthenticatePersonalToken() error = %v", err)
+	}
+	if token.UserID != userID || a.pats.tokens[resp.ID].LastUsedAt == nil {
+		t.Fatalf("token = %+v, want the user's, marked as used", token)
+	}
+
+	if _, err := a.AuthenticatePersonalToken(auth.PersonalTokenPrefix + "unknown"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("AuthenticatePersonalToken() of an unknown token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	a.pats.tokens[resp.ID].ExpiresAt = &expired
+	if _, err := a.AuthenticatePersonalToken(resp.Token); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("AuthenticatePersonalToken() of an expired token error = %v, want %v", err, ErrExpiredToken)
+	}
+}
+
+func TestDeletePersonalToken(t *testing.T) {
+	a := newTestAuth(t)
+	userID := uuid.NewString()
+	resp, err := a.CreatePersonalToken(userID, model.CreatePersonalTokenRequest{Name: "ci", Scopes: []string{model.ScopeTasksRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.DeletePersonalToken(resp.ID.String(), uuid.NewString()); !errors.Is(err, ErrPersonalTokenNotFound) {
+		t.Fatalf("DeletePersonalToken() by another user error = %v, want %v", err, ErrPersonalTokenNotFound)
+	}
+	if err := a.DeletePersonalToken(resp.ID.String(), userID); err != nil {
+		t.Fatalf("DeletePersonalToken() error = %v", err)
+	}
+	if _, err := a.AuthenticatePersonalToken(resp.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("AuthenticatePersonalToken() after delete error = %v, want %v", err, ErrInvalidToken)
+	}
+}
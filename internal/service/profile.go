@@ -0,0 +1,298 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/mail"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/storage"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidProfileParam = errors.New("invalid profile request")
+	ErrInvalidEmailChange  = errors.New("invalid or expired email change link")
+)
+
+const changeEmailPurpose = "change-email"
+
+type ProfileStorage interface {
+	GetByID(id uuid.UUID) (*model.User, error)
+	GetByEmail(email string) (*model.User, error)
+	UpdateProfile(user model.User) error
+	UpdatePassword(id uuid.UUID, password string) error
+	ChangeEmail(id uuid.UUID, current, replacement string) (bool, error)
+}
+
+type ProfileOptions struct {
+	// Secret signs email change links. It must be kept apart from the token
+	// signing secret, which may be empty.
+	Secret  []byte
+	LinkTTL time.Duration
+	// ConfirmURL is the endpoint the email change link points to; the token
+	// is appended as the "token" query parameter.
+	ConfirmURL string
+	Hasher     *auth.PasswordHasher
+	Policy     *auth.PasswordPolicy
+}
+
+// ProfileService lets users manage their own account.
+type ProfileService struct {
+	users      ProfileStorage
+	resets     PasswordResetStorage
+	sender     mail.Sender
+	sessions   *JWTService
+	secret     []byte
+	linkTTL    time.Duration
+	confirmURL string
+	hasher     *auth.PasswordHasher
+	policy     *auth.PasswordPolicy
+}
+
+func NewProfileService(users ProfileStorage, resets PasswordResetStorage, sender mail.Sender, sessions *JWTService, opts ProfileOptions) *ProfileService {
+	hasher := opts.Hasher
+	if hasher == nil {
+		hasher = auth.DefaultPasswordHasher()
+	}
+	policy := opts.Policy
+	if policy == nil {
+		policy = auth.DefaultPasswordPolicy()
+	}
+
+	return &ProfileService{
+		users:      users,
+		resets:     resets,
+		sender:     sender,
+		sessions:   sessions,
+		secret:     opts.Secret,
+		linkTTL:    opts.LinkTTL,
+		confirmURL: opts.ConfirmURL,
+		hasher:     hasher,
+		policy:     policy,
+	}
+}
+
+func (s *ProfileService) GetProfile(userID string) (*model.ProfileResponse, error) {
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("get profile service: %w", err)
+	}
+
+	return toProfileResponse(user), nil
+}
+
+func (s *ProfileService) UpdateProfile(userID string, req model.UpdateProfileRequest) (*model.ProfileResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProfileParam, err)
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return nil, fmt.Errorf("update profile service: %w", err)
+	}
+
+	if req.Username != nil {
+		user.Username = *req.Username
+	}
+	if req.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+
+	if err := s.users.UpdateProfile(*user); err != nil {
+		return nil, fmt.Errorf("update profile service: %w", err)
+	}
+
+	return toProfileResponse(user), nil
+}
+
+// ChangePassword sets a new password after checking the current one, ends
+// every other session of the user and revokes their personal access tokens
+// and outstanding reset links. The session the change was made from stays
+// logged in.
+func (s *ProfileService) ChangePassword(userID string, claims jwt.MapClaims, req model.ChangePasswordRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProfileParam, err)
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+
+	if _, err := s.hasher.Verify(user.Password, req.CurrentPassword); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.policy.Check(req.NewPassword, user.Email, user.Username); err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+
+	hashed, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+
+	if err := s.users.UpdatePassword(user.ID, hashed); err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+	if err := s.resets.InvalidateUser(user.ID, time.Now()); err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+
+	if err := s.sessions.LogoutOthers(userID, claims); err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+	if err := s.sessions.RevokePersonalTokens(user.ID); err != nil {
+		return fmt.Errorf("change password service: %w", err)
+	}
+
+	return nil
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// address only changes once the link is followed, and the old address is
+// told about the request.
+func (s *ProfileService) RequestEmailChange(userID string, req model.ChangeEmailRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProfileParam, err)
+	}
+
+	user, err := s.user(userID)
+	if err != nil {
+		return fmt.Errorf("change email service: %w", err)
+	}
+
+	if _, err := s.hasher.Verify(user.Password, req.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	if strings.EqualFold(user.Email, req.Email) {
+		return fmt.Errorf("%w: email is unchanged", ErrInvalidProfileParam)
+	}
+	if err := s.checkEmailAvailable(req.Email); err != nil {
+		return err
+	}
+
+	token := auth.Sign(s.secret, changeEmailPurpose, []string{user.ID.String(), user.Email, req.Email}, time.Now().Add(s.linkTTL))
+
+	confirm := mail.Message{
+		To:      req.Email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm that you want to use this address for your account by opening the link below. It expires in %s.\n\n%s?token=%s\n",
+			user.Username, s.linkTTL, s.confirmURL, url.QueryEscape(token),
+		),
+	}
+	if err := s.sender.Send(confirm); err != nil {
+		return fmt.Errorf("change email service: %w", err)
+	}
+
+	notice := mail.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nA change of your account's email address to %s was requested. If this was not you, change your password now.\n",
+			user.Username, req.Email,
+		),
+	}
+	if err := s.sender.Send(notice); err != nil {
+		return fmt.Errorf("change email service: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches to the address an email change link was
+// issued for. Links carry the address they replace, so they stop working
+// once the address has changed.
+func (s *ProfileService) ConfirmEmailChange(token string) error {
+	values, err := auth.Verify(s.secret, changeEmailPurpose, token, time.Now())
+	if err != nil || len(values) != 3 {
+		return ErrInvalidEmailChange
+	}
+
+	userID, err := uuid.Parse(values[0])
+	if err != nil {
+		return ErrInvalidEmailChange
+	}
+	current, replacement := values[1], values[2]
+
+	if err := s.checkEmailAvailable(replacement); err != nil {
+		return err
+	}
+
+	// The check above gives the common case a clear answer; the unique
+	// index on users.email settles a race with another account taking the
+	// address meanwhile.
+	changed, err := s.users.ChangeEmail(userID, current, replacement)
+	if err != nil {
+		if errors.Is(err, storage.ErrDuplicateEmail) {
+			return ErrEmailInUse
+		}
+		return fmt.Errorf("confirm email change service: %w", err)
+	}
+	if !changed {
+		return ErrInvalidEmailChange
+	}
+
+	return nil
+}
+
+func (s *ProfileService) checkEmailAvailable(email string) error {
+	_, err := s.users.GetByEmail(email)
+	if err == nil {
+		return ErrEmailInUse
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("profile service: %w", err)
+	}
+	return nil
+}
+
+func (s *ProfileService) user(userID string) (*model.User, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.users.GetByID(uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func toProfileResponse(user *model.User) *model.ProfileResponse {
+	return &model.ProfileResponse{
+		ID:                  user.ID,
+		Email:               user.Email,
+		EmailVerified:       user.EmailVerified,
+		Username:            user.Username,
+		DisplayName:         user.DisplayName,
+		Timezone:            user.Timezone,
+		Locale:              user.Locale,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	}
+}
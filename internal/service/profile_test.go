@@ -0,0 +1,108 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/mail"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type memoryMail struct {
+	sent []mail.Message
+}
+
+func (m *memoryMail) Send(msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestProfile(t *testing.T) (*ProfileService, *testAuth, *memoryResetTokens, *memoryMail) {
+	t.Helper()
+
+	a := newTestAuth(t)
+	resets := &memoryResetTokens{tokens: make(map[string]*model.PasswordResetToken)}
+	sender := &memoryMail{}
+	s := NewProfileService(a.users, resets, sender, a.JWTService, ProfileOptions{
+		Secret:     []byte("test secret"),
+		LinkTTL:    time.Hour,
+		ConfirmURL: "http://app.test/confirm",
+		Hasher:     a.passwords,
+	})
+	return s, a, resets, sender
+}
+
+func TestChangePasswordInvalidatesResetLinks(t *testing.T) {
+	s, a, resets, _ := newTestProfile(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resets.tokens["hash"] = &model.PasswordResetToken{ID: uuid.New(), UserID: user.ID, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+
+	err := s.ChangePassword(user.ID.String(), jwt.MapClaims{}, model.ChangePasswordRequest{
+		CurrentPassword: "correct horse battery",
+		NewPassword:     "staple battery horse correct",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if resets.tokens["hash"].UsedAt == nil {
+		t.Fatal("reset link still usable after the password was changed")
+	}
+}
+
+// racingUsers hides other users from GetByEmail, as if the address had been
+// taken between the availability check and the update.
+type racingUsers struct {
+	*memoryUsers
+}
+
+func (racingUsers) GetByEmail(string) (*model.User, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestConfirmEmailChangeLosingRaceReportsEmailInUse(t *testing.T) {
+	s, a, _, sender := newTestProfile(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	err := s.RequestEmailChange(user.ID.String(), model.ChangeEmailRequest{Email: "bob@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("RequestEmailChange() error = %v", err)
+	}
+	link, err := url.Parse(strings.Fields(sender.sent[0].Body[strings.Index(sender.sent[0].Body, "http://"):])[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.addUser(t, "bob@example.com", "another good password")
+	s.users = racingUsers{a.users}
+
+	if err := s.ConfirmEmailChange(link.Query().Get("token")); !errors.Is(err, ErrEmailInUse) {
+		t.Fatalf("ConfirmEmailChange() error = %v, want %v", err, ErrEmailInUse)
+	}
+}
+
+func TestUpdateProfileUsernameBounds(t *testing.T) {
+	s, a, _, _ := newTestProfile(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	for _, tt := range []struct {
+		username string
+		valid    bool
+	}{
+		{"al", false},
+		{"ali", true},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 65), false},
+	} {
+		username := tt.username
+		_, err := s.UpdateProfile(user.ID.String(), model.UpdateProfileRequest{Username: &username})
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("UpdateProfile(username of %d characters) error = %v, want valid %v", len(username), err, tt.valid)
+		}
+	}
+}
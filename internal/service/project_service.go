@@ -0,0 +1,161 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidProject  = errors.New("invalid project")
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+type ProjectStorage interface {
+	Create(project model.Project) error
+	GetByID(projectID, userID uuid.UUID) (*model.Project, error)
+	List(userID uuid.UUID, includeArchived bool) ([]model.Project, error)
+	Update(projectID, userID uuid.UUID, project model.Project) error
+	Delete(projectID, userID uuid.UUID, deleteTasks bool) error
+}
+
+type ProjectService struct {
+	storage ProjectStorage
+}
+
+func NewProjectService(store ProjectStorage) *ProjectService {
+	return &ProjectService{storage: store}
+}
+
+func (s *ProjectService) ListProjects(userID string, includeArchived bool) ([]model.Project, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("list projects service: %w", err)
+	}
+
+	projects, err := s.storage.List(uuidUserID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("list projects service: %w", err)
+	}
+
+	return projects, nil
+}
+
+func (s *ProjectService) GetProject(projectID, userID string) (*model.Project, error) {
+	uuidProjectID, uuidUserID, err := parseIDPair(projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get project service: %w", err)
+	}
+
+	project, err := s.storage.GetByID(uuidProjectID, uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("get project service: %w", err)
+	}
+
+	return project, nil
+}
+
+func (s *ProjectService) CreateProject(userID string, req model.CreateProjectRequest) (*model.Project, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("create project service: %w", err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProject, err)
+	}
+
+	project := model.Project{
+		ID:          uuid.New(),
+		UserID:      uuidUserID,
+		Name:        req.Name,
+		Description: req.Description,
+		Color:       req.Color,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := s.storage.Create(project); err != nil {
+		return nil, fmt.Errorf("create project service: %w", err)
+	}
+
+	return &project, nil
+}
+
+func (s *ProjectService) UpdateProject(projectID, userID string, req model.UpdateProjectRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProject, err)
+	}
+
+	project, err := s.GetProject(projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return fmt.Errorf("%w: name must not be empty", ErrInvalidProject)
+		}
+		project.Name = *req.Name
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if req.Archived != nil {
+		project.Archived = *req.Archived
+	}
+	project.UpdatedAt = time.Now()
+
+	if err := s.storage.Update(project.ID, project.UserID, *project); err != nil {
+		return fmt.Errorf("update project service: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ProjectService) DeleteProject(projectID, userID, mode string) error {
+	if mode == "" {
+		mode = model.ProjectDeleteMoveToInbox
+	}
+	if mode != model.ProjectDeleteMoveToInbox && mode != model.ProjectDeleteTasks {
+		return fmt.Errorf("%w: unknown delete mode %q", ErrInvalidProject, mode)
+	}
+
+	project, err := s.GetProject(projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.Delete(project.ID, project.UserID, mode == model.ProjectDeleteTasks); err != nil {
+		return fmt.Errorf("delete project service: %w", err)
+	}
+
+	return nil
+}
+
+func parseIDPair(id, userID string) (uuid.UUID, uuid.UUID, error) {
+	uuidID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	return uuidID, uuidUserID, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// memoryProjects records how the last project was deleted instead of
+// touching its tasks, which the SQL store does in the same statement.
+type memoryProjects struct {
+	projects    map[uuid.UUID]model.Project
+	deleteTasks *bool
+}
+
+func newMemoryProjects() *memoryProjects {
+	return &memoryProjects{projects: make(map[uuid.UUID]model.Project)}
+}
+
+func (m *memoryProjects) Create(project model.Project) error {
+	m.projects[project.ID] = project
+	return nil
+}
+
+func (m *memoryProjects) GetByID(projectID, userID uuid.UUID) (*model.Project, error) {
+	project, ok := m.projects[projectID]
+	if !ok || project.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	return &project, nil
+}
+
+func (m *memoryProjects) List(userID uuid.UUID, includeArchived bool) ([]model.Project, error) {
+	var projects []model.Project
+	for _, project := range m.projects {
+		if project.UserID == userID && (includeArchived || !project.Archived) {
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+func (m *memoryProjects) Update(projectID, userID uuid.UUID, project model.Project) error {
+	m.projects[projectID] = project
+	return nil
+}
+
+func (m *memoryProjects) Delete(projectID, userID uuid.UUID, deleteTasks bool) error {
+	delete(m.projects, projectID)
+	m.deleteTasks = &deleteTasks
+	return nil
+}
+
+func TestProjectLifecycle(t *testing.T) {
+	store := newMemoryProjects()
+	s := NewProjectService(store)
+	alice := uuid.NewString()
+
+	if _, err := s.CreateProject(alice, model.CreateProjectRequest{Name: "home", Color: "red"}); !errors.Is(err, ErrInvalidProject) {
+		t.Fatalf("CreateProject() with a bad colour error = %v, want %v", err, ErrInvalidProject)
+	}
+	project, err := s.CreateProject(alice, model.CreateProjectRequest{Name: "home", Color: "#ff0000"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	id := project.ID.String()
+
+	empty := ""
+	if err := s.UpdateProject(id, alice, model.UpdateProjectRequest{Name: &empty}); !errors.Is(err, ErrInvalidProject) {
+		t.Fatalf("UpdateProject() with an empty name error = %v, want %v", err, ErrInvalidProject)
+	}
+	archived := true
+	if err := s.UpdateProject(id, alice, model.UpdateProjectRequest{Archived: &archived}); err != nil {
+		t.Fatalf("UpdateProject() error = %v", err)
+	}
+	if projects, _ := s.ListProjects(alice, false); len(projects) != 0 {
+		t.Fatalf("ListProjects() = %+v, want archived projects left out", projects)
+	}
+	if projects, _ := s.ListProjects(alice, true); len(projects) != 1 || projects[0].Name != "home" {
+		t.Fatalf("ListProjects() with archived = %+v, want home", projects)
+	}
+
+	if _, err := s.GetProject(id, uuid.NewString()); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("GetProject() by another user error = %v, want %v", err, ErrProjectNotFound)
+	}
+}
+
+func TestDeleteProjectModes(t *testing.T) {
+	tests := []struct {
+		mode            string
+		wantErr         error
+		wantDeleteTasks bool
+	}{
+		{"", nil, false},
+		{model.ProjectDeleteMoveToInbox, nil, false},
+		{model.ProjectDeleteTasks, nil, true},
+		{"archive", ErrInvalidProject, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			store := newMemoryProjects()
+			s := NewProjectService(store)
+			alice := uuid.NewString()
+			project, err := s.CreateProject(alice, model.CreateProjectRequest{Name: "home"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.DeleteProject(project.ID.String(), alice, tt.mode)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteProject() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if store.deleteTasks != nil {
+					t.Fatal("DeleteProject() deleted the project despite the error")
+				}
+				return
+			}
+			if store.deleteTasks == nil || *store.deleteTasks != tt.wantDeleteTasks {
+				t.Fatalf("deleteTasks = %v, want %t", store.deleteTasks, tt.wantDeleteTasks)
+			}
+		})
+	}
+}
+
+func TestCreateTaskInProject(t *testing.T) {
+	s, tasks := newTestTasks(SubtaskOrphan)
+	projects := newMemoryProjects()
+	s.projects = projects
+	userID := uuid.New()
+	project, err := NewProjectService(projects).CreateProject(userID.String(), model.CreateProjectRequest{Name: "home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewProjectService(projects).CreateProject(uuid.NewString(), model.CreateProjectRequest{Name: "theirs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		project string
+		want    *uuid.UUID
+		wantErr error
+	}{
+		{"own project", project.ID.String(), &project.ID, nil},
+		{"inbox", model.InboxProject, nil, nil},
+		{"no project", "", nil, nil},
+		{"another user's project", other.ID.String(), nil, ErrProjectNotFound},
+		{"not an id", "home", nil, ErrInvalidTask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CreateTask(model.CreateTaskRequest{Title: tt.name, UserID: userID.String(), ProjectID: tt.project})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateTask() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			task, err := tasks.GetByTitle(tt.name, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (task.ProjectID == nil) != (tt.want == nil) || (tt.want != nil && *task.ProjectID != *tt.want) {
+				t.Fatalf("project = %v, want %v", task.ProjectID, tt.want)
+			}
+		})
+	}
+}
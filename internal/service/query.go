@@ -0,0 +1,427 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// The task query language combines field filters such as
+//
+//	is:open tag:backend due<2026-11-01 (priority:high OR title:"deploy")
+//
+// Terms next to each other are ANDed, OR binds weaker than AND, and a term
+// can be negated with NOT or a leading "-". Bare words and quoted strings
+// match the title or description. Queries are compiled into a parameterised
+// SQL condition: user input only ever reaches the database as an argument.
+// Every term compiles to a condition that is TRUE or FALSE, never NULL, so
+// that NOT also matches rows where a nullable column is empty.
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokNot
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func queryError(pos int, format string, args ...any) error {
+	return fmt.Errorf("%w: at position %d: %s", ErrInvalidQuery, pos+1, fmt.Sprintf(format, args...))
+}
+
+func isOpChar(c byte) bool {
+	return c == ':' || c == '<' || c == '>' || c == '='
+}
+
+func lexQuery(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			i++
+			var b strings.Builder
+			for i < len(input) && input[i] != '"' {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return nil, queryError(start, "unterminated string")
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String(), start})
+		case isOpChar(c):
+			start := i
+			i++
+			if (c == '<' || c == '>') && i < len(input) && input[i] == '=' {
+				i++
+			}
+			tokens = append(tokens, token{tokOp, input[start:i], start})
+		case c == '-' && i+1 < len(input) && input[i+1] != ' ' && (len(tokens) == 0 || tokens[len(tokens)-1].kind != tokOp):
+			tokens = append(tokens, token{tokNot, "-", i})
+			i++
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n()\"", rune(input[i])) && !isOpChar(input[i]) {
+				i++
+			}
+			word := input[start:i]
+			if word == "NOT" {
+				tokens = append(tokens, token{tokNot, word, start})
+			} else {
+				tokens = append(tokens, token{tokWord, word, start})
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+type queryNode interface {
+	compile(c *queryCompiler) string
+}
+
+type andNode struct{ left, right queryNode }
+type orNode struct{ left, right queryNode }
+type notNode struct{ inner queryNode }
+type textNode struct{ text string }
+type fieldNode struct {
+	field, op, value string
+	pos              int
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token { return p.tokens[p.pos] }
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokWord && t.text == word
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.isKeyword("AND") {
+			p.next()
+		} else if t := p.peek(); t.kind == tokEOF || t.kind == tokRParen || p.isKeyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, queryError(closing.pos, "expected \")\" but found %s", closing.describe())
+		}
+		return inner, nil
+	case tokString:
+		return textNode{t.text}, nil
+	case tokWord:
+		if t.text == "AND" || t.text == "OR" {
+			return nil, queryError(t.pos, "unexpected %s", t.text)
+		}
+		if p.peek().kind != tokOp {
+			return textNode{t.text}, nil
+		}
+		op := p.next()
+		value := p.next()
+		if value.kind != tokWord && value.kind != tokString {
+			return nil, queryError(value.pos, "expected a value after %s%s but found %s", t.text, op.text, value.describe())
+		}
+		return fieldNode{field: strings.ToLower(t.text), op: op.text, value: value.text, pos: t.pos}, nil
+	default:
+		return nil, queryError(t.pos, "unexpected %s", t.describe())
+	}
+}
+
+type queryCompiler struct {
+	now  time.Time
+	loc  *time.Location
+	args []any
+	err  error
+}
+
+func (c *queryCompiler) arg(v any) string {
+	c.args = append(c.args, v)
+	return "?"
+}
+
+func (c *queryCompiler) fail(err error) string {
+	if c.err == nil {
+		c.err = err
+	}
+	return "FALSE"
+}
+
+func (n andNode) compile(c *queryCompiler) string {
+	return "(" + n.left.compile(c) + " AND " + n.right.compile(c) + ")"
+}
+
+func (n orNode) compile(c *queryCompiler) string {
+	return "(" + n.left.compile(c) + " OR " + n.right.compile(c) + ")"
+}
+
+func (n notNode) compile(c *queryCompiler) string {
+	return "NOT (" + n.inner.compile(c) + ")"
+}
+
+func (n textNode) compile(c *queryCompiler) string {
+	pattern := likePattern(n.text)
+	return "(title LIKE " + c.arg(pattern) + " OR COALESCE(description, '') LIKE " + c.arg(pattern) + ")"
+}
+
+func (n fieldNode) compile(c *queryCompiler) string {
+	switch n.field {
+	case "is":
+		return n.compileIs(c)
+	case "tag":
+		if n.op != ":" {
+			return c.fail(queryError(n.pos, "tag only supports \":\""))
+		}
+		return "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = " + c.arg(normalizeTagName(n.value)) + ")"
+	case "title", "description", "desc":
+		if n.op != ":" {
+			return c.fail(queryError(n.pos, "%s only supports \":\"", n.field))
+		}
+		column := "title"
+		if n.field != "title" {
+			column = "COALESCE(description, '')"
+		}
+		return column + " LIKE " + c.arg(likePattern(n.value))
+	case "priority":
+		return n.compilePriority(c)
+	case "project":
+		if n.op != ":" {
+			return c.fail(queryError(n.pos, "project only supports \":\""))
+		}
+		if n.value == model.InboxProject {
+			return "project_id IS NULL"
+		}
+		id, err := uuid.Parse(n.value)
+		if err != nil {
+			return c.fail(queryError(n.pos, "project must be \"inbox\" or a project id"))
+		}
+		return notNull("project_id", "project_id = "+c.arg(id))
+	case "due":
+		if n.op == ":" && n.value == "none" {
+			return "due_at IS NULL"
+		}
+		return n.compileDate(c, "due_at")
+	case "created":
+		return n.compileDate(c, "created_at")
+	case "updated":
+		return n.compileDate(c, "updated_at")
+	default:
+		return c.fail(queryError(n.pos, "unknown field %q", n.field))
+	}
+}
+
+func (n fieldNode) compileIs(c *queryCompiler) string {
+	if n.op != ":" {
+		return c.fail(queryError(n.pos, "is only supports \":\""))
+	}
+	switch n.value {
+	case "open":
+		return "is_done = FALSE"
+	case "done":
+		return "is_done = TRUE"
+	case "overdue":
+		return "(is_done = FALSE AND " + notNull("due_at", "due_at < "+c.arg(c.now.UTC())) + ")"
+	case "recurring":
+		return "(recurrence IS NOT NULL AND recurrence <> '')"
+	case "subtask":
+		return "parent_id IS NOT NULL"
+	default:
+		return c.fail(queryError(n.pos, "unknown value is:%s, expected open, done, overdue, recurring or subtask", n.value))
+	}
+}
+
+func (n fieldNode) compilePriority(c *queryCompiler) string {
+	p := model.Priority(n.value)
+	if _, ok := priorityRank(p); !ok {
+		return c.fail(queryError(n.pos, "unknown priority %q, expected none, low, medium, high or urgent", n.value))
+	}
+	op := n.op
+	if op == ":" {
+		op = "="
+	}
+	return "priority " + op + " " + c.arg(p.Rank())
+}
+
+func priorityRank(p model.Priority) (int, bool) {
+	switch p {
+	case model.PriorityNone, model.PriorityLow, model.PriorityMedium, model.PriorityHigh, model.PriorityUrgent:
+		return p.Rank(), true
+	}
+	return 0, false
+}
+
+// compileDate turns a comparison against a calendar day into a half-open
+// range on the column, so due<2026-11-01 means "before that day starts" and
+// due<=2026-11-01 means "before the next day starts".
+func (n fieldNode) compileDate(c *queryCompiler, column string) string {
+	day, err := c.parseDay(n.value)
+	if err != nil {
+		return c.fail(queryError(n.pos, "%s expects a date (YYYY-MM-DD, today, tomorrow or yesterday), got %q", n.field, n.value))
+	}
+	start := day.UTC()
+	end := day.AddDate(0, 0, 1).UTC()
+
+	switch n.op {
+	case ":", "=":
+		return notNull(column, column+" >= "+c.arg(start)+" AND "+column+" < "+c.arg(end))
+	case "<":
+		return notNull(column, column+" < "+c.arg(start))
+	case "<=":
+		return notNull(column, column+" < "+c.arg(end))
+	case ">":
+		return notNull(column, column+" >= "+c.arg(end))
+	case ">=":
+		return notNull(column, column+" >= "+c.arg(start))
+	default:
+		return c.fail(queryError(n.pos, "unsupported operator %q", n.op))
+	}
+}
+
+// notNull guards a condition on a nullable column so that it is FALSE
+// rather than NULL for rows without a value.
+func notNull(column, cond string) string {
+	return "(" + column + " IS NOT NULL AND " + cond + ")"
+}
+
+func (c *queryCompiler) parseDay(value string) (time.Time, error) {
+	today := startOfDay(c.now.In(c.loc))
+	switch value {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	return time.ParseInLocation(dueDateLayout, value, c.loc)
+}
+
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
+
+// compileQuery parses a task query and returns an SQL condition over the
+// tasks table together with its arguments.
+func compileQuery(query string, now time.Time, loc *time.Location) (string, []any, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return "", nil, nil
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return "", nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return "", nil, queryError(t.pos, "unexpected %s", t.describe())
+	}
+
+	c := &queryCompiler{now: now, loc: loc}
+	clause := node.compile(c)
+	if c.err != nil {
+		return "", nil, c.err
+	}
+
+	return clause, c.args, nil
+}
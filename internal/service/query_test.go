@@ -0,0 +1,172 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCompileQuery(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	day := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse(dueDateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	projectID := uuid.MustParse("2f1c7f4e-8a65-4d8a-9f2c-8c3c1b9e7d10")
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+		args  []any
+	}{
+		{
+			name:  "empty",
+			query: "  ",
+			want:  "",
+		},
+		{
+			name:  "bare word",
+			query: "deploy",
+			want:  "(title LIKE ? OR COALESCE(description, '') LIKE ?)",
+			args:  []any{"%deploy%", "%deploy%"},
+		},
+		{
+			name:  "quoted string keeps spaces and escapes like wildcards",
+			query: `"50% off_now"`,
+			want:  "(title LIKE ? OR COALESCE(description, '') LIKE ?)",
+			args:  []any{`%50\% off\_now%`, `%50\% off\_now%`},
+		},
+		{
+			name:  "implicit and",
+			query: "is:open tag:backend",
+			want:  "(is_done = FALSE AND id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?))",
+			args:  []any{"backend"},
+		},
+		{
+			name:  "or binds weaker than and",
+			query: "is:open priority:high OR is:done",
+			want:  "((is_done = FALSE AND priority = ?) OR is_done = TRUE)",
+			args:  []any{3},
+		},
+		{
+			name:  "parentheses",
+			query: "is:open (priority>=high OR title:deploy)",
+			want:  "(is_done = FALSE AND (priority >= ? OR title LIKE ?))",
+			args:  []any{3, "%deploy%"},
+		},
+		{
+			name:  "not over a non-null column",
+			query: "NOT is:done",
+			want:  "NOT (is_done = TRUE)",
+		},
+		{
+			name:  "minus negates",
+			query: "-tag:later",
+			want:  "NOT (id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?))",
+			args:  []any{"later"},
+		},
+		{
+			name:  "not over due date keeps tasks without one",
+			query: "-due<2026-11-01",
+			want:  "NOT ((due_at IS NOT NULL AND due_at < ?))",
+			args:  []any{day("2026-11-01")},
+		},
+		{
+			name:  "due on a day",
+			query: "due:2026-11-01",
+			want:  "(due_at IS NOT NULL AND due_at >= ? AND due_at < ?)",
+			args:  []any{day("2026-11-01"), day("2026-11-02")},
+		},
+		{
+			name:  "due before or on a day",
+			query: "due<=today",
+			want:  "(due_at IS NOT NULL AND due_at < ?)",
+			args:  []any{day("2026-10-19")},
+		},
+		{
+			name:  "no due date",
+			query: "due:none",
+			want:  "due_at IS NULL",
+		},
+		{
+			name:  "not over project keeps inbox tasks",
+			query: "NOT project:" + projectID.String(),
+			want:  "NOT ((project_id IS NOT NULL AND project_id = ?))",
+			args:  []any{projectID},
+		},
+		{
+			name:  "inbox",
+			query: "project:inbox",
+			want:  "project_id IS NULL",
+		},
+		{
+			name:  "not overdue keeps undated open tasks",
+			query: "NOT is:overdue",
+			want:  "NOT ((is_done = FALSE AND (due_at IS NOT NULL AND due_at < ?)))",
+			args:  []any{now},
+		},
+		{
+			name:  "created after",
+			query: "created>yesterday",
+			want:  "(created_at IS NOT NULL AND created_at >= ?)",
+			args:  []any{day("2026-10-18")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args, err := compileQuery(tt.query, now, time.UTC)
+			if err != nil {
+				t.Fatalf("compileQuery(%q) error: %v", tt.query, err)
+			}
+			if clause != tt.want {
+				t.Errorf("clause = %q\n want   %q", clause, tt.want)
+			}
+			if len(args) != len(tt.args) {
+				t.Fatalf("args = %v, want %v", args, tt.args)
+			}
+			for i := range args {
+				if want, ok := tt.args[i].(time.Time); ok {
+					if got, ok := args[i].(time.Time); !ok || !got.Equal(want) {
+						t.Errorf("arg %d = %v, want %v", i, args[i], want)
+					}
+					continue
+				}
+				if args[i] != tt.args[i] {
+					t.Errorf("arg %d = %v, want %v", i, args[i], tt.args[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	tests := []string{
+		`"unterminated`,
+		"(is:open",
+		"is:open)",
+		"OR is:open",
+		"is:open AND",
+		"is:",
+		"is:maybe",
+		"priority:extreme",
+		"tag<x",
+		"project:nope",
+		"due<someday",
+		"due>",
+		"color:red",
+	}
+
+	for _, query := range tests {
+		if _, _, err := compileQuery(query, time.Now(), time.UTC); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("compileQuery(%q) error = %v, want ErrInvalidQuery", query, err)
+		}
+	}
+}
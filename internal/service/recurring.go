@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/recurrence"
+	"github.com/google/uuid"
+)
+
+const maxPreviewOccurrences = 100
+
+var (
+	ErrNotRecurring = errors.New("task is not recurring")
+	ErrSeriesEnded  = errors.New("recurring series has no more occurrences")
+)
+
+func normalizeRecurrence(value string, dueAt *time.Time) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	rule, err := recurrence.Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTask, err)
+	}
+
+	if dueAt == nil {
+		return "", fmt.Errorf("%w: recurring tasks need a due date", ErrInvalidTask)
+	}
+
+	return rule.String(), nil
+}
+
+// nextOccurrences returns up to n due dates following the task's current one,
+// taking into account how many occurrences of a COUNT-limited series have
+// already been used up.
+func nextOccurrences(task *model.Task, n int) ([]time.Time, error) {
+	if task.Recurrence == "" || task.DueAt == nil {
+		return nil, ErrNotRecurring
+	}
+
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Count > 0 {
+		n = min(n, rule.Count-task.Occurrence)
+	}
+	if n <= 0 {
+		return []time.Time{}, nil
+	}
+
+	loc, err := loadLocation(task.DueTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := rule.After(task.DueAt.In(loc), n)
+	for i := range occurrences {
+		occurrences[i] = occurrences[i].UTC()
+	}
+
+	return occurrences, nil
+}
+
+// nextInSeries returns the follow-up task of a recurring series for when the
+// current one is completed, or nil if the series has ended. The rule moves to
+// the new task so that re-opening and re-completing the old one does not
+// spawn duplicates.
+func nextInSeries(task *model.Task) (*model.Task, error) {
+	next, err := nextOccurrences(task, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := task.Recurrence
+	task.Recurrence = ""
+	if len(next) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	spawned := *task
+	spawned.ID = uuid.New()
+	spawned.IsDone = false
+	spawned.CompletedAt = nil
+	spawned.DueAt = &next[0]
+	spawned.Recurrence = rule
+	spawned.Occurrence = task.Occurrence + 1
+	spawned.Tags = nil
+	spawned.CreatedAt = now
+	spawned.UpdatedAt = now
+
+	return &spawned, nil
+}
+
+func (s *TodoService) PreviewOccurrences(taskID, userID string, n int) ([]time.Time, error) {
+	if n <= 0 || n > maxPreviewOccurrences {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidFilter, maxPreviewOccurrences)
+	}
+
+	task, err := s.GetTaskByID(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences, err := nextOccurrences(task, n)
+	if err != nil {
+		return nil, fmt.Errorf("preview occurrences service: %w", err)
+	}
+
+	return occurrences, nil
+}
+
+func (s *TodoService) SkipOccurrence(taskID, userID string) error {
+	task, err := s.GetTaskByID(taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	next, err := nextOccurrences(task, 1)
+	if err != nil {
+		return fmt.Errorf("skip occurrence service: %w", err)
+	}
+	if len(next) == 0 {
+		return ErrSeriesEnded
+	}
+
+	task.DueAt = &next[0]
+	task.Occurrence++
+	task.UpdatedAt = time.Now()
+
+	if err := s.storage.Update(task.ID, task.UserId, *task, nil); err != nil {
+		return fmt.Errorf("skip occurrence service: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TodoService) EndSeries(taskID, userID string) error {
+	task, err := s.GetTaskByID(taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	if task.Recurrence == "" {
+		return ErrNotRecurring
+	}
+
+	task.Recurrence = ""
+	task.UpdatedAt = time.Now()
+
+	if err := s.storage.Update(task.ID, task.UserId, *task, nil); err != nil {
+		return fmt.Errorf("end series service: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+)
+
+func TestNextOccurrencesCount(t *testing.T) {
+	due := time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		occurrence int
+		n          int
+		want       int
+	}{
+		{"first of three", 1, 5, 2},
+		{"second of three", 2, 5, 1},
+		{"last of three", 3, 5, 0},
+		{"fewer requested than left", 1, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &model.Task{DueAt: &due, Recurrence: "FREQ=DAILY;COUNT=3", Occurrence: tt.occurrence}
+
+			got, err := nextOccurrences(task, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("nextOccurrences() returned %d occurrences, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrencesUsesTaskTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	due := time.Date(2026, time.November, 4, 22, 0, 0, 0, loc).UTC()
+	task := &model.Task{DueAt: &due, DueTimezone: "America/New_York", Recurrence: "FREQ=DAILY;UNTIL=20261105"}
+
+	got, err := nextOccurrences(task, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, time.November, 5, 22, 0, 0, 0, loc)
+	if len(got) != 1 || !got[0].Equal(want) {
+		t.Fatalf("nextOccurrences() = %v, want [%v]", got, want)
+	}
+}
+
+func TestNextOccurrencesNotRecurring(t *testing.T) {
+	if _, err := nextOccurrences(&model.Task{}, 1); !errors.Is(err, ErrNotRecurring) {
+		t.Fatalf("error = %v, want ErrNotRecurring", err)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type RefreshTokenStorage interface {
+	Create(token model.RefreshToken) error
+	GetByHash(hash string) (*model.RefreshToken, error)
+	MarkUsed(id uuid.UUID, at time.Time) (bool, error)
+	RevokeFamily(familyID uuid.UUID, at time.Time) error
+	RevokeUser(userID uuid.UUID, at time.Time) error
+}
+
+func (j *JWTService) newRefreshToken(userID, familyID uuid.UUID) (string, error) {
+	raw, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := model.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(j.refreshTTL),
+		CreatedAt: now,
+	}
+
+	if err := j.refreshStore.Create(token); err != nil {
+		return "", fmt.Errorf("refresh token creation err: %w", err)
+	}
+
+	return raw, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// works once; presenting one that was already rotated means it leaked, so
+// the whole family is revoked and the user has to log in again.
+func (j *JWTService) Refresh(raw string, client model.ClientInfo) (*model.LoginResponse, error) {
+	stored, err := j.refreshStore.GetByHash(auth.HashToken(raw))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidToken
+	}
+	if stored.UsedAt != nil {
+		return nil, j.revokeReusedFamily(stored.FamilyID, now)
+	}
+	if now.After(stored.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	consumed, err := j.refreshStore.MarkUsed(stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, j.revokeReusedFamily(stored.FamilyID, now)
+	}
+
+	user, err := j.userStore.GetByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := j.sessionStore.Extend(stored.FamilyID, clampClient(client), now, now.Add(j.refreshTTL)); err != nil {
+		return nil, fmt.Errorf("refresh session: %w", err)
+	}
+
+	return j.issueTokens(user, stored.FamilyID)
+}
+
+func (j *JWTService) revokeReusedFamily(familyID uuid.UUID, at time.Time) error {
+	if err := j.refreshStore.RevokeFamily(familyID, at); err != nil {
+		return err
+	}
+	return ErrTokenReuse
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memoryRefreshTokens struct {
+	tokens map[string]*model.RefreshToken
+}
+
+func (m *memoryRefreshTokens) Create(token model.RefreshToken) error {
+	m.tokens[token.TokenHash] = &token
+	return nil
+}
+
+func (m *memoryRefreshTokens) GetByHash(hash string) (*model.RefreshToken, error) {
+	token, ok := m.tokens[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *memoryRefreshTokens) MarkUsed(id uuid.UUID, at time.Time) (bool, error) {
+	for _, token := range m.tokens {
+		if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+			token.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRefreshTokens) RevokeFamily(familyID uuid.UUID, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *memoryRefreshTokens) RevokeUser(userID uuid.UUID, at time.Time) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := a.Refresh(resp.RefreshToken, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.Token == "" || refreshed.RefreshToken == "" || refreshed.RefreshToken == resp.RefreshToken {
+		t.Fatalf("Refresh() = %+v, want a new token pair", refreshed)
+	}
+
+	// The rotated token keeps working in turn.
+	if _, err := a.Refresh(refreshed.RefreshToken, model.ClientInfo{}); err != nil {
+		t.Fatalf("Refresh() with the rotated token error = %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := a.Refresh(resp.RefreshToken, model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying the first token gives the leak away: the legitimate
+	// holder's current token is revoked along with it.
+	if _, err := a.Refresh(resp.RefreshToken, model.ClientInfo{}); !errors.Is(err, ErrTokenReuse) {
+		t.Fatalf("Refresh() with a used token error = %v, want %v", err, ErrTokenReuse)
+	}
+	if _, err := a.Refresh(refreshed.RefreshToken, model.ClientInfo{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Refresh() after reuse error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// Other logins of the same user are a different family.
+	other, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Refresh(other.RefreshToken, model.ClientInfo{}); err != nil {
+		t.Fatalf("Refresh() of another family error = %v", err)
+	}
+}
+
+func TestRefreshRejectsUnknownAndExpiredTokens(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Refresh("not a token", model.ClientInfo{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Refresh() with an unknown token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	store := a.refreshStore.(*memoryRefreshTokens)
+	store.tokens[auth.HashToken(resp.RefreshToken)].ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := a.Refresh(resp.RefreshToken, model.ClientInfo{}); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("Refresh() with an expired token error = %v, want %v", err, ErrExpiredToken)
+	}
+}
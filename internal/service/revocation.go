@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type RevocationStorage interface {
+	Create(rev model.TokenRevocation) error
+	ListActive(now time.Time) ([]model.TokenRevocation, error)
+	DeleteExpired(now time.Time) error
+}
+
+// TokenRevoker remembers revoked access tokens until they would have expired
+// anyway. Revocations are written through to the store and kept in memory, so
+// checking a token on every request never touches the database. Revocations
+// made by other instances only show up here on the next Load, which RunSync
+// repeats every interval; until then such a token keeps working.
+type TokenRevoker struct {
+	store    RevocationStorage
+	tokenTTL time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	sessions map[uuid.UUID]time.Time
+	cutoffs  map[uuid.UUID]model.TokenRevocation
+}
+
+func NewTokenRevoker(store RevocationStorage, tokenTTL time.Duration) *TokenRevoker {
+	return &TokenRevoker{
+		store:    store,
+		tokenTTL: tokenTTL,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[uuid.UUID]time.Time),
+		cutoffs:  make(map[uuid.UUID]model.TokenRevocation),
+	}
+}
+
+// Load drops expired revocations from the store and adds the remaining ones
+// to the cache.
+func (r *TokenRevoker) Load() error {
+	now := time.Now()
+	if err := r.store.DeleteExpired(now); err != nil {
+		return fmt.Errorf("load revocations: %w", err)
+	}
+
+	revocations, err := r.store.ListActive(now)
+	if err != nil {
+		return fmt.Errorf("load revocations: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(now)
+	for _, rev := range revocations {
+		r.remember(rev)
+	}
+
+	return nil
+}
+
+// RunSync reloads revocations every interval until ctx is done. Errors are
+// passed to onError and retried on the next run.
+func (r *TokenRevoker) RunSync(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.Load(); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// RevokeToken invalidates a single access token until its expiry.
+func (r *TokenRevoker) RevokeToken(jti string, userID uuid.UUID, expiresAt time.Time) error {
+	return r.add(model.TokenRevocation{
+		ID:        uuid.New(),
+		UserID:    userID,
+		JTI:       jti,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RevokeSession invalidates every access token issued for a session.
+func (r *TokenRevoker) RevokeSession(sessionID, userID uuid.UUID) error {
+	now := time.Now()
+	return r.add(model.TokenRevocation{
+		ID:        uuid.New(),
+		UserID:    userID,
+		SessionID: &sessionID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(r.tokenTTL),
+	})
+}
+
+// RevokeUser invalidates every access token issued to the user before now.
+// The cutoff only has to outlive the longest-lived token issued before it.
+func (r *TokenRevoker) RevokeUser(userID uuid.UUID) error {
+	now := time.Now().Truncate(time.Microsecond)
+	return r.add(model.TokenRevocation{
+		ID:        uuid.New(),
+		UserID:    userID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(r.tokenTTL),
+	})
+}
+
+func (r *TokenRevoker) add(rev model.TokenRevocation) error {
+	if err := r.store.Create(rev); err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(time.Now())
+	r.remember(rev)
+
+	return nil
+}
+
+// IsRevoked reports whether a token with the given id, session, subject and
+// issue time has been revoked. Issue times and cutoffs have microsecond
+// precision, so a token issued right after a log-out-everywhere stays valid.
+func (r *TokenRevoker) IsRevoked(jti string, sessionID, userID uuid.UUID, issuedAt time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if jti != "" {
+		if _, ok := r.tokens[jti]; ok {
+			return true
+		}
+	}
+	if sessionID != uuid.Nil {
+		if _, ok := r.sessions[sessionID]; ok {
+			return true
+		}
+	}
+
+	cutoff, ok := r.cutoffs[userID]
+	return ok && issuedAt.Before(cutoff.RevokedAt)
+}
+
+func (r *TokenRevoker) remember(rev model.TokenRevocation) {
+	if rev.JTI != "" {
+		r.tokens[rev.JTI] = rev.ExpiresAt
+		return
+	}
+	if rev.SessionID != nil {
+		r.sessions[*rev.SessionID] = rev.ExpiresAt
+		return
+	}
+	if current, ok := r.cutoffs[rev.UserID]; !ok || rev.RevokedAt.After(current.RevokedAt) {
+		r.cutoffs[rev.UserID] = rev
+	}
+}
+
+func (r *TokenRevoker) prune(now time.Time) {
+	for jti, expiresAt := range r.tokens {
+		if !expiresAt.After(now) {
+			delete(r.tokens, jti)
+		}
+	}
+	for sessionID, expiresAt := range r.sessions {
+		if !expiresAt.After(now) {
+			delete(r.sessions, sessionID)
+		}
+	}
+	for userID, cutoff := range r.cutoffs {
+		if !cutoff.ExpiresAt.After(now) {
+			delete(r.cutoffs, userID)
+		}
+	}
+}
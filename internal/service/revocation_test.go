@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type memoryRevocations struct {
+	revocations []model.TokenRevocation
+}
+
+func (m *memoryRevocations) Create(rev model.TokenRevocation) error {
+	m.revocations = append(m.revocations, rev)
+	return nil
+}
+
+func (m *memoryRevocations) ListActive(now time.Time) ([]model.TokenRevocation, error) {
+	active := make([]model.TokenRevocation, 0, len(m.revocations))
+	for _, rev := range m.revocations {
+		if rev.ExpiresAt.After(now) {
+			active = append(active, rev)
+		}
+	}
+	return active, nil
+}
+
+func (m *memoryRevocations) DeleteExpired(now time.Time) error {
+	m.revocations, _ = m.ListActive(now)
+	return nil
+}
+
+func TestRevokeUserCutoff(t *testing.T) {
+	revoker := NewTokenRevoker(&memoryRevocations{}, time.Minute)
+	userID := uuid.New()
+
+	before := time.Now().Add(-time.Microsecond)
+	if err := revoker.RevokeUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().Add(time.Microsecond)
+
+	if !revoker.IsRevoked("", uuid.Nil, userID, before) {
+		t.Error("token issued before the cutoff is not revoked")
+	}
+	if revoker.IsRevoked("", uuid.Nil, userID, after) {
+		t.Error("token issued after the cutoff is revoked")
+	}
+	if revoker.IsRevoked("", uuid.Nil, uuid.New(), before) {
+		t.Error("cutoff applies to another user")
+	}
+}
+
+func TestLoadPicksUpOtherInstances(t *testing.T) {
+	store := &memoryRevocations{}
+	local := NewTokenRevoker(store, time.Minute)
+	other := NewTokenRevoker(store, time.Minute)
+	userID := uuid.New()
+
+	if err := other.RevokeToken("jti", userID, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if local.IsRevoked("jti", uuid.Nil, userID, time.Now()) {
+		t.Fatal("revocation visible before reload")
+	}
+
+	if err := local.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !local.IsRevoked("jti", uuid.Nil, userID, time.Now()) {
+		t.Error("revocation not visible after reload")
+	}
+}
+
+func TestClaimedIssuedAtKeepsMicroseconds(t *testing.T) {
+	issued := time.Date(2026, time.October, 18, 12, 0, 0, 123456000, time.UTC)
+
+	got, ok := claimedIssuedAt(jwt.MapClaims{"iat": preciseTime(issued)})
+	if !ok {
+		t.Fatal("claimedIssuedAt() rejected a fractional iat")
+	}
+	if !got.Equal(issued) {
+		t.Errorf("claimedIssuedAt() = %v, want %v", got, issued)
+	}
+
+	if _, ok := claimedIssuedAt(jwt.MapClaims{}); ok {
+		t.Error("claimedIssuedAt() accepted a token without iat")
+	}
+}
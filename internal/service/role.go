@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	ErrInvalidRoleParam = errors.New("invalid role request")
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrRoleExists       = errors.New("role already exists")
+	ErrBuiltinRole      = errors.New("built-in roles cannot be changed")
+	ErrRoleInUse        = errors.New("role is still assigned to users")
+)
+
+type RoleStorage interface {
+	List() ([]model.Role, error)
+	Create(role model.Role) error
+	Update(name string, permissions []string) error
+	Delete(name string) error
+	CountUsers(name string) (int, error)
+}
+
+// RoleService manages roles and answers permission checks. Roles are kept in
+// memory and written through to the store, so checking a permission on every
+// request never touches the database. RunSync picks up changes made by other
+// instances.
+type RoleService struct {
+	store RoleStorage
+
+	mu    sync.RWMutex
+	roles map[string]model.Role
+}
+
+func NewRoleService(store RoleStorage) *RoleService {
+	return &RoleService{
+		store: store,
+		roles: make(map[string]model.Role),
+	}
+}
+
+// Load replaces the cache with the roles in the store.
+func (s *RoleService) Load() error {
+	roles, err := s.store.List()
+	if err != nil {
+		return fmt.Errorf("load roles: %w", err)
+	}
+
+	cache := make(map[string]model.Role, len(roles))
+	for _, role := range roles {
+		if role.Name == model.RoleAdmin {
+			role.Permissions = model.Permissions
+		}
+		cache[role.Name] = role
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roles = cache
+
+	return nil
+}
+
+// RunSync reloads the roles every interval until ctx is done. Errors are
+// passed to onError and retried on the next run.
+func (s *RoleService) RunSync(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Load(); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// CountUsers returns how many users hold the role.
+func (s *RoleService) CountUsers(name string) (int, error) {
+	count, err := s.store.CountUsers(name)
+	if err != nil {
+		return 0, fmt.Errorf("count role users service: %w", err)
+	}
+
+	return count, nil
+}
+
+// HasPermission reports whether the role grants the permission. The admin
+// role grants every permission; unknown roles grant none.
+func (s *RoleService) HasPermission(role, permission string) bool {
+	if role == model.RoleAdmin {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.roles[role]
+	return ok && slices.Contains(r.Permissions, permission)
+}
+
+// Covers reports whether role grants every permission other grants, i.e.
+// whether a holder of role may manage holders of other. Only the admin role
+// covers the admin role.
+func (s *RoleService) Covers(role, other string) bool {
+	if role == model.RoleAdmin {
+		return true
+	}
+	if other == model.RoleAdmin {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	granted := s.roles[role].Permissions
+	for _, permission := range s.roles[other].Permissions {
+		if !slices.Contains(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *RoleService) Exists(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.roles[name]
+	return ok
+}
+
+func (s *RoleService) List() []model.RoleResponse {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make([]model.RoleResponse, 0, len(s.roles))
+	for _, role := range s.roles {
+		roles = append(roles, toRoleResponse(role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	return roles
+}
+
+func (s *RoleService) Create(req model.RoleRequest) (*model.RoleResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRoleParam, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[req.Name]; ok {
+		return nil, ErrRoleExists
+	}
+
+	role := model.Role{
+		Name:        req.Name,
+		Permissions: normalizePermissions(req.Permissions),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.store.Create(role); err != nil {
+		return nil, fmt.Errorf("create role service: %w", err)
+	}
+	s.roles[role.Name] = role
+
+	resp := toRoleResponse(role)
+	return &resp, nil
+}
+
+func (s *RoleService) Update(name string, req model.UpdateRoleRequest) (*model.RoleResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRoleParam, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, err := s.custom(name)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = normalizePermissions(req.Permissions)
+	if err := s.store.Update(name, role.Permissions); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("update role service: %w", err)
+	}
+	s.roles[name] = role
+
+	resp := toRoleResponse(role)
+	return &resp, nil
+}
+
+// Delete removes a custom role. Roles that are still assigned to users have
+// to be taken away from them first.
+func (s *RoleService) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.custom(name); err != nil {
+		return err
+	}
+
+	count, err := s.store.CountUsers(name)
+	if err != nil {
+		return fmt.Errorf("delete role service: %w", err)
+	}
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.store.Delete(name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("delete role service: %w", err)
+	}
+	delete(s.roles, name)
+
+	return nil
+}
+
+// custom returns the cached role if it exists and can be changed. The caller
+// must hold the lock.
+func (s *RoleService) custom(name string) (model.Role, error) {
+	role, ok := s.roles[name]
+	if !ok {
+		return model.Role{}, ErrRoleNotFound
+	}
+	if role.Builtin {
+		return model.Role{}, ErrBuiltinRole
+	}
+	return role, nil
+}
+
+func normalizePermissions(permissions []string) []string {
+	normalized := slices.Clone(permissions)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+func toRoleResponse(role model.Role) model.RoleResponse {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return model.RoleResponse{
+		Name:        role.Name,
+		Permissions: permissions,
+		Builtin:     role.Builtin,
+		CreatedAt:   role.CreatedAt,
+	}
+}
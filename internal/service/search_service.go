@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/search"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	snippetWidth       = 160
+)
+
+var ErrInvalidSearch = errors.New("invalid search query")
+
+// TaskSearcher is implemented by full-text search backends. Results must be
+// restricted to tasks owned by userID and ordered by descending relevance.
+type TaskSearcher interface {
+	Search(userID uuid.UUID, query string, limit int) ([]model.SearchResult, error)
+}
+
+type SearchService struct {
+	searcher TaskSearcher
+}
+
+func NewSearchService(searcher TaskSearcher) *SearchService {
+	return &SearchService{searcher: searcher}
+}
+
+func (s *SearchService) SearchTasks(userID, query string, limit int) ([]model.SearchResult, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("search tasks service: %w", err)
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	results, err := s.searcher.Search(uuidUserID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search tasks service: %w", err)
+	}
+
+	terms := search.Terms(query)
+	for i := range results {
+		results[i].TitleHighlight = search.Highlight(results[i].Task.Title, terms)
+		results[i].Snippet = search.Snippet(results[i].Task.Description, terms, snippetWidth)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const maxUserAgentLength = 255
+
+type SessionStorage interface {
+	Create(session model.Session) error
+	GetByID(sessionID, userID uuid.UUID) (*model.Session, error)
+	ListActive(userID uuid.UUID, now time.Time) ([]model.Session, error)
+	Touch(sessionID uuid.UUID, client model.ClientInfo, at time.Time) error
+	Extend(sessionID uuid.UUID, client model.ClientInfo, at, expiresAt time.Time) error
+	Revoke(sessionID, userID uuid.UUID, at time.Time) error
+	RevokeUser(userID uuid.UUID, at time.Time) error
+}
+
+func (j *JWTService) startSession(userID uuid.UUID, client model.ClientInfo) (uuid.UUID, error) {
+	now := time.Now()
+	client = clampClient(client)
+	session := model.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(j.refreshTTL),
+	}
+
+	if err := j.sessionStore.Create(session); err != nil {
+		return uuid.Nil, fmt.Errorf("session creation err: %w", err)
+	}
+
+	return session.ID, nil
+}
+
+// ListSessions returns the user's active sessions, flagging the one the
+// request was made with.
+func (j *JWTService) ListSessions(userID string, claims jwt.MapClaims) ([]model.SessionResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions service: %w", err)
+	}
+
+	sessions, err := j.sessionStore.ListActive(uuidUserID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("list sessions service: %w", err)
+	}
+
+	current := claimedSessionID(claims)
+	resp := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, model.SessionResponse{
+			ID:         session.ID,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == current,
+		})
+	}
+
+	return resp, nil
+}
+
+// TerminateSession ends a session: its refresh tokens stop working and its
+// access tokens are rejected from now on.
+func (j *JWTService) TerminateSession(sessionID, userID string) error {
+	uuidSessionID, uuidUserID, err := parseIDPair(sessionID, userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := j.endSession(uuidSessionID, uuidUserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("terminate session service: %w", err)
+	}
+
+	return nil
+}
+
+func (j *JWTService) endSession(sessionID, userID uuid.UUID) error {
+	now := time.Now()
+	if err := j.sessionStore.Revoke(sessionID, userID, now); err != nil {
+		return err
+	}
+
+	if err := j.refreshStore.RevokeFamily(sessionID, now); err != nil {
+		return err
+	}
+
+	return j.revoker.RevokeSession(sessionID, userID)
+}
+
+// TouchSession records that the session behind claims was just used. Writes
+// are throttled per session, so most calls return without touching the store.
+func (j *JWTService) TouchSession(claims jwt.MapClaims, client model.ClientInfo) error {
+	sessionID := claimedSessionID(claims)
+	if sessionID == uuid.Nil {
+		return nil
+	}
+
+	now := time.Now()
+	if !j.touches.allow(sessionID, now) {
+		return nil
+	}
+
+	if err := j.sessionStore.Touch(sessionID, clampClient(client), now); err != nil {
+		return fmt.Errorf("touch session service: %w", err)
+	}
+
+	return nil
+}
+
+// RecentLogin reports whether claims belong to a live session of the user
+// that was started within window, i.e. whether the user has just proven who
+// they are. Refreshing a session does not make it recent.
+func (j *JWTService) RecentLogin(userID uuid.UUID, claims jwt.MapClaims, window time.Duration) (bool, error) {
+	sessionID := claimedSessionID(claims)
+	if sessionID == uuid.Nil {
+		return false, nil
+	}
+
+	session, err := j.sessionStore.GetByID(sessionID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("recent login check: %w", err)
+	}
+
+	now := time.Now()
+	return session.RevokedAt == nil && now.Before(session.ExpiresAt) && now.Sub(session.CreatedAt) <= window, nil
+}
+
+func claimedSessionID(claims jwt.MapClaims) uuid.UUID {
+	raw, _ := claims["sid"].(string)
+	sessionID, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionID
+}
+
+func clampClient(client model.ClientInfo) model.ClientInfo {
+	if len(client.UserAgent) > maxUserAgentLength {
+		client.UserAgent = client.UserAgent[:maxUserAgentLength]
+	}
+	return client
+}
+
+type touchThrottle struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[uuid.UUID]time.Time
+}
+
+func newTouchThrottle(interval time.Duration) *touchThrottle {
+	return &touchThrottle{interval: interval, last: make(map[uuid.UUID]time.Time)}
+}
+
+// allow reports whether a session may be written again and, if so, records
+// the write. Stale entries are dropped once the map grows large.
+func (t *touchThrottle) allow(sessionID uuid.UUID, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.last[sessionID]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+
+	if len(t.last) >= 1024 {
+		for id, last := range t.last {
+			if now.Sub(last) >= t.interval {
+				delete(t.last, id)
+			}
+		}
+	}
+	t.last[sessionID] = now
+
+	return true
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memorySessions struct {
+	sessions map[uuid.UUID]*model.Session
+}
+
+func (m *memorySessions) Create(session model.Session) error {
+	m.sessions[session.ID] = &session
+	return nil
+}
+
+func (m *memorySessions) GetByID(sessionID, userID uuid.UUID) (*model.Session, error) {
+	session, ok := m.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memorySessions) ListActive(userID uuid.UUID, now time.Time) ([]model.Session, error) {
+	var active []model.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			active = append(active, *session)
+		}
+	}
+	return active, nil
+}
+
+func (m *memorySessions) Touch(sessionID uuid.UUID, client model.ClientInfo, at time.Time) error {
+	if session, ok := m.sessions[sessionID]; ok {
+		session.LastSeenAt = at
+	}
+	return nil
+}
+
+func (m *memorySessions) Extend(sessionID uuid.UUID, client model.ClientInfo, at, expiresAt time.Time) error {
+	if session, ok := m.sessions[sessionID]; ok {
+		session.LastSeenAt = at
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (m *memorySessions) Revoke(sessionID, userID uuid.UUID, at time.Time) error {
+	session, ok := m.sessions[sessionID]
+	if !ok || session.UserID != userID {
+		return sql.ErrNoRows
+	}
+	session.RevokedAt = &at
+	return nil
+}
+
+func (m *memorySessions) RevokeUser(userID uuid.UUID, at time.Time) error {
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func TestListSessionsFlagsCurrent(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	laptop, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{IP: "10.0.0.1", UserAgent: strings.Repeat("x", 300)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{IP: "10.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := a.ValidateToken(laptop.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := a.ListSessions(user.ID.String(), claims)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("%d sessions, want 2", len(sessions))
+	}
+
+	var current []model.SessionResponse
+	for _, session := range sessions {
+		if session.Current {
+			current = append(current, session)
+		}
+	}
+	if len(current) != 1 || current[0].IP != "10.0.0.1" {
+		t.Fatalf("current sessions = %+v, want the laptop's", current)
+	}
+	if got := len(current[0].UserAgent); got != maxUserAgentLength {
+		t.Fatalf("user agent of %d bytes stored, want %d", got, maxUserAgentLength)
+	}
+}
+
+func TestTerminateSession(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.ValidateToken(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := claimedSessionID(claims).String()
+
+	tests := []struct {
+		name      string
+		sessionID string
+		userID    string
+	}{
+		{"another user's session", sessionID, uuid.NewString()},
+		{"unknown session", uuid.NewString(), user.ID.String()},
+		{"not an id", "current", user.ID.String()},
+	}
+	for _, tt := range tests {
+		if err := a.TerminateSession(tt.sessionID, tt.userID); !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("TerminateSession() of %s error = %v, want %v", tt.name, err, ErrSessionNotFound)
+		}
+	}
+
+	if err := a.TerminateSession(sessionID, user.ID.String()); err != nil {
+		t.Fatalf("TerminateSession() error = %v", err)
+	}
+	if _, err := a.ValidateToken(resp.Token); err == nil {
+		t.Fatal("the access token of a terminated session is still accepted")
+	}
+	if _, err := a.Refresh(resp.RefreshToken, model.ClientInfo{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Refresh() of a terminated session error = %v, want %v", err, ErrInvalidToken)
+	}
+	if sessions, _ := a.ListSessions(user.ID.String(), nil); len(sessions) != 0 {
+		t.Fatalf("ListSessions() = %+v, want none", sessions)
+	}
+}
+
+func TestRecentLogin(t *testing.T) {
+	a := newTestAuth(t)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.ValidateToken(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recent, err := a.RecentLogin(user.ID, claims, time.Minute); err != nil || !recent {
+		t.Fatalf("RecentLogin() = %t, %v; want true", recent, err)
+	}
+
+	// Refreshing extends the session but does not make it recent again.
+	session := a.sessions.sessions[claimedSessionID(claims)]
+	session.CreatedAt = session.CreatedAt.Add(-time.Hour)
+	if _, err := a.Refresh(resp.RefreshToken, model.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if recent, err := a.RecentLogin(user.ID, claims, time.Minute); err != nil || recent {
+		t.Fatalf("RecentLogin() of an old session = %t, %v; want false", recent, err)
+	}
+
+	if recent, _ := a.RecentLogin(uuid.New(), claims, time.Hour*2); recent {
+		t.Fatal("RecentLogin() accepted another user's session")
+	}
+}
+
+func TestTouchThrottle(t *testing.T) {
+	throttle := newTouchThrottle(time.Minute)
+	sessionID, other := uuid.New(), uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		sessionID uuid.UUID
+		at        time.Time
+		want      bool
+	}{
+		{"first touch", sessionID, now, true},
+		{"within the interval", sessionID, now.Add(30 * time.Second), false},
+		{"another session", other, now.Add(30 * time.Second), true},
+		{"after the interval", sessionID, now.Add(time.Minute), true},
+		{"interval counts from the last write", sessionID, now.Add(90 * time.Second), false},
+	}
+
+	for _, tt := range tests {
+		if got := throttle.allow(tt.sessionID, tt.at); got != tt.want {
+			t.Fatalf("allow() for %s = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/devvdark0/todo/internal/storage"
+	"github.com/google/uuid"
+)
+
+// The in-memory user store and JWT service shared by most service tests.
+// Stores used by a single area live next to its tests. Like those, they
+// follow the contracts of the SQL stores closely enough for the services,
+// not more.
+
+type memoryUsers struct {
+	users       map[uuid.UUID]*model.User
+	resetSentAt map[uuid.UUID]time.Time
+}
+
+func newMemoryUsers() *memoryUsers {
+	return &memoryUsers{users: make(map[uuid.UUID]*model.User), resetSentAt: make(map[uuid.UUID]time.Time)}
+}
+
+func (m *memoryUsers) Create(user model.User) error {
+	for _, other := range m.users {
+		if strings.EqualFold(other.Email, user.Email) {
+			return storage.ErrDuplicateEmail
+		}
+	}
+	m.users[user.ID] = &user
+	return nil
+}
+
+func (m *memoryUsers) GetByID(id uuid.UUID) (*model.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *memoryUsers) GetByEmail(email string) (*model.User, error) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryUsers) ReplacePassword(id uuid.UUID, current, replacement string) (bool, error) {
+	user, ok := m.users[id]
+	if !ok || user.Password != current {
+		return false, nil
+	}
+	user.Password = replacement
+	return true, nil
+}
+
+func (m *memoryUsers) UpdatePassword(id uuid.UUID, password string) error {
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.Password = password
+	return nil
+}
+
+func (m *memoryUsers) UpdateProfile(user model.User) error {
+	current, ok := m.users[user.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	current.Username, current.DisplayName, current.Timezone, current.Locale = user.Username, user.DisplayName, user.Timezone, user.Locale
+	return nil
+}
+
+// ChangeEmail enforces unique addresses like the index on users.email.
+func (m *memoryUsers) ChangeEmail(id uuid.UUID, current, replacement string) (bool, error) {
+	for _, other := range m.users {
+		if other.ID != id && strings.EqualFold(other.Email, replacement) {
+			return false, storage.ErrDuplicateEmail
+		}
+	}
+	user, ok := m.users[id]
+	if !ok || user.Email != current {
+		return false, nil
+	}
+	user.Email = replacement
+	user.EmailVerified = true
+	return true, nil
+}
+
+func (m *memoryUsers) ClaimResetSend(id uuid.UUID, at, notBefore time.Time) (bool, error) {
+	if sent, ok := m.resetSentAt[id]; ok && sent.After(notBefore) {
+		return false, nil
+	}
+	m.resetSentAt[id] = at
+	return true, nil
+}
+
+func (m *memoryUsers) ScheduleDeletion(id uuid.UUID, at *time.Time) error {
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.DeletionScheduledAt = at
+	return nil
+}
+
+func (m *memoryUsers) ListDueDeletions(now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, user := range m.users {
+		if user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(now) {
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryUsers) Delete(id uuid.UUID, now time.Time) (bool, error) {
+	user, ok := m.users[id]
+	if !ok || user.DeletionScheduledAt == nil || user.DeletionScheduledAt.After(now) {
+		return false, nil
+	}
+	delete(m.users, id)
+	return true, nil
+}
+
+func (m *memoryUsers) GetAdminUser(id uuid.UUID) (*model.AdminUser, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &model.AdminUser{User: *user}, nil
+}
+
+func (m *memoryUsers) Search(query string, limit, offset int) ([]model.AdminUser, error) {
+	var users []model.AdminUser
+	for _, user := range m.users {
+		if strings.Contains(user.Email, query) || strings.Contains(user.Username, query) {
+			users = append(users, model.AdminUser{User: *user})
+		}
+	}
+	if offset >= len(users) {
+		return nil, nil
+	}
+	return users[offset:min(offset+limit, len(users))], nil
+}
+
+func (m *memoryUsers) SetRole(id uuid.UUID, role string) error {
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.Role = role
+	return nil
+}
+
+func (m *memoryUsers) SetDisabled(id uuid.UUID, at *time.Time) error {
+	user, ok := m.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.DisabledAt = at
+	return nil
+}
+
+// testAuth is a JWTService over in-memory stores.
+type testAuth struct {
+	*JWTService
+	users    *memoryUsers
+	sessions *memorySessions
+	totp     *memoryTOTP
+	pats     *memoryPersonalTokens
+}
+
+func newTestAuth(t *testing.T) *testAuth {
+	t.Helper()
+
+	a := &testAuth{
+		users:    newMemoryUsers(),
+		sessions: &memorySessions{sessions: make(map[uuid.UUID]*model.Session)},
+		totp:     &memoryTOTP{enrolments: make(map[uuid.UUID]*model.TOTP), recoveryCodes: make(map[uuid.UUID]map[string]bool)},
+		pats:     &memoryPersonalTokens{tokens: make(map[uuid.UUID]*model.PersonalAccessToken)},
+	}
+	a.JWTService = NewJWTService(
+		JWTOptions{
+			Secret:       []byte("test secret"),
+			TokenTTL:     time.Minute,
+			RefreshTTL:   time.Hour,
+			ChallengeTTL: time.Minute,
+			Passwords:    testHasher(t),
+		},
+		AuthStores{
+			Users:         a.users,
+			RefreshTokens: &memoryRefreshTokens{tokens: make(map[string]*model.RefreshToken)},
+			Sessions:      a.sessions,
+			TOTP:          a.totp,
+			AccessTokens:  a.pats,
+		},
+		NewTokenRevoker(&memoryRevocations{}, time.Minute),
+		nil,
+	)
+	return a
+}
+
+// testHasher hashes with the cheapest bcrypt cost so tests stay fast.
+func testHasher(t *testing.T) *auth.PasswordHasher {
+	t.Helper()
+
+	hasher, err := auth.NewPasswordHasher(auth.PasswordHashOptions{Algorithm: "bcrypt", BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+// addUser stores a verified user with the given password.
+func (a *testAuth) addUser(t *testing.T, email, password string) *model.User {
+	t.Helper()
+
+	hashed, err := a.passwords.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := model.User{
+		ID:            uuid.New(),
+		Username:      strings.Split(email, "@")[0],
+		Email:         email,
+		Password:      hashed,
+		EmailVerified: true,
+		Role:          model.RoleUser,
+	}
+	if err := a.users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
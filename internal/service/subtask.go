@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// Subtask policies decide what happens to the children of a task when it is
+// completed or deleted.
+const (
+	SubtaskCascade = "cascade"
+	SubtaskBlock   = "block"
+	SubtaskOrphan  = "orphan"
+)
+
+var (
+	ErrTaskCycle        = errors.New("task cannot be nested under itself or its subtasks")
+	ErrParentNotFound   = errors.New("parent task not found")
+	ErrOpenSubtasks     = errors.New("task has open subtasks")
+	ErrHasSubtasks      = errors.New("task has subtasks")
+	ErrUnknownSubPolicy = errors.New("unknown subtask policy")
+)
+
+func validSubtaskPolicy(policy string) bool {
+	switch policy {
+	case SubtaskCascade, SubtaskBlock, SubtaskOrphan:
+		return true
+	}
+	return false
+}
+
+func (s *TodoService) GetTaskTree(taskID, userID string) (*model.TaskTree, error) {
+	task, err := s.GetTaskByID(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	descendants, err := s.storage.ListDescendants(task.ID, task.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("get task tree service: %w", err)
+	}
+
+	children := make(map[uuid.UUID][]model.Task)
+	for _, d := range descendants {
+		children[*d.ParentID] = append(children[*d.ParentID], d)
+	}
+
+	tree := buildTree(*task, children)
+	return &tree, nil
+}
+
+func buildTree(task model.Task, children map[uuid.UUID][]model.Task) model.TaskTree {
+	node := model.TaskTree{Task: task, Subtasks: make([]model.TaskTree, 0, len(children[task.ID]))}
+	for _, child := range children[task.ID] {
+		node.Subtasks = append(node.Subtasks, buildTree(child, children))
+	}
+	return node
+}
+
+func (s *TodoService) resolveParent(raw string, taskID, userID uuid.UUID) (*uuid.UUID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	parentID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTask, err)
+	}
+
+	if parentID == taskID {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTask, ErrTaskCycle)
+	}
+
+	if _, err := s.storage.GetByID(parentID, userID); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTask, ErrParentNotFound)
+	}
+
+	descendants, err := s.storage.ListDescendants(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range descendants {
+		if d.ID == parentID {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTask, ErrTaskCycle)
+		}
+	}
+
+	return &parentID, nil
+}
+
+// completion works out what completing task changes besides the task
+// itself. Nothing is written; the caller saves it all with the task.
+func (s *TodoService) completion(task *model.Task) (*model.TaskCompletion, error) {
+	subtasks, err := s.openSubtasks(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var next *model.Task
+	if task.Recurrence != "" {
+		if next, err = nextInSeries(task); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.TaskCompletion{Subtasks: subtasks, Next: next}, nil
+}
+
+// openSubtasks returns the open subtasks that completing task closes, or
+// ErrOpenSubtasks if the block policy forbids completing it.
+func (s *TodoService) openSubtasks(task *model.Task) ([]uuid.UUID, error) {
+	if s.subtaskPolicy == SubtaskOrphan {
+		return nil, nil
+	}
+
+	descendants, err := s.storage.ListDescendants(task.ID, task.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	open := make([]uuid.UUID, 0, len(descendants))
+	for _, d := range descendants {
+		if !d.IsDone {
+			open = append(open, d.ID)
+		}
+	}
+	if len(open) == 0 {
+		return nil, nil
+	}
+
+	if s.subtaskPolicy == SubtaskBlock {
+		return nil, ErrOpenSubtasks
+	}
+
+	return open, nil
+}
+
+// subtasksToDelete returns the subtasks that deleting the task removes with
+// it, or ErrHasSubtasks if the block policy forbids deleting it. Nothing is
+// written; the caller deletes them together with the task.
+func (s *TodoService) subtasksToDelete(taskID, userID uuid.UUID) ([]uuid.UUID, error) {
+	if s.subtaskPolicy == SubtaskOrphan {
+		return nil, nil
+	}
+
+	descendants, err := s.storage.ListDescendants(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(descendants) == 0 {
+		return nil, nil
+	}
+
+	if s.subtaskPolicy == SubtaskBlock {
+		return nil, ErrHasSubtasks
+	}
+
+	ids := make([]uuid.UUID, 0, len(descendants))
+	for _, d := range descendants {
+		ids = append(ids, d.ID)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTagExists   = errors.New("tag with such name already exists")
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+type TagStorage interface {
+	Create(tag model.Tag) error
+	GetByID(tagID, userID uuid.UUID) (*model.Tag, error)
+	GetByName(name string, userID uuid.UUID) (*model.Tag, error)
+	List(userID uuid.UUID) ([]model.Tag, error)
+	Update(tagID, userID uuid.UUID, tag model.Tag) error
+	Delete(tagID, userID uuid.UUID) error
+}
+
+type TagService struct {
+	storage TagStorage
+}
+
+func NewTagService(store TagStorage) *TagService {
+	return &TagService{storage: store}
+}
+
+func normalizeTagName(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), "#")
+}
+
+func (s *TagService) ListTags(userID string) ([]model.Tag, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("list tags service: %w", err)
+	}
+
+	tags, err := s.storage.List(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("list tags service: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (s *TagService) GetTag(tagID, userID string) (*model.Tag, error) {
+	uuidTagID, uuidUserID, err := parseIDPair(tagID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get tag service: %w", err)
+	}
+
+	tag, err := s.storage.GetByID(uuidTagID, uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTagNotFound
+		}
+		return nil, fmt.Errorf("get tag service: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (s *TagService) CreateTag(userID string, req model.CreateTagRequest) (*model.Tag, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("create tag service: %w", err)
+	}
+
+	req.Name = normalizeTagName(req.Name)
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTag, err)
+	}
+
+	if err := s.ensureNameFree(req.Name, uuidUserID, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	tag := model.Tag{
+		ID:        uuid.New(),
+		UserID:    uuidUserID,
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.storage.Create(tag); err != nil {
+		return nil, fmt.Errorf("create tag service: %w", err)
+	}
+
+	return &tag, nil
+}
+
+func (s *TagService) UpdateTag(tagID, userID string, req model.UpdateTagRequest) error {
+	uuidTagID, uuidUserID, err := parseIDPair(tagID, userID)
+	if err != nil {
+		return fmt.Errorf("update tag service: %w", err)
+	}
+
+	req.Name = normalizeTagName(req.Name)
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTag, err)
+	}
+
+	tag, err := s.storage.GetByID(uuidTagID, uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		return fmt.Errorf("update tag service: %w", err)
+	}
+
+	if err := s.ensureNameFree(req.Name, uuidUserID, tag.ID); err != nil {
+		return err
+	}
+
+	tag.Name = req.Name
+	if err := s.storage.Update(uuidTagID, uuidUserID, *tag); err != nil {
+		return fmt.Errorf("update tag service: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TagService) DeleteTag(tagID, userID string) error {
+	uuidTagID, uuidUserID, err := parseIDPair(tagID, userID)
+	if err != nil {
+		return fmt.Errorf("delete tag service: %w", err)
+	}
+
+	if _, err := s.storage.GetByID(uuidTagID, uuidUserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTagNotFound
+		}
+		return fmt.Errorf("delete tag service: %w", err)
+	}
+
+	if err := s.storage.Delete(uuidTagID, uuidUserID); err != nil {
+		return fmt.Errorf("delete tag service: %w", err)
+	}
+
+	return nil
+}
+
+func (s *TagService) ensureNameFree(name string, userID, selfID uuid.UUID) error {
+	existing, err := s.storage.GetByName(name, userID)
+	if err == nil && existing.ID != selfID {
+		return ErrTagExists
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("tag lookup: %w", err)
+	}
+
+	return nil
+}
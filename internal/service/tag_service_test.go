@@ -0,0 +1,143 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// memoryTags matches names without regard to case, as the column's
+// collation does.
+type memoryTags struct {
+	tags map[uuid.UUID]model.Tag
+}
+
+func (m *memoryTags) Create(tag model.Tag) error {
+	m.tags[tag.ID] = tag
+	return nil
+}
+
+func (m *memoryTags) GetByID(tagID, userID uuid.UUID) (*model.Tag, error) {
+	tag, ok := m.tags[tagID]
+	if !ok || tag.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	return &tag, nil
+}
+
+func (m *memoryTags) GetByName(name string, userID uuid.UUID) (*model.Tag, error) {
+	for _, tag := range m.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, name) {
+			return &tag, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryTags) List(userID uuid.UUID) ([]model.Tag, error) {
+	var tags []model.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (m *memoryTags) Update(tagID, userID uuid.UUID, tag model.Tag) error {
+	m.tags[tagID] = tag
+	return nil
+}
+
+func (m *memoryTags) Delete(tagID, userID uuid.UUID) error {
+	delete(m.tags, tagID)
+	return nil
+}
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"work", "work"},
+		{"  work ", "work"},
+		{"#work", "work"},
+		{" #work", "work"},
+		{"##work", "#work"},
+		{"#", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTagName(tt.name); got != tt.want {
+			t.Errorf("normalizeTagName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCreateTag(t *testing.T) {
+	s := NewTagService(&memoryTags{tags: make(map[uuid.UUID]model.Tag)})
+	alice, bob := uuid.NewString(), uuid.NewString()
+
+	tag, err := s.CreateTag(alice, model.CreateTagRequest{Name: " #Work "})
+	if err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if tag.Name != "Work" {
+		t.Fatalf("name = %q, want %q", tag.Name, "Work")
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		tagName string
+		wantErr error
+	}{
+		{"same name", alice, "Work", ErrTagExists},
+		{"differs in case", alice, "#work", ErrTagExists},
+		{"another user", bob, "work", nil},
+		{"empty after the hash", alice, "#", ErrInvalidTag},
+		{"comma", alice, "a,b", ErrInvalidTag},
+		{"too long", alice, strings.Repeat("x", 65), ErrInvalidTag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateTag(tt.userID, model.CreateTagRequest{Name: tt.tagName})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateTag() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateAndDeleteTag(t *testing.T) {
+	s := NewTagService(&memoryTags{tags: make(map[uuid.UUID]model.Tag)})
+	alice := uuid.NewString()
+	work, _ := s.CreateTag(alice, model.CreateTagRequest{Name: "work"})
+	if _, err := s.CreateTag(alice, model.CreateTagRequest{Name: "home"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Renaming a tag to its own name in another case is not a clash.
+	if err := s.UpdateTag(work.ID.String(), alice, model.UpdateTagRequest{Name: "Work"}); err != nil {
+		t.Fatalf("UpdateTag() error = %v", err)
+	}
+	if err := s.UpdateTag(work.ID.String(), alice, model.UpdateTagRequest{Name: "home"}); !errors.Is(err, ErrTagExists) {
+		t.Fatalf("UpdateTag() to a taken name error = %v, want %v", err, ErrTagExists)
+	}
+
+	// Other users' tags are not found.
+	if err := s.DeleteTag(work.ID.String(), uuid.NewString()); !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("DeleteTag() by another user error = %v, want %v", err, ErrTagNotFound)
+	}
+	if err := s.DeleteTag(work.ID.String(), alice); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	if _, err := s.GetTag(work.ID.String(), alice); !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("GetTag() after delete error = %v, want %v", err, ErrTagNotFound)
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,34 +12,139 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidTask   = errors.New("invalid task")
+	ErrInvalidFilter = errors.New("invalid task filter")
+)
+
 type TaskStorage interface {
-	Create(task model.Task) error
+	Create(task model.Task, tagIDs []uuid.UUID) error
 	GetByID(taskID, userID uuid.UUID) (*model.Task, error)
 	GetByTitle(title string, userID uuid.UUID) (*model.Task, error)
-	Update(taskID, userID uuid.UUID, task model.Task) error
-	List(userID uuid.UUID) ([]model.Task, error)
-	Delete(taskID, userID uuid.UUID) error
+	Update(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID) error
+	List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error)
+	Delete(taskID, userID uuid.UUID, subtasks []uuid.UUID) error
+	ListDescendants(taskID, userID uuid.UUID) ([]model.Task, error)
+	Complete(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID, completion model.TaskCompletion) error
+}
+
+type TaskOptions struct {
+	SubtaskPolicy string
+	MaxPageSize   int
 }
 
 type TodoService struct {
-	storage TaskStorage
+	storage       TaskStorage
+	projects      ProjectStorage
+	subtaskPolicy string
+	maxPageSize   int
 }
 
-func NewService(store *storage.TodoStore) *TodoService {
-	return &TodoService{storage: store}
+func NewService(store *storage.TodoStore, projects ProjectStorage, opts TaskOptions) (*TodoService, error) {
+	if !validSubtaskPolicy(opts.SubtaskPolicy) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSubPolicy, opts.SubtaskPolicy)
+	}
+
+	if opts.MaxPageSize <= 0 {
+		return nil, fmt.Errorf("max page size must be positive, got %d", opts.MaxPageSize)
+	}
+
+	return &TodoService{
+		storage:       store,
+		projects:      projects,
+		subtaskPolicy: opts.SubtaskPolicy,
+		maxPageSize:   opts.MaxPageSize,
+	}, nil
 }
 
-func (s *TodoService) ListTasks(userID string) ([]model.Task, error) {
+func (s *TodoService) ListTasks(userID string, req model.ListTasksRequest) (*model.TaskPage, error) {
 	uuidUserID, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, fmt.Errorf("user id parsing err: %w", err)
 	}
-	tasks, err := s.storage.List(uuidUserID)
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+
+	filter, err := dueFilter(req, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("list tasks service: %w", err)
+	}
+	filter.Sort = req.Sort
+	filter.Order = req.Order
+	filter.Limit = s.pageSize(req.Limit)
+	filter.IsDone = mergeIsDone(filter.IsDone, req.IsDone)
+	if req.Cursor != "" {
+		if filter.After, err = decodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	if filter.CreatedFrom, err = parseBound(req.CreatedAfter); err != nil {
+		return nil, err
+	}
+	if filter.CreatedTo, err = parseBound(req.CreatedBefore); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedFrom, err = parseBound(req.UpdatedAfter); err != nil {
+		return nil, err
+	}
+	if filter.UpdatedTo, err = parseBound(req.UpdatedBefore); err != nil {
+		return nil, err
+	}
+	if filter.CompletedFrom, err = parseBound(req.CompletedAfter); err != nil {
+		return nil, err
+	}
+	if filter.CompletedTo, err = parseBound(req.CompletedBefore); err != nil {
+		return nil, err
+	}
+	if req.Query != "" {
+		loc, err := loadLocation(req.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Condition, filter.ConditionArgs, err = compileQuery(req.Query, time.Now(), loc); err != nil {
+			return nil, err
+		}
+	}
+	if req.ProjectID != "" {
+		projectID := uuid.Nil
+		if req.ProjectID != model.InboxProject {
+			if projectID, err = uuid.Parse(req.ProjectID); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+			}
+		}
+		filter.ProjectID = &projectID
+	}
+	filter.TagMatch = req.TagMatch
+	for _, name := range req.Tags {
+		if name = normalizeTagName(name); name != "" {
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+
+	page, err := s.paginate(func(f model.TaskFilter) ([]model.Task, error) {
+		return s.storage.List(uuidUserID, f)
+	}, filter)
 	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
 		return nil, fmt.Errorf("list tasks service: %w", err)
 	}
 
-	return tasks, nil
+	return page, nil
+}
+
+// mergeIsDone combines the completion state implied by the due filter with
+// the one requested explicitly; an explicit is_done always wins, so
+// due=overdue&is_done=true lists completed tasks that are past their due date.
+func mergeIsDone(implied, requested *bool) *bool {
+	if requested != nil {
+		return requested
+	}
+	return implied
 }
 
 func (s *TodoService) GetTaskByID(taskID, userID string) (*model.Task, error) {
@@ -62,7 +169,17 @@ func (s *TodoService) GetTaskByID(taskID, userID string) (*model.Task, error) {
 func (s *TodoService) CreateTask(req model.CreateTaskRequest) error {
 	validator := validator.New()
 	if err := validator.Struct(req); err != nil {
-		return fmt.Errorf("create user service: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidTask, err)
+	}
+
+	dueAt, allDay, err := parseDueDate(req.DueDate, req.DueTimezone)
+	if err != nil {
+		return fmt.Errorf("create task service: %w", err)
+	}
+
+	rule, err := normalizeRecurrence(req.Recurrence, dueAt)
+	if err != nil {
+		return fmt.Errorf("create task service: %w", err)
 	}
 
 	id := uuid.New()
@@ -71,18 +188,45 @@ func (s *TodoService) CreateTask(req model.CreateTaskRequest) error {
 		return fmt.Errorf("create task service: %w", err)
 	}
 
+	parentID, err := s.resolveParent(req.ParentID, id, userID)
+	if err != nil {
+		return fmt.Errorf("create task service: %w", err)
+	}
+
+	projectID, err := s.resolveProject(req.ProjectID, userID)
+	if err != nil {
+		return fmt.Errorf("create task service: %w", err)
+	}
+
+	tagIDs, err := parseTagIDs(req.TagIDs)
+	if err != nil {
+		return fmt.Errorf("create task service: %w", err)
+	}
+
 	task := model.Task{
 		ID:          id,
 		Title:       req.Title,
 		Description: req.Description,
 		IsDone:      req.IsDone,
+		Priority:    req.Priority,
 		UserId:      userID,
+		ParentID:    parentID,
+		ProjectID:   projectID,
+		DueAt:       dueAt,
+		DueAllDay:   allDay,
+		DueTimezone: req.DueTimezone,
+		Recurrence:  rule,
+		Occurrence:  1,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := s.storage.Create(task); err != nil {
-		return fmt.Errorf("create user service: %w", err)
+	if task.IsDone {
+		task.CompletedAt = &task.CreatedAt
+	}
+
+	if err := s.storage.Create(task, tagIDs); err != nil {
+		return fmt.Errorf("create task service: %w", tagError(err))
 	}
 
 	return nil
@@ -99,9 +243,14 @@ func (s *TodoService) UpdateTask(taskID, userID string, req model.UpdateTaskRequ
 		return fmt.Errorf("update task service: %w", err)
 	}
 
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTask, err)
+	}
+
 	task, err := s.storage.GetByID(uuidTaskID, uuidUserID)
 	if err != nil {
-		return fmt.Errorf("delete task service: %w", err)
+		return fmt.Errorf("update task service: %w", err)
 	}
 
 	if req.Title != nil {
@@ -110,12 +259,71 @@ func (s *TodoService) UpdateTask(taskID, userID string, req model.UpdateTaskRequ
 	if req.Description != nil {
 		task.Description = *req.Description
 	}
+	if req.ParentID != nil {
+		parentID, err := s.resolveParent(*req.ParentID, uuidTaskID, uuidUserID)
+		if err != nil {
+			return fmt.Errorf("update task service: %w", err)
+		}
+		task.ParentID = parentID
+	}
+	if req.ProjectID != nil {
+		projectID, err := s.resolveProject(*req.ProjectID, uuidUserID)
+		if err != nil {
+			return fmt.Errorf("update task service: %w", err)
+		}
+		task.ProjectID = projectID
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+	if req.DueTimezone != nil {
+		task.DueTimezone = *req.DueTimezone
+	}
+	if req.DueDate != nil {
+		dueAt, allDay, err := parseDueDate(*req.DueDate, task.DueTimezone)
+		if err != nil {
+			return fmt.Errorf("update task service: %w", err)
+		}
+		task.DueAt = dueAt
+		task.DueAllDay = allDay
+	}
+	if req.Recurrence != nil {
+		task.Recurrence = *req.Recurrence
+	}
+	if task.Recurrence, err = normalizeRecurrence(task.Recurrence, task.DueAt); err != nil {
+		return fmt.Errorf("update task service: %w", err)
+	}
+	now := time.Now()
+	var completion *model.TaskCompletion
 	if req.IsDone != nil {
+		if *req.IsDone && !task.IsDone {
+			if completion, err = s.completion(task); err != nil {
+				return fmt.Errorf("update task service: %w", err)
+			}
+			task.CompletedAt = &now
+		}
+		if !*req.IsDone {
+			task.CompletedAt = nil
+		}
 		task.IsDone = *req.IsDone
 	}
+	task.UpdatedAt = now
 
-	if err := s.storage.Update(uuidTaskID, uuidUserID, *task); err != nil {
-		return fmt.Errorf("delete task service: %w", err)
+	// Nil tag ids leave the tags alone; an empty list removes them all.
+	var tagIDs []uuid.UUID
+	if req.TagIDs != nil {
+		if tagIDs, err = parseTagIDs(*req.TagIDs); err != nil {
+			return fmt.Errorf("update task service: %w", err)
+		}
+	}
+
+	if completion != nil {
+		err = s.storage.Complete(uuidTaskID, uuidUserID, *task, tagIDs, *completion)
+	} else {
+		err = s.storage.Update(uuidTaskID, uuidUserID, *task, tagIDs)
+	}
+	if err != nil {
+		return fmt.Errorf("update task service: %w", tagError(err))
 	}
 
 	return nil
@@ -131,9 +339,65 @@ func (s *TodoService) DeleteTask(taskID, userID string) error {
 		return fmt.Errorf("delete task service: %w", err)
 	}
 
-	if err = s.storage.Delete(uuidTaskID, uuidUserID); err != nil {
-		return fmt.Errorf("update task service: %w", err)
+	if _, err := s.storage.GetByID(uuidTaskID, uuidUserID); err != nil {
+		return fmt.Errorf("delete task service: %w", err)
+	}
+
+	subtasks, err := s.subtasksToDelete(uuidTaskID, uuidUserID)
+	if err != nil {
+		return fmt.Errorf("delete task service: %w", err)
+	}
+
+	if err = s.storage.Delete(uuidTaskID, uuidUserID, subtasks); err != nil {
+		return fmt.Errorf("delete task service: %w", err)
 	}
 
 	return nil
 }
+
+// parseTagIDs parses and deduplicates tag ids. The result is never nil.
+func parseTagIDs(tagIDs []string) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(tagIDs))
+	ids := make([]uuid.UUID, 0, len(tagIDs))
+	for _, raw := range tagIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTask, err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// tagError reports the storage's answer to unknown tag ids as
+// ErrTagNotFound.
+func tagError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrInvalidTask, ErrTagNotFound)
+	}
+	return err
+}
+
+func (s *TodoService) resolveProject(raw string, userID uuid.UUID) (*uuid.UUID, error) {
+	if raw == "" || raw == model.InboxProject {
+		return nil, nil
+	}
+
+	projectID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTask, err)
+	}
+
+	if _, err := s.projects.GetByID(projectID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTask, ErrProjectNotFound)
+		}
+		return nil, err
+	}
+
+	return &projectID, nil
+}
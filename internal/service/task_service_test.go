@@ -0,0 +1,270 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// memoryTaskStore keeps tasks in memory. Like the SQL store, a write that
+// names an unknown tag changes nothing and reports sql.ErrNoRows.
+type memoryTaskStore struct {
+	tasks map[uuid.UUID]*model.Task
+	tags  map[uuid.UUID]model.Tag
+}
+
+func newMemoryTaskStore() *memoryTaskStore {
+	return &memoryTaskStore{tasks: make(map[uuid.UUID]*model.Task), tags: make(map[uuid.UUID]model.Tag)}
+}
+
+func (m *memoryTaskStore) addTag(userID uuid.UUID, name string) model.Tag {
+	tag := model.Tag{ID: uuid.New(), UserID: userID, Name: name}
+	m.tags[tag.ID] = tag
+	return tag
+}
+
+func (m *memoryTaskStore) resolveTags(userID uuid.UUID, tagIDs []uuid.UUID) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(tagIDs))
+	for _, id := range tagIDs {
+		tag, ok := m.tags[id]
+		if !ok || tag.UserID != userID {
+			return nil, sql.ErrNoRows
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func (m *memoryTaskStore) Create(task model.Task, tagIDs []uuid.UUID) error {
+	tags, err := m.resolveTags(task.UserId, tagIDs)
+	if err != nil {
+		return err
+	}
+	task.Tags = tags
+	m.tasks[task.ID] = &task
+	return nil
+}
+
+func (m *memoryTaskStore) GetByID(taskID, userID uuid.UUID) (*model.Task, error) {
+	task, ok := m.tasks[taskID]
+	if !ok || task.UserId != userID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *task
+	return &copied, nil
+}
+
+func (m *memoryTaskStore) GetByTitle(title string, userID uuid.UUID) (*model.Task, error) {
+	for _, task := range m.tasks {
+		if task.Title == title && task.UserId == userID {
+			copied := *task
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryTaskStore) Update(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID) error {
+	if tagIDs != nil {
+		tags, err := m.resolveTags(userID, tagIDs)
+		if err != nil {
+			return err
+		}
+		task.Tags = tags
+	}
+	if _, ok := m.tasks[taskID]; ok {
+		m.tasks[taskID] = &task
+	}
+	return nil
+}
+
+func (m *memoryTaskStore) List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error) {
+	var tasks []model.Task
+	for _, task := range m.tasks {
+		if task.UserId == userID {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *memoryTaskStore) Delete(taskID, userID uuid.UUID, subtasks []uuid.UUID) error {
+	for _, id := range append(subtasks, taskID) {
+		if task, ok := m.tasks[id]; ok && task.UserId == userID {
+			delete(m.tasks, id)
+		}
+	}
+	return nil
+}
+
+func (m *memoryTaskStore) ListDescendants(taskID, userID uuid.UUID) ([]model.Task, error) {
+	var tasks []model.Task
+	for _, task := range m.tasks {
+		if task.UserId == userID && task.ParentID != nil && *task.ParentID == taskID {
+			tasks = append(tasks, *task)
+			below, _ := m.ListDescendants(task.ID, userID)
+			tasks = append(tasks, below...)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *memoryTaskStore) Complete(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID, completion model.TaskCompletion) error {
+	if err := m.Update(taskID, userID, task, tagIDs); err != nil {
+		return err
+	}
+	for _, id := range completion.Subtasks {
+		m.tasks[id].IsDone = true
+	}
+	if next := completion.Next; next != nil {
+		next.Tags = m.tasks[taskID].Tags
+		m.tasks[next.ID] = next
+	}
+	return nil
+}
+
+func newTestTasks(policy string) (*TodoService, *memoryTaskStore) {
+	store := newMemoryTaskStore()
+	return &TodoService{storage: store, subtaskPolicy: policy, maxPageSize: 100}, store
+}
+
+func tagNames(task *model.Task) []string {
+	names := make([]string, 0, len(task.Tags))
+	for _, tag := range task.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestCreateTaskWithTags(t *testing.T) {
+	s, store := newTestTasks(SubtaskOrphan)
+	userID := uuid.New()
+	work := store.addTag(userID, "work")
+	other := store.addTag(uuid.New(), "other")
+
+	req := model.CreateTaskRequest{Title: "report", UserID: userID.String(), TagIDs: []string{work.ID.String(), work.ID.String()}}
+	if err := s.CreateTask(req); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	task, err := store.GetByTitle("report", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tagNames(task); len(got) != 1 || got[0] != "work" {
+		t.Fatalf("tags = %v, want [work]", got)
+	}
+
+	// A tag of another user is unknown, and the task is not created.
+	req = model.CreateTaskRequest{Title: "leak", UserID: userID.String(), TagIDs: []string{work.ID.String(), other.ID.String()}}
+	if err := s.CreateTask(req); !errors.Is(err, ErrTagNotFound) || !errors.Is(err, ErrInvalidTask) {
+		t.Fatalf("CreateTask() error = %v, want %v", err, ErrTagNotFound)
+	}
+	if _, err := store.GetByTitle("leak", userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("the task was stored despite the unknown tag: %v", err)
+	}
+}
+
+func TestUpdateTaskTags(t *testing.T) {
+	s, store := newTestTasks(SubtaskOrphan)
+	userID := uuid.New()
+	work := store.addTag(userID, "work")
+	home := store.addTag(userID, "home")
+	if err := s.CreateTask(model.CreateTaskRequest{Title: "report", UserID: userID.String(), TagIDs: []string{work.ID.String()}}); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := store.GetByTitle("report", userID)
+	taskID, user := task.ID.String(), userID.String()
+
+	title := "quarterly report"
+	if err := s.UpdateTask(taskID, user, model.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if got := tagNames(store.tasks[task.ID]); len(got) != 1 || got[0] != "work" {
+		t.Fatalf("tags after an update without tag_ids = %v, want [work]", got)
+	}
+
+	// An unknown tag leaves the whole update unapplied.
+	renamed, unknown := "renamed", []string{home.ID.String(), uuid.NewString()}
+	err := s.UpdateTask(taskID, user, model.UpdateTaskRequest{Title: &renamed, TagIDs: &unknown})
+	if !errors.Is(err, ErrTagNotFound) {
+		t.Fatalf("UpdateTask() error = %v, want %v", err, ErrTagNotFound)
+	}
+	if got := store.tasks[task.ID].Title; got != title {
+		t.Fatalf("title = %q, want %q", got, title)
+	}
+
+	done, none := true, []string{}
+	if err := s.UpdateTask(taskID, user, model.UpdateTaskRequest{IsDone: &done, TagIDs: &none}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if got := store.tasks[task.ID]; !got.IsDone || len(got.Tags) != 0 {
+		t.Fatalf("task = %+v, want it done without tags", got)
+	}
+}
+
+func TestDeleteTaskSubtaskPolicies(t *testing.T) {
+	tests := []struct {
+		policy    string
+		wantErr   error
+		remaining int
+	}{
+		{SubtaskCascade, nil, 0},
+		{SubtaskOrphan, nil, 2},
+		{SubtaskBlock, ErrHasSubtasks, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s, store := newTestTasks(tt.policy)
+			userID := uuid.New()
+			parent := model.Task{ID: uuid.New(), Title: "parent", UserId: userID}
+			child := model.Task{ID: uuid.New(), Title: "child", UserId: userID, ParentID: &parent.ID}
+			grandchild := model.Task{ID: uuid.New(), Title: "grandchild", UserId: userID, ParentID: &child.ID}
+			for _, task := range []model.Task{parent, child, grandchild} {
+				if err := store.Create(task, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := s.DeleteTask(parent.ID.String(), userID.String())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteTask() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(store.tasks) != tt.remaining {
+				t.Fatalf("%d tasks left, want %d", len(store.tasks), tt.remaining)
+			}
+		})
+	}
+}
+
+func TestTaskPriority(t *testing.T) {
+	s, store := newTestTasks(SubtaskOrphan)
+	userID := uuid.New()
+
+	err := s.CreateTask(model.CreateTaskRequest{Title: "urgent", UserID: userID.String(), Priority: "critical"})
+	if !errors.Is(err, ErrInvalidTask) {
+		t.Fatalf("CreateTask() with an unknown priority error = %v, want %v", err, ErrInvalidTask)
+	}
+
+	if err := s.CreateTask(model.CreateTaskRequest{Title: "report", UserID: userID.String(), Priority: model.PriorityHigh}); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	task, _ := store.GetByTitle("report", userID)
+	if task.Priority != model.PriorityHigh {
+		t.Fatalf("priority = %s, want %s", task.Priority, model.PriorityHigh)
+	}
+
+	low, unknown := model.PriorityLow, model.Priority("critical")
+	if err := s.UpdateTask(task.ID.String(), userID.String(), model.UpdateTaskRequest{Priority: &unknown}); !errors.Is(err, ErrInvalidTask) {
+		t.Fatalf("UpdateTask() with an unknown priority error = %v, want %v", err, ErrInvalidTask)
+	}
+	if err := s.UpdateTask(task.ID.String(), userID.String(), model.UpdateTaskRequest{Priority: &low}); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if got := store.tasks[task.ID].Priority; got != model.PriorityLow {
+		t.Fatalf("priority = %s, want %s", got, model.PriorityLow)
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorReq  = errors.New("invalid two-factor request")
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not configured on this server")
+)
+
+const (
+	tokenTypeAccess    = "access"
+	tokenTypeChallenge = "2fa_challenge"
+	// The typ headers of the two token types. Access tokens use the one
+	// registered for JWT access tokens in RFC 9068.
+	headerTypeAccess    = "at+jwt"
+	headerTypeChallenge = "2fa-challenge+jwt"
+
+	recoveryCodeCount = 10
+	// maxTwoFactorAttempts wrong codes in a row lock a user's second factor
+	// for twoFactorLockout, whichever challenge or endpoint they came from.
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+type TOTPStorage interface {
+	Get(userID uuid.UUID) (*model.TOTP, error)
+	SavePending(totp model.TOTP) error
+	Confirm(userID uuid.UUID, step int64, at time.Time, codeHashes []string) error
+	ClaimAttempt(userID uuid.UUID, now time.Time, maxAttempts int, lockUntil time.Time) (bool, error)
+	ResetAttempts(userID uuid.UUID) error
+	UpdateSecret(userID uuid.UUID, secret string) error
+	UseStep(userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(userID uuid.UUID, hash string, at time.Time) (bool, error)
+	Delete(userID uuid.UUID) error
+}
+
+// EnrollTOTP starts enrolment with a new secret. The secret has no effect
+// until ConfirmTOTP has seen a code generated from it.
+func (j *JWTService) EnrollTOTP(userID string) (*model.TOTPEnrollResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+
+	enabled, err := j.twoFactorEnabled(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+	if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	user, err := j.userStore.GetByID(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+	sealed, err := j.totpKeys.Seal(secret)
+	if err != nil {
+		if errors.Is(err, auth.ErrNoSealKey) {
+			return nil, ErrTwoFactorUnavailable
+		}
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+
+	totp := model.TOTP{UserID: uuidUserID, Secret: sealed, CreatedAt: time.Now()}
+	if err := j.totpStore.SavePending(totp); err != nil {
+		return nil, fmt.Errorf("enroll totp service: %w", err)
+	}
+
+	return &model.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(j.totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their
+// authenticator works, and returns a fresh set of recovery codes. The codes
+// are only ever shown here.
+func (j *JWTService) ConfirmTOTP(userID string, req model.TOTPCodeRequest) (*model.RecoveryCodesResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("confirm totp service: %w", err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTwoFactorReq, err)
+	}
+
+	totp, err := j.totpStore.Get(uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, fmt.Errorf("confirm totp service: %w", err)
+	}
+	if totp.ConfirmedAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := j.totpKeys.Open(totp.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("confirm totp service: %w", err)
+	}
+
+	step, ok := auth.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("confirm totp service: %w", err)
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	}
+
+	if err := j.totpStore.Confirm(uuidUserID, step, time.Now(), hashes); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, fmt.Errorf("confirm totp service: %w", err)
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It asks for both the
+// password and a current code so a stolen session alone cannot do it, and
+// counts wrong answers against the same limit as login codes.
+func (j *JWTService) DisableTOTP(userID string, req model.DisableTOTPRequest) error {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("disable totp service: %w", err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTwoFactorReq, err)
+	}
+
+	user, err := j.userStore.GetByID(uuidUserID)
+	if err != nil {
+		return fmt.Errorf("disable totp service: %w", err)
+	}
+
+	totp, err := j.enrolledTOTP(uuidUserID)
+	if err != nil {
+		return err
+	}
+	if err := j.claimTwoFactorAttempt(totp); err != nil {
+		return err
+	}
+
+	if _, err := j.passwords.Verify(user.Password, req.Password); err != nil {
+		return j.secondFactorFailed(user, ErrInvalidCredentials)
+	}
+	if err := j.checkSecondFactor(totp, req.Code, ""); err != nil {
+		return j.secondFactorFailed(user, err)
+	}
+
+	if err := j.totpStore.Delete(uuidUserID); err != nil {
+		return fmt.Errorf("disable totp service: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or recovery
+// code for a token pair. A challenge is retired once it has been used, by
+// revoking it like an access token.
+func (j *JWTService) CompleteTwoFactorLogin(req model.TwoFactorLoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTwoFactorReq, err)
+	}
+
+	claims, err := j.parseToken(req.ChallengeToken, tokenTypeChallenge)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := subjectID(claims)
+	if err != nil {
+		return nil, err
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if jti == "" || err != nil || exp == nil {
+		return nil, ErrInvalidToken
+	}
+	if err := j.checkRevoked(claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := j.userStore.GetByID(userID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	totp, err := j.enrolledTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.claimTwoFactorAttempt(totp); err != nil {
+		return nil, err
+	}
+	if err := j.checkSecondFactor(totp, req.Code, req.RecoveryCode); err != nil {
+		return nil, j.secondFactorFailed(user, err)
+	}
+	if err := j.revoker.RevokeToken(jti, userID, exp.Time); err != nil {
+		return nil, fmt.Errorf("two-factor login service: %w", err)
+	}
+
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	sessionID, err := j.startSession(user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.issueTokens(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if j.limiter != nil {
+		j.limiter.Success(user.Email)
+	}
+
+	return resp, nil
+}
+
+// secondFactorFailed counts a wrong code or password against the user's
+// account in the login limiter as well, so password logins slow down with
+// it, and returns err.
+func (j *JWTService) secondFactorFailed(user *model.User, err error) error {
+	if j.limiter == nil || !(errors.Is(err, ErrInvalidTwoFactorCode) || errors.Is(err, ErrInvalidCredentials)) {
+		return err
+	}
+	if limitErr := j.limiter.SecondFactorFailure(user.Email, &user.ID); limitErr != nil {
+		return fmt.Errorf("second factor check: %w", limitErr)
+	}
+	return err
+}
+
+func (j *JWTService) twoFactorEnabled(userID uuid.UUID) (bool, error) {
+	totp, err := j.totpStore.Get(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return totp.ConfirmedAt != nil, nil
+}
+
+func (j *JWTService) newChallenge(user *model.User) (*model.LoginResponse, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti": uuid.NewString(),
+		"typ": tokenTypeChallenge,
+		"iss": j.issuer,
+		"aud": j.issuer,
+		"sub": user.ID.String(),
+		"exp": now.Add(j.challengeTTL).Unix(),
+		"iat": preciseTime(now),
+	}
+
+	token, err := j.keys.Sign(claims, headerTypeChallenge)
+	if err != nil {
+		return nil, fmt.Errorf("challenge generation err: %w", err)
+	}
+
+	return &model.LoginResponse{TwoFactorRequired: true, ChallengeToken: token}, nil
+}
+
+// enrolledTOTP returns the user's confirmed enrolment.
+func (j *JWTService) enrolledTOTP(userID uuid.UUID) (*model.TOTP, error) {
+	totp, err := j.totpStore.Get(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, fmt.Errorf("second factor check: %w", err)
+	}
+	if totp.ConfirmedAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return totp, nil
+}
+
+// claimTwoFactorAttempt counts an attempt at the second factor before it is
+// checked, so that guesses sent in parallel or to other instances share one
+// budget. A correct code gives the budget back.
+func (j *JWTService) claimTwoFactorAttempt(totp *model.TOTP) error {
+	now := time.Now()
+	claimed, err := j.totpStore.ClaimAttempt(totp.UserID, now, maxTwoFactorAttempts, now.Add(twoFactorLockout))
+	if err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+	if claimed {
+		return nil
+	}
+
+	retryAfter := twoFactorLockout
+	if totp.LockedUntil != nil && totp.LockedUntil.After(now) {
+		retryAfter = totp.LockedUntil.Sub(now)
+	}
+	return &LoginThrottledError{RetryAfter: retryAfter, Locked: true}
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code. Both are
+// single use: a TOTP code must belong to a later time step than the last one
+// accepted. Once a code is accepted, a secret sealed with a retired key is
+// sealed again with the active one.
+func (j *JWTService) checkSecondFactor(totp *model.TOTP, code, recoveryCode string) error {
+	if code == "" {
+		used, err := j.totpStore.UseRecoveryCode(totp.UserID, auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)), time.Now())
+		if err != nil {
+			return fmt.Errorf("second factor check: %w", err)
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+		return j.secondFactorPassed(totp, "")
+	}
+
+	secret, err := j.totpKeys.Open(totp.Secret)
+	if err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok || step <= totp.LastStep {
+		return ErrInvalidTwoFactorCode
+	}
+
+	fresh, err := j.totpStore.UseStep(totp.UserID, step)
+	if err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return j.secondFactorPassed(totp, secret)
+}
+
+func (j *JWTService) secondFactorPassed(totp *model.TOTP, secret string) error {
+	if err := j.totpStore.ResetAttempts(totp.UserID); err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+
+	if secret == "" || j.totpKeys.Current(totp.Secret) {
+		return nil
+	}
+	sealed, err := j.totpKeys.Seal(secret)
+	if err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+	if err := j.totpStore.UpdateSecret(totp.UserID, sealed); err != nil {
+		return fmt.Errorf("second factor check: %w", err)
+	}
+
+	return nil
+}
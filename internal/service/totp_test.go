@@ -0,0 +1,122 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memoryTOTP struct {
+	enrolments    map[uuid.UUID]*model.TOTP
+	recoveryCodes map[uuid.UUID]map[string]bool
+}
+
+func (m *memoryTOTP) Get(userID uuid.UUID) (*model.TOTP, error) {
+	totp, ok := m.enrolments[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *totp
+	return &copied, nil
+}
+
+func (m *memoryTOTP) SavePending(totp model.TOTP) error {
+	if current, ok := m.enrolments[totp.UserID]; ok && current.ConfirmedAt != nil {
+		return nil
+	}
+	m.enrolments[totp.UserID] = &totp
+	return nil
+}
+
+func (m *memoryTOTP) Confirm(userID uuid.UUID, step int64, at time.Time, codeHashes []string) error {
+	totp, ok := m.enrolments[userID]
+	if !ok || totp.ConfirmedAt != nil {
+		return sql.ErrNoRows
+	}
+	totp.ConfirmedAt = &at
+	totp.LastStep = step
+
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	m.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *memoryTOTP) ClaimAttempt(userID uuid.UUID, now time.Time, maxAttempts int, lockUntil time.Time) (bool, error) {
+	totp, ok := m.enrolments[userID]
+	if !ok || (totp.LockedUntil != nil && totp.LockedUntil.After(now)) {
+		return false, nil
+	}
+	totp.LockedUntil = nil
+	totp.FailedAttempts++
+	if totp.FailedAttempts >= maxAttempts {
+		totp.LockedUntil = &lockUntil
+		totp.FailedAttempts = 0
+	}
+	return true, nil
+}
+
+func (m *memoryTOTP) ResetAttempts(userID uuid.UUID) error {
+	if totp, ok := m.enrolments[userID]; ok {
+		totp.FailedAttempts = 0
+		totp.LockedUntil = nil
+	}
+	return nil
+}
+
+func (m *memoryTOTP) UpdateSecret(userID uuid.UUID, secret string) error {
+	if totp, ok := m.enrolments[userID]; ok {
+		totp.Secret = secret
+	}
+	return nil
+}
+
+func (m *memoryTOTP) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	totp, ok := m.enrolments[userID]
+	if !ok || totp.LastStep >= step {
+		return false, nil
+	}
+	totp.LastStep = step
+	return true, nil
+}
+
+func (m *memoryTOTP) UseRecoveryCode(userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	used, ok := m.recoveryCodes[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recoveryCodes[userID][hash] = true
+	return true, nil
+}
+
+func (m *memoryTOTP) Delete(userID uuid.UUID) error {
+	delete(m.enrolments, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func TestEnrollTOTPWithoutSealKey(t *testing.T) {
+	a := newTestAuth(t)
+	a.totpKeys = auth.NewLegacySealKeys(nil)
+	user := a.addUser(t, "alice@example.com", "correct horse battery")
+
+	if _, err := a.EnrollTOTP(user.ID.String()); !errors.Is(err, ErrTwoFactorUnavailable) {
+		t.Fatalf("EnrollTOTP() error = %v, want %v", err, ErrTwoFactorUnavailable)
+	}
+	if len(a.totp.enrolments) != 0 {
+		t.Fatalf("enrolments = %+v, want none", a.totp.enrolments)
+	}
+
+	// Users can still sign in with their password.
+	resp, err := a.Login(user.Email, "correct horse battery", model.ClientInfo{})
+	if err != nil || resp.Token == "" {
+		t.Fatalf("Login() = %+v, %v; want tokens", resp, err)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/devvdark0/todo/internal/auth"
+	"github.com/devvdark0/todo/internal/mail"
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrInvalidVerification      = errors.New("invalid or expired verification link")
+	ErrInvalidVerificationParam = errors.New("invalid verification request")
+)
+
+const verifyEmailPurpose = "verify-email"
+
+type VerificationStorage interface {
+	GetByID(id uuid.UUID) (*model.User, error)
+	GetByEmail(email string) (*model.User, error)
+	MarkEmailVerified(id uuid.UUID, email string) (bool, error)
+	ClaimVerificationSend(id uuid.UUID, at, notBefore time.Time) (bool, error)
+}
+
+type VerificationOptions struct {
+	Secret         []byte
+	LinkTTL        time.Duration
+	ResendInterval time.Duration
+	// VerifyURL is the endpoint the emailed link points to; the token is
+	// appended as the "token" query parameter.
+	VerifyURL string
+}
+
+// EmailVerifier sends signed verification links and confirms addresses when
+// they are followed. Links carry the address they were issued for, so they
+// stop working once the user changes it.
+type EmailVerifier struct {
+	users          VerificationStorage
+	sender         mail.Sender
+	secret         []byte
+	linkTTL        time.Duration
+	resendInterval time.Duration
+	verifyURL      string
+}
+
+func NewEmailVerifier(users VerificationStorage, sender mail.Sender, opts VerificationOptions) *EmailVerifier {
+	return &EmailVerifier{
+		users:          users,
+		sender:         sender,
+		secret:         opts.Secret,
+		linkTTL:        opts.LinkTTL,
+		resendInterval: opts.ResendInterval,
+		verifyURL:      opts.VerifyURL,
+	}
+}
+
+// SendVerification mails a verification link to a newly registered user.
+// The send is only recorded once the mail is out, so after a failure the
+// user can ask for a new link right away.
+func (v *EmailVerifier) SendVerification(user *model.User) error {
+	if err := v.send(user); err != nil {
+		return err
+	}
+
+	if _, err := v.users.ClaimVerificationSend(user.ID, time.Now(), time.Now()); err != nil {
+		return fmt.Errorf("send verification service: %w", err)
+	}
+
+	return nil
+}
+
+// Resend mails a new verification link unless the address is unknown,
+// already verified or was sent one within the resend interval. All of these
+// succeed silently so the endpoint does not reveal who is registered.
+func (v *EmailVerifier) Resend(req model.ResendVerificationRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidVerificationParam, err)
+	}
+
+	user, err := v.users.GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("resend verification service: %w", err)
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	now := time.Now()
+	claimed, err := v.users.ClaimVerificationSend(user.ID, now, now.Add(-v.resendInterval))
+	if err != nil {
+		return fmt.Errorf("resend verification service: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	return v.send(user)
+}
+
+func (v *EmailVerifier) send(user *model.User) error {
+	token := auth.Sign(v.secret, verifyEmailPurpose, []string{user.ID.String(), user.Email}, time.Now().Add(v.linkTTL))
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s?token=%s\n",
+			user.Username, v.linkTTL, v.verifyURL, url.QueryEscape(token),
+		),
+	}
+	if err := v.sender.Send(msg); err != nil {
+		return fmt.Errorf("send verification service: %w", err)
+	}
+
+	return nil
+}
+
+// Verify confirms the address a verification link was issued for.
+func (v *EmailVerifier) Verify(token string) error {
+	values, err := auth.Verify(v.secret, verifyEmailPurpose, token, time.Now())
+	if err != nil || len(values) != 2 {
+		return ErrInvalidVerification
+	}
+
+	userID, err := uuid.Parse(values[0])
+	if err != nil {
+		return ErrInvalidVerification
+	}
+
+	verified, err := v.users.MarkEmailVerified(userID, values[1])
+	if err != nil {
+		return fmt.Errorf("verify email service: %w", err)
+	}
+	if verified {
+		return nil
+	}
+
+	// Following a link twice changes no rows; that is still a success as
+	// long as the address has not changed since.
+	user, err := v.users.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidVerification
+		}
+		return fmt.Errorf("verify email service: %w", err)
+	}
+	if !user.EmailVerified || user.Email != values[1] {
+		return ErrInvalidVerification
+	}
+
+	return nil
+}
+
+// IsVerified reports whether the user's email address has been verified.
+func (v *EmailVerifier) IsVerified(userID string) (bool, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return false, fmt.Errorf("is verified service: %w", err)
+	}
+
+	user, err := v.users.GetByID(uuidUserID)
+	if err != nil {
+		return false, fmt.Errorf("is verified service: %w", err)
+	}
+
+	return user.EmailVerified, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+// memoryVerification adds the verification columns to memoryUsers. Like
+// MariaDB, it only reports rows an update actually changed.
+type memoryVerification struct {
+	*memoryUsers
+}
+
+func (m memoryVerification) MarkEmailVerified(id uuid.UUID, email string) (bool, error) {
+	user, ok := m.users[id]
+	if !ok || user.Email != email || user.EmailVerified {
+		return false, nil
+	}
+	user.EmailVerified = true
+	return true, nil
+}
+
+func (m memoryVerification) ClaimVerificationSend(id uuid.UUID, at, notBefore time.Time) (bool, error) {
+	user, ok := m.users[id]
+	if !ok || (user.VerificationSentAt != nil && user.VerificationSentAt.After(notBefore)) {
+		return false, nil
+	}
+	user.VerificationSentAt = &at
+	return true, nil
+}
+
+func newTestVerifier(t *testing.T) (*EmailVerifier, *testAuth, *memoryMail) {
+	t.Helper()
+
+	a := newTestAuth(t)
+	sender := &memoryMail{}
+	v := NewEmailVerifier(memoryVerification{a.users}, sender, VerificationOptions{
+		Secret:         []byte("link secret"),
+		LinkTTL:        time.Hour,
+		ResendInterval: time.Minute,
+		VerifyURL:      "http://app.test/verify",
+	})
+	return v, a, sender
+}
+
+func addUnverified(t *testing.T, a *testAuth, email string) *model.User {
+	t.Helper()
+
+	user := a.addUser(t, email, "correct horse battery")
+	a.users.users[user.ID].EmailVerified = false
+	user.EmailVerified = false
+	return user
+}
+
+// sentToken pulls the token out of the link in the last mail.
+func sentToken(t *testing.T, sender *memoryMail) string {
+	t.Helper()
+
+	if len(sender.sent) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	body := sender.sent[len(sender.sent)-1].Body
+	_, link, ok := strings.Cut(body, "?token=")
+	if !ok {
+		t.Fatalf("no link in %q", body)
+	}
+	token, err := url.QueryUnescape(strings.TrimSpace(link))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	v, a, sender := newTestVerifier(t)
+	user := addUnverified(t, a, "alice@example.com")
+
+	if err := v.SendVerification(user); err != nil {
+		t.Fatalf("SendVerification() error = %v", err)
+	}
+	token := sentToken(t, sender)
+
+	if err := v.Verify(token + "x"); !errors.Is(err, ErrInvalidVerification) {
+		t.Fatalf("Verify() of a tampered token error = %v, want %v", err, ErrInvalidVerification)
+	}
+	if err := v.Verify(token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if verified, _ := v.IsVerified(user.ID.String()); !verified {
+		t.Fatal("the address is not verified")
+	}
+
+	// Following the link again is harmless.
+	if err := v.Verify(token); err != nil {
+		t.Fatalf("second Verify() error = %v", err)
+	}
+}
+
+func TestVerifyEmailAfterAddressChange(t *testing.T) {
+	v, a, sender := newTestVerifier(t)
+	user := addUnverified(t, a, "alice@example.com")
+	if err := v.SendVerification(user); err != nil {
+		t.Fatal(err)
+	}
+	token := sentToken(t, sender)
+
+	a.users.users[user.ID].Email = "alice@example.org"
+	if err := v.Verify(token); !errors.Is(err, ErrInvalidVerification) {
+		t.Fatalf("Verify() for an old address error = %v, want %v", err, ErrInvalidVerification)
+	}
+	if a.users.users[user.ID].EmailVerified {
+		t.Fatal("the new address was verified by a link for the old one")
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	v, a, sender := newTestVerifier(t)
+	user := addUnverified(t, a, "alice@example.com")
+	a.addUser(t, "bob@example.com", "correct horse battery")
+
+	tests := []struct {
+		name     string
+		email    string
+		wantSent int
+	}{
+		{"unverified", user.Email, 1},
+		{"within the resend interval", user.Email, 1},
+		{"already verified", "bob@example.com", 1},
+		{"unknown address", "carol@example.com", 1},
+	}
+	for _, tt := range tests {
+		if err := v.Resend(model.ResendVerificationRequest{Email: tt.email}); err != nil {
+			t.Fatalf("Resend() for %s error = %v", tt.name, err)
+		}
+		if len(sender.sent) != tt.wantSent {
+			t.Fatalf("%d mails after resend for %s, want %d", len(sender.sent), tt.name, tt.wantSent)
+		}
+	}
+
+	// Once the interval has passed, another link goes out.
+	sentAt := a.users.users[user.ID].VerificationSentAt.Add(-2 * time.Minute)
+	a.users.users[user.ID].VerificationSentAt = &sentAt
+	if err := v.Resend(model.ResendVerificationRequest{Email: user.Email}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 2 {
+		t.Fatalf("%d mails after the interval, want 2", len(sender.sent))
+	}
+
+	if err := v.Resend(model.ResendVerificationRequest{Email: "not an address"}); !errors.Is(err, ErrInvalidVerificationParam) {
+		t.Fatalf("Resend() error = %v, want %v", err, ErrInvalidVerificationParam)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidView  = errors.New("invalid view")
+	ErrViewNotFound = errors.New("view not found")
+)
+
+type ViewStorage interface {
+	Create(view model.SavedView) error
+	GetByID(viewID, userID uuid.UUID) (*model.SavedView, error)
+	List(userID uuid.UUID) ([]model.SavedView, error)
+	Update(viewID, userID uuid.UUID, view model.SavedView) error
+	Delete(viewID, userID uuid.UUID) error
+}
+
+type smartList struct {
+	model.SmartList
+	request func(now time.Time) model.ListTasksRequest
+}
+
+var smartLists = []smartList{
+	{
+		SmartList: model.SmartList{Key: "today", Name: "Today"},
+		request: func(time.Time) model.ListTasksRequest {
+			return model.ListTasksRequest{Query: "is:open due:today", Sort: model.SortPriority}
+		},
+	},
+	{
+		SmartList: model.SmartList{Key: "upcoming", Name: "Upcoming"},
+		request: func(time.Time) model.ListTasksRequest {
+			return model.ListTasksRequest{Query: "is:open due>today", DueWithin: 7, Sort: model.SortPriority}
+		},
+	},
+	{
+		SmartList: model.SmartList{Key: "overdue", Name: "Overdue"},
+		request: func(time.Time) model.ListTasksRequest {
+			return model.ListTasksRequest{Query: "is:overdue", Sort: model.SortPriority}
+		},
+	},
+	{
+		SmartList: model.SmartList{Key: "completed-this-week", Name: "Completed this week"},
+		request: func(now time.Time) model.ListTasksRequest {
+			monday := startOfDay(now).AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+			return model.ListTasksRequest{
+				Query:          "is:done",
+				CompletedAfter: monday.Format(time.RFC3339),
+				Sort:           model.SortUpdatedAt,
+				Order:          model.OrderDesc,
+			}
+		},
+	},
+}
+
+type ViewService struct {
+	storage ViewStorage
+	tasks   *TodoService
+}
+
+func NewViewService(store ViewStorage, tasks *TodoService) *ViewService {
+	return &ViewService{storage: store, tasks: tasks}
+}
+
+func (s *ViewService) ListViews(userID string) (*model.ViewsResponse, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("list views service: %w", err)
+	}
+
+	views, err := s.storage.List(uuidUserID)
+	if err != nil {
+		return nil, fmt.Errorf("list views service: %w", err)
+	}
+
+	resp := &model.ViewsResponse{Views: views}
+	for _, list := range smartLists {
+		resp.SmartLists = append(resp.SmartLists, list.SmartList)
+	}
+
+	return resp, nil
+}
+
+func (s *ViewService) GetView(viewID, userID string) (*model.SavedView, error) {
+	uuidViewID, uuidUserID, err := parseIDPair(viewID, userID)
+	if err != nil {
+		return nil, ErrViewNotFound
+	}
+
+	view, err := s.storage.GetByID(uuidViewID, uuidUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrViewNotFound
+		}
+		return nil, fmt.Errorf("get view service: %w", err)
+	}
+
+	return view, nil
+}
+
+func (s *ViewService) CreateView(userID string, req model.CreateViewRequest) (*model.SavedView, error) {
+	uuidUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("create view service: %w", err)
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidView, err)
+	}
+	if _, _, err := compileQuery(req.Query, time.Now(), time.UTC); err != nil {
+		return nil, err
+	}
+
+	view := model.SavedView{
+		ID:        uuid.New(),
+		UserID:    uuidUserID,
+		Name:      req.Name,
+		Query:     req.Query,
+		Sort:      req.Sort,
+		Order:     req.Order,
+		Pinned:    req.Pinned,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.storage.Create(view); err != nil {
+		return nil, fmt.Errorf("create view service: %w", err)
+	}
+
+	return &view, nil
+}
+
+func (s *ViewService) UpdateView(viewID, userID string, req model.UpdateViewRequest) error {
+	validator := validator.New()
+	if err := validator.Struct(req); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidView, err)
+	}
+
+	view, err := s.GetView(viewID, userID)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			return fmt.Errorf("%w: name must not be empty", ErrInvalidView)
+		}
+		view.Name = *req.Name
+	}
+	if req.Query != nil {
+		if _, _, err := compileQuery(*req.Query, time.Now(), time.UTC); err != nil {
+			return err
+		}
+		view.Query = *req.Query
+	}
+	if req.Sort != nil {
+		view.Sort = *req.Sort
+	}
+	if req.Order != nil {
+		view.Order = *req.Order
+	}
+	if req.Pinned != nil {
+		view.Pinned = *req.Pinned
+	}
+	view.UpdatedAt = time.Now()
+
+	if err := s.storage.Update(view.ID, view.UserID, *view); err != nil {
+		return fmt.Errorf("update view service: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ViewService) DeleteView(viewID, userID string) error {
+	view, err := s.GetView(viewID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.Delete(view.ID, view.UserID); err != nil {
+		return fmt.Errorf("delete view service: %w", err)
+	}
+
+	return nil
+}
+
+// ViewTasks lists the tasks matched by a saved view or a smart list. Paging
+// and timezone come from page; an extra page.Query is ANDed with the view's.
+func (s *ViewService) ViewTasks(viewID, userID string, page model.ListTasksRequest) (*model.TaskPage, error) {
+	req, err := s.viewRequest(viewID, userID, page.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Timezone = page.Timezone
+	req.Limit = page.Limit
+	req.Cursor = page.Cursor
+	if page.Query != "" {
+		if req.Query != "" {
+			req.Query = "(" + req.Query + ") (" + page.Query + ")"
+		} else {
+			req.Query = page.Query
+		}
+	}
+
+	return s.tasks.ListTasks(userID, req)
+}
+
+func (s *ViewService) viewRequest(viewID, userID, timezone string) (model.ListTasksRequest, error) {
+	for _, list := range smartLists {
+		if list.Key == viewID {
+			loc, err := loadLocation(timezone)
+			if err != nil {
+				return model.ListTasksRequest{}, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+			}
+			return list.request(time.Now().In(loc)), nil
+		}
+	}
+
+	view, err := s.GetView(viewID, userID)
+	if err != nil {
+		return model.ListTasksRequest{}, err
+	}
+
+	return model.ListTasksRequest{Query: view.Query, Sort: view.Sort, Order: view.Order}, nil
+}
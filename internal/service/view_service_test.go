@@ -0,0 +1,171 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+type memoryViews struct {
+	views map[uuid.UUID]model.SavedView
+}
+
+func (m *memoryViews) Create(view model.SavedView) error {
+	m.views[view.ID] = view
+	return nil
+}
+
+func (m *memoryViews) GetByID(viewID, userID uuid.UUID) (*model.SavedView, error) {
+	view, ok := m.views[viewID]
+	if !ok || view.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	return &view, nil
+}
+
+func (m *memoryViews) List(userID uuid.UUID) ([]model.SavedView, error) {
+	var views []model.SavedView
+	for _, view := range m.views {
+		if view.UserID == userID {
+			views = append(views, view)
+		}
+	}
+	return views, nil
+}
+
+func (m *memoryViews) Update(viewID, userID uuid.UUID, view model.SavedView) error {
+	m.views[viewID] = view
+	return nil
+}
+
+func (m *memoryViews) Delete(viewID, userID uuid.UUID) error {
+	delete(m.views, viewID)
+	return nil
+}
+
+// recordingTasks keeps the filter of the last listing.
+type recordingTasks struct {
+	*memoryTaskStore
+	filter model.TaskFilter
+}
+
+func (r *recordingTasks) List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error) {
+	r.filter = filter
+	return r.memoryTaskStore.List(userID, filter)
+}
+
+func newTestViews() (*ViewService, *recordingTasks) {
+	tasks, store := newTestTasks(SubtaskOrphan)
+	recording := &recordingTasks{memoryTaskStore: store}
+	tasks.storage = recording
+	return NewViewService(&memoryViews{views: make(map[uuid.UUID]model.SavedView)}, tasks), recording
+}
+
+func TestCreateAndUpdateView(t *testing.T) {
+	s, _ := newTestViews()
+	alice := uuid.NewString()
+
+	tests := []struct {
+		name    string
+		req     model.CreateViewRequest
+		wantErr error
+	}{
+		{"valid", model.CreateViewRequest{Name: "work", Query: "tag:work is:open", Sort: model.SortPriority}, nil},
+		{"empty query", model.CreateViewRequest{Name: "everything"}, nil},
+		{"no name", model.CreateViewRequest{Query: "is:open"}, ErrInvalidView},
+		{"unknown sort", model.CreateViewRequest{Name: "work", Sort: "colour"}, ErrInvalidView},
+		{"query does not compile", model.CreateViewRequest{Name: "work", Query: "due:someday"}, ErrInvalidQuery},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CreateView(alice, tt.req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateView() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	views, err := s.ListViews(alice)
+	if err != nil {
+		t.Fatalf("ListViews() error = %v", err)
+	}
+	if len(views.Views) != 2 || len(views.SmartLists) != len(smartLists) {
+		t.Fatalf("ListViews() = %+v, want 2 views and every smart list", views)
+	}
+
+	view, _ := s.CreateView(alice, model.CreateViewRequest{Name: "home"})
+	broken, empty := "(is:open", ""
+	if err := s.UpdateView(view.ID.String(), alice, model.UpdateViewRequest{Query: &broken}); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("UpdateView() with a broken query error = %v, want %v", err, ErrInvalidQuery)
+	}
+	if err := s.UpdateView(view.ID.String(), alice, model.UpdateViewRequest{Name: &empty}); !errors.Is(err, ErrInvalidView) {
+		t.Fatalf("UpdateView() with an empty name error = %v, want %v", err, ErrInvalidView)
+	}
+	if err := s.DeleteView(view.ID.String(), uuid.NewString()); !errors.Is(err, ErrViewNotFound) {
+		t.Fatalf("DeleteView() by another user error = %v, want %v", err, ErrViewNotFound)
+	}
+}
+
+func TestViewTasks(t *testing.T) {
+	s, tasks := newTestViews()
+	alice := uuid.NewString()
+	view, err := s.CreateView(alice, model.CreateViewRequest{Name: "urgent", Query: "priority:urgent", Sort: model.SortTitle, Order: model.OrderDesc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ViewTasks(view.ID.String(), alice, model.ListTasksRequest{Limit: 10}); err != nil {
+		t.Fatalf("ViewTasks() error = %v", err)
+	}
+	if tasks.filter.Sort != model.SortTitle || tasks.filter.Order != model.OrderDesc || tasks.filter.Condition == "" {
+		t.Fatalf("filter = %+v, want the view's query and sort", tasks.filter)
+	}
+	viewOnly := tasks.filter.Condition
+
+	// A query of the page narrows the view's.
+	if _, err := s.ViewTasks(view.ID.String(), alice, model.ListTasksRequest{Query: "is:open"}); err != nil {
+		t.Fatalf("ViewTasks() with a query error = %v", err)
+	}
+	if tasks.filter.Condition == viewOnly {
+		t.Fatal("the page's query was not added to the view's")
+	}
+
+	if _, err := s.ViewTasks("overdue", alice, model.ListTasksRequest{Timezone: "Mars/Olympus"}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("ViewTasks() with an unknown timezone error = %v, want %v", err, ErrInvalidFilter)
+	}
+	if _, err := s.ViewTasks("someday", alice, model.ListTasksRequest{}); !errors.Is(err, ErrViewNotFound) {
+		t.Fatalf("ViewTasks() of an unknown view error = %v, want %v", err, ErrViewNotFound)
+	}
+}
+
+func TestCompletedThisWeekStartsOnMonday(t *testing.T) {
+	var list smartList
+	for _, l := range smartLists {
+		if l.Key == "completed-this-week" {
+			list = l
+		}
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, 10, 12, 9, 0, 0, 0, berlin), "2026-10-12T00:00:00+02:00"},
+		{time.Date(2026, 10, 14, 18, 0, 0, 0, berlin), "2026-10-12T00:00:00+02:00"},
+		{time.Date(2026, 10, 18, 23, 0, 0, 0, berlin), "2026-10-12T00:00:00+02:00"},
+		{time.Date(2026, 10, 19, 0, 30, 0, 0, berlin), "2026-10-19T00:00:00+02:00"},
+	}
+
+	for _, tt := range tests {
+		if got := list.request(tt.now).CompletedAfter; got != tt.want {
+			t.Errorf("completed after on %s = %s, want %s", tt.now.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type AuditStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewAuditStore(db *sql.DB, log *zap.Logger) *AuditStore {
+	return &AuditStore{db: db, log: log}
+}
+
+func (s *AuditStore) Create(entry model.AuditEntry) error {
+	query := `INSERT INTO audit_log (id, user_id, event, subject, ip, detail, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	var userID uuid.NullUUID
+	if entry.UserID != nil {
+		userID = uuid.NullUUID{UUID: *entry.UserID, Valid: true}
+	}
+
+	_, err := s.db.Exec(query, entry.ID, userID, entry.Event, entry.Subject, entry.IP, entry.Detail, entry.CreatedAt)
+	if err != nil {
+		s.log.Error("db insert audit entry err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
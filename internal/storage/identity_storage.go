@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"go.uber.org/zap"
+)
+
+type IdentityStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewIdentityStore(db *sql.DB, log *zap.Logger) *IdentityStore {
+	return &IdentityStore{db: db, log: log}
+}
+
+func (s *IdentityStore) Create(identity model.ExternalIdentity) error {
+	query := `INSERT INTO external_identities (id, user_id, provider, subject, email, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(
+		query,
+		identity.ID,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	)
+	if err != nil {
+		s.log.Error("db insert external identity err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *IdentityStore) GetBySubject(provider, subject string) (*model.ExternalIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at FROM external_identities WHERE provider=? AND subject=?`
+	var identity model.ExternalIdentity
+	err := s.db.QueryRow(query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		s.log.Error("db select external identity err", zap.Error(err))
+		return nil, err
+	}
+
+	return &identity, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type PasswordResetStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewPasswordResetStore(db *sql.DB, log *zap.Logger) *PasswordResetStore {
+	return &PasswordResetStore{db: db, log: log}
+}
+
+func (s *PasswordResetStore) Create(token model.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		s.log.Error("db insert password reset token err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *PasswordResetStore) GetByHash(hash string) (*model.PasswordResetToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, created_at, used_at FROM password_reset_tokens WHERE token_hash=?`
+	var (
+		token  model.PasswordResetToken
+		usedAt sql.NullTime
+	)
+	err := s.db.QueryRow(query, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&usedAt,
+	)
+	if err != nil {
+		s.log.Error("db select password reset token err", zap.Error(err))
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return &token, nil
+}
+
+// Redeem consumes a token and sets its user's password in one transaction.
+// It reports whether this call was the one that consumed the token, so two
+// concurrent resets cannot both succeed and a failed password update leaves
+// the token usable.
+func (s *PasswordResetStore) Redeem(token model.PasswordResetToken, password string, at time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE password_reset_tokens SET used_at=? WHERE id=? AND used_at IS NULL`, at, token.ID)
+	if err != nil {
+		s.log.Error("db mark password reset token used err", zap.Error(err), zap.String("id", token.ID.String()))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password=? WHERE id=?`, password, token.UserID); err != nil {
+		s.log.Error("db update user password error", zap.Error(err), zap.String("user_id", token.UserID.String()))
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// InvalidateUser consumes every outstanding token of the user.
+func (s *PasswordResetStore) InvalidateUser(userID uuid.UUID, at time.Time) error {
+	query := `UPDATE password_reset_tokens SET used_at=? WHERE user_id=? AND used_at IS NULL`
+	if _, err := s.db.Exec(query, at, userID); err != nil {
+		s.log.Error("db invalidate password reset tokens err", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+
+	return nil
+}
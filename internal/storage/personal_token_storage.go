@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type PersonalTokenStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewPersonalTokenStore(db *sql.DB, log *zap.Logger) *PersonalTokenStore {
+	return &PersonalTokenStore{db: db, log: log}
+}
+
+const personalTokenColumns = `id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at`
+
+func scanPersonalToken(row rowScanner) (*model.PersonalAccessToken, error) {
+	var (
+		token      model.PersonalAccessToken
+		scopes     string
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &token, nil
+}
+
+func (s *PersonalTokenStore) Create(token model.PersonalAccessToken) error {
+	query := `INSERT INTO personal_access_tokens (` + personalTokenColumns + `) VALUES (?, ?, ?, ?, ?, ?, NULL, ?)`
+	_, err := s.db.Exec(
+		query,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		s.log.Error("db insert personal access token err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *PersonalTokenStore) GetByHash(hash string) (*model.PersonalAccessToken, error) {
+	query := `SELECT ` + personalTokenColumns + ` FROM personal_access_tokens WHERE token_hash=?`
+	token, err := scanPersonalToken(s.db.QueryRow(query, hash))
+	if err != nil {
+		s.log.Error("db select personal access token err", zap.Error(err))
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (s *PersonalTokenStore) List(userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	query := `SELECT ` + personalTokenColumns + ` FROM personal_access_tokens WHERE user_id=? ORDER BY created_at DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		s.log.Error("db select personal access tokens err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]model.PersonalAccessToken, 0)
+	for rows.Next() {
+		token, err := scanPersonalToken(rows)
+		if err != nil {
+			s.log.Error("db scan personal access token err", zap.Error(err))
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *PersonalTokenStore) Touch(id uuid.UUID, at time.Time) error {
+	if _, err := s.db.Exec(`UPDATE personal_access_tokens SET last_used_at=? WHERE id=?`, at, id); err != nil {
+		s.log.Error("db touch personal access token err", zap.Error(err), zap.String("id", id.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *PersonalTokenStore) Delete(id, userID uuid.UUID) error {
+	res, err := s.db.Exec(`DELETE FROM personal_access_tokens WHERE id=? AND user_id=?`, id, userID)
+	if err != nil {
+		s.log.Error("db delete personal access token err", zap.Error(err), zap.String("id", id.String()))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *PersonalTokenStore) DeleteUser(userID uuid.UUID) error {
+	_, err := s.db.Exec(`DELETE FROM personal_access_tokens WHERE user_id=?`, userID)
+	if err != nil {
+		s.log.Error("db delete user personal access tokens err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
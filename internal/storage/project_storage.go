@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const projectColumns = `id, user_id, name, description, color, archived, created_at, updated_at`
+
+type ProjectStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewProjectStore(db *sql.DB, log *zap.Logger) *ProjectStore {
+	return &ProjectStore{db: db, log: log}
+}
+
+func scanProject(row rowScanner) (*model.Project, error) {
+	var (
+		project     model.Project
+		description sql.NullString
+		color       sql.NullString
+	)
+	err := row.Scan(
+		&project.ID,
+		&project.UserID,
+		&project.Name,
+		&description,
+		&color,
+		&project.Archived,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Description = description.String
+	project.Color = color.String
+
+	return &project, nil
+}
+
+func (s *ProjectStore) Create(project model.Project) error {
+	query := `INSERT INTO projects (` + projectColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(
+		query,
+		project.ID,
+		project.UserID,
+		project.Name,
+		project.Description,
+		project.Color,
+		project.Archived,
+		project.CreatedAt,
+		project.UpdatedAt,
+	)
+	if err != nil {
+		s.log.Error("db insert project err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *ProjectStore) GetByID(projectID, userID uuid.UUID) (*model.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id=? AND user_id=?`
+	project, err := scanProject(s.db.QueryRow(query, projectID, userID))
+	if err != nil {
+		s.log.Error("db select project err", zap.Error(err))
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *ProjectStore) List(userID uuid.UUID, includeArchived bool) ([]model.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE user_id=?`
+	if !includeArchived {
+		query += ` AND archived = FALSE`
+	}
+	query += ` ORDER BY name`
+
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		s.log.Error("db select projects err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]model.Project, 0)
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			s.log.Error("db scan project err", zap.Error(err))
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (s *ProjectStore) Update(projectID, userID uuid.UUID, project model.Project) error {
+	query := `UPDATE projects SET name=?, description=?, color=?, archived=?, updated_at=? WHERE id=? AND user_id=?`
+	_, err := s.db.Exec(
+		query,
+		project.Name,
+		project.Description,
+		project.Color,
+		project.Archived,
+		project.UpdatedAt,
+		projectID,
+		userID,
+	)
+	if err != nil {
+		s.log.Error("db update project err", zap.Error(err), zap.String("project_id", projectID.String()))
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a project. Its tasks are either deleted with it or, through
+// the ON DELETE SET NULL foreign key, left in the inbox.
+func (s *ProjectStore) Delete(projectID, userID uuid.UUID, deleteTasks bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if deleteTasks {
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE project_id=? AND user_id=?`, projectID, userID); err != nil {
+			s.log.Error("db delete project tasks err", zap.Error(err), zap.String("project_id", projectID.String()))
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM projects WHERE id=? AND user_id=?`, projectID, userID); err != nil {
+		s.log.Error("db delete project err", zap.Error(err), zap.String("project_id", projectID.String()))
+		return err
+	}
+
+	return tx.Commit()
+}
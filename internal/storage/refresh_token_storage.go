@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RefreshTokenStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewRefreshTokenStore(db *sql.DB, log *zap.Logger) *RefreshTokenStore {
+	return &RefreshTokenStore{db: db, log: log}
+}
+
+func (s *RefreshTokenStore) Create(token model.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		s.log.Error("db insert refresh token err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *RefreshTokenStore) GetByHash(hash string) (*model.RefreshToken, error) {
+	query := `SELECT id, user_id, family_id, token_hash, expires_at, created_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash=?`
+	var (
+		token     model.RefreshToken
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+	err := s.db.QueryRow(query, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&usedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		s.log.Error("db select refresh token err", zap.Error(err))
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return &token, nil
+}
+
+// MarkUsed consumes a refresh token. It reports false when the token had
+// already been used or revoked, which happens when two requests race to
+// rotate the same token.
+func (s *RefreshTokenStore) MarkUsed(id uuid.UUID, at time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at=? WHERE id=? AND used_at IS NULL AND revoked_at IS NULL`
+	res, err := s.db.Exec(query, at, id)
+	if err != nil {
+		s.log.Error("db mark refresh token used err", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *RefreshTokenStore) RevokeFamily(familyID uuid.UUID, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at=? WHERE family_id=? AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, at, familyID); err != nil {
+		s.log.Error("db revoke refresh token family err", zap.Error(err), zap.String("family_id", familyID.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *RefreshTokenStore) RevokeUser(userID uuid.UUID, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at=? WHERE user_id=? AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, at, userID); err != nil {
+		s.log.Error("db revoke user refresh tokens err", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RevocationStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewRevocationStore(db *sql.DB, log *zap.Logger) *RevocationStore {
+	return &RevocationStore{db: db, log: log}
+}
+
+func (s *RevocationStore) Create(rev model.TokenRevocation) error {
+	query := `INSERT INTO token_revocations (id, user_id, jti, session_id, revoked_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	var (
+		jti       sql.NullString
+		sessionID uuid.NullUUID
+	)
+	if rev.JTI != "" {
+		jti = sql.NullString{String: rev.JTI, Valid: true}
+	}
+	if rev.SessionID != nil {
+		sessionID = uuid.NullUUID{UUID: *rev.SessionID, Valid: true}
+	}
+
+	_, err := s.db.Exec(query, rev.ID, rev.UserID, jti, sessionID, rev.RevokedAt, rev.ExpiresAt)
+	if err != nil {
+		s.log.Error("db insert token revocation err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *RevocationStore) ListActive(now time.Time) ([]model.TokenRevocation, error) {
+	query := `SELECT id, user_id, jti, session_id, revoked_at, expires_at FROM token_revocations WHERE expires_at > ?`
+	rows, err := s.db.Query(query, now)
+	if err != nil {
+		s.log.Error("db select token revocations err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revocations := make([]model.TokenRevocation, 0)
+	for rows.Next() {
+		var (
+			rev       model.TokenRevocation
+			jti       sql.NullString
+			sessionID uuid.NullUUID
+		)
+		if err := rows.Scan(&rev.ID, &rev.UserID, &jti, &sessionID, &rev.RevokedAt, &rev.ExpiresAt); err != nil {
+			s.log.Error("db scan token revocation err", zap.Error(err))
+			return nil, err
+		}
+		rev.JTI = jti.String
+		if sessionID.Valid {
+			rev.SessionID = &sessionID.UUID
+		}
+		revocations = append(revocations, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return revocations, nil
+}
+
+func (s *RevocationStore) DeleteExpired(now time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM token_revocations WHERE expires_at <= ?`, now); err != nil {
+		s.log.Error("db delete expired token revocations err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/devvdark0/todo/internal/model"
+	"go.uber.org/zap"
+)
+
+type RoleStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewRoleStore(db *sql.DB, log *zap.Logger) *RoleStore {
+	return &RoleStore{db: db, log: log}
+}
+
+func (s *RoleStore) List() ([]model.Role, error) {
+	rows, err := s.db.Query(`SELECT name, permissions, builtin, created_at FROM roles ORDER BY name`)
+	if err != nil {
+		s.log.Error("db select roles err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]model.Role, 0)
+	for rows.Next() {
+		var (
+			role        model.Role
+			permissions string
+		)
+		if err := rows.Scan(&role.Name, &permissions, &role.Builtin, &role.CreatedAt); err != nil {
+			s.log.Error("db scan role err", zap.Error(err))
+			return nil, err
+		}
+		role.Permissions = splitPermissions(permissions)
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (s *RoleStore) Create(role model.Role) error {
+	query := `INSERT INTO roles (name, permissions, builtin, created_at) VALUES (?, ?, ?, ?)`
+	_, err := s.db.Exec(query, role.Name, strings.Join(role.Permissions, ","), role.Builtin, role.CreatedAt)
+	if err != nil {
+		s.log.Error("db insert role err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *RoleStore) Update(name string, permissions []string) error {
+	res, err := s.db.Exec(`UPDATE roles SET permissions=? WHERE name=? AND builtin=FALSE`, strings.Join(permissions, ","), name)
+	if err != nil {
+		s.log.Error("db update role err", zap.Error(err))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Delete removes a custom role. It fails with sql.ErrNoRows for unknown and
+// built-in roles, and the foreign key keeps roles that are still assigned.
+func (s *RoleStore) Delete(name string) error {
+	res, err := s.db.Exec(`DELETE FROM roles WHERE name=? AND builtin=FALSE`, name)
+	if err != nil {
+		s.log.Error("db delete role err", zap.Error(err))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *RoleStore) CountUsers(name string) (int, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role=?`, name).Scan(&count); err != nil {
+		s.log.Error("db count role users err", zap.Error(err))
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func splitPermissions(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
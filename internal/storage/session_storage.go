@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type SessionStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewSessionStore(db *sql.DB, log *zap.Logger) *SessionStore {
+	return &SessionStore{db: db, log: log}
+}
+
+const sessionColumns = `id, user_id, ip, user_agent, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner) (*model.Session, error) {
+	var (
+		session   model.Session
+		revokedAt sql.NullTime
+	)
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.IP,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return &session, nil
+}
+
+func (s *SessionStore) Create(session model.Session) error {
+	query := `INSERT INTO sessions (` + sessionColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, NULL)`
+	_, err := s.db.Exec(
+		query,
+		session.ID,
+		session.UserID,
+		session.IP,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		s.log.Error("db insert session err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *SessionStore) GetByID(sessionID, userID uuid.UUID) (*model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id=? AND user_id=?`
+	session, err := scanSession(s.db.QueryRow(query, sessionID, userID))
+	if err != nil {
+		s.log.Error("db select session err", zap.Error(err), zap.String("id", sessionID.String()))
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SessionStore) ListActive(userID uuid.UUID, now time.Time) ([]model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id=? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC`
+	rows, err := s.db.Query(query, userID, now)
+	if err != nil {
+		s.log.Error("db select sessions err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			s.log.Error("db scan session err", zap.Error(err))
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionStore) Touch(sessionID uuid.UUID, client model.ClientInfo, at time.Time) error {
+	query := `UPDATE sessions SET last_seen_at=?, ip=? WHERE id=? AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, at, client.IP, sessionID); err != nil {
+		s.log.Error("db touch session err", zap.Error(err), zap.String("id", sessionID.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *SessionStore) Extend(sessionID uuid.UUID, client model.ClientInfo, at, expiresAt time.Time) error {
+	query := `UPDATE sessions SET last_seen_at=?, ip=?, user_agent=?, expires_at=? WHERE id=? AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, at, client.IP, client.UserAgent, expiresAt, sessionID); err != nil {
+		s.log.Error("db extend session err", zap.Error(err), zap.String("id", sessionID.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *SessionStore) Revoke(sessionID, userID uuid.UUID, at time.Time) error {
+	query := `UPDATE sessions SET revoked_at=? WHERE id=? AND user_id=? AND revoked_at IS NULL`
+	res, err := s.db.Exec(query, at, sessionID, userID)
+	if err != nil {
+		s.log.Error("db revoke session err", zap.Error(err), zap.String("id", sessionID.String()))
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *SessionStore) RevokeUser(userID uuid.UUID, at time.Time) error {
+	query := `UPDATE sessions SET revoked_at=? WHERE user_id=? AND revoked_at IS NULL`
+	if _, err := s.db.Exec(query, at, userID); err != nil {
+		s.log.Error("db revoke user sessions err", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type TagStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewTagStore(db *sql.DB, log *zap.Logger) *TagStore {
+	return &TagStore{db: db, log: log}
+}
+
+func (s *TagStore) Create(tag model.Tag) error {
+	query := `INSERT INTO tags (id, user_id, name, created_at) VALUES (?, ?, ?, ?)`
+	_, err := s.db.Exec(query, tag.ID, tag.UserID, tag.Name, tag.CreatedAt)
+	if err != nil {
+		s.log.Error("db insert tag err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *TagStore) GetByID(tagID, userID uuid.UUID) (*model.Tag, error) {
+	query := `SELECT id, user_id, name, created_at FROM tags WHERE id=? AND user_id=?`
+	var tag model.Tag
+	err := s.db.QueryRow(query, tagID, userID).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt)
+	if err != nil {
+		s.log.Error("db select tag err", zap.Error(err))
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (s *TagStore) GetByName(name string, userID uuid.UUID) (*model.Tag, error) {
+	query := `SELECT id, user_id, name, created_at FROM tags WHERE name=? AND user_id=?`
+	var tag model.Tag
+	err := s.db.QueryRow(query, name, userID).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt)
+	if err != nil {
+		s.log.Error("db select tag by name err", zap.Error(err))
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (s *TagStore) List(userID uuid.UUID) ([]model.Tag, error) {
+	query := `SELECT id, user_id, name, created_at FROM tags WHERE user_id=? ORDER BY name`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		s.log.Error("db select tags err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]model.Tag, 0)
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt); err != nil {
+			s.log.Error("db scan tag err", zap.Error(err))
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *TagStore) Update(tagID, userID uuid.UUID, tag model.Tag) error {
+	query := `UPDATE tags SET name=? WHERE id=? AND user_id=?`
+	_, err := s.db.Exec(query, tag.Name, tagID, userID)
+	if err != nil {
+		s.log.Error("db update tag err", zap.Error(err), zap.String("tag_id", tagID.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *TagStore) Delete(tagID, userID uuid.UUID) error {
+	query := `DELETE FROM tags WHERE id=? AND user_id=?`
+	_, err := s.db.Exec(query, tagID, userID)
+	if err != nil {
+		s.log.Error("db delete tag err", zap.Error(err), zap.String("tag_id", tagID.String()))
+		return err
+	}
+
+	return nil
+}
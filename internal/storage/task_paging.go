@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+)
+
+var ErrInvalidCursor = errors.New("cursor does not match the requested sort")
+
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// farFuture stands in for a missing due date so that tasks without one sort
+// after every dated task and still have a comparable keyset value. It must
+// match the due_sort generated column, which lets the priority sort use
+// idx_tasks_user_priority.
+const farFuture = "9999-12-31 23:59:59"
+
+type sortColumn struct {
+	expr  string
+	desc  bool
+	value func(task model.Task) any
+}
+
+var taskSorts = map[string][]sortColumn{
+	model.SortCreatedAt: {
+		{expr: "created_at", value: func(t model.Task) any { return t.CreatedAt.UTC().Format(cursorTimeLayout) }},
+	},
+	model.SortUpdatedAt: {
+		{expr: "updated_at", value: func(t model.Task) any { return t.UpdatedAt.UTC().Format(cursorTimeLayout) }},
+	},
+	model.SortTitle: {
+		{expr: "title", value: func(t model.Task) any { return t.Title }},
+	},
+	model.SortIsDone: {
+		{expr: "is_done", value: func(t model.Task) any { return t.IsDone }},
+	},
+	model.SortPriority: {
+		{expr: "priority", desc: true, value: func(t model.Task) any { return t.Priority.Rank() }},
+		{expr: "due_sort", value: func(t model.Task) any {
+			if t.DueAt == nil {
+				return farFuture
+			}
+			return t.DueAt.UTC().Format(cursorTimeLayout)
+		}},
+	},
+}
+
+// sortKey resolves the sort a listing actually uses.
+func sortKey(sort string) string {
+	if _, ok := taskSorts[sort]; ok {
+		return sort
+	}
+	return model.SortCreatedAt
+}
+
+func sortColumns(sort string) []sortColumn {
+	return taskSorts[sortKey(sort)]
+}
+
+// keyColumns is sortColumns with the primary key appended as a tie-breaker,
+// which makes every position in the listing unique.
+func keyColumns(sort string) []sortColumn {
+	columns := sortColumns(sort)
+	keys := make([]sortColumn, 0, len(columns)+1)
+	keys = append(keys, columns...)
+	return append(keys, sortColumn{expr: "id"})
+}
+
+// effectiveDesc reports whether a column is walked in descending order once
+// the requested order and the paging direction are applied.
+func effectiveDesc(column sortColumn, order string, backward bool) bool {
+	desc := column.desc
+	if order == model.OrderDesc {
+		desc = !desc
+	}
+	if backward {
+		desc = !desc
+	}
+	return desc
+}
+
+func taskOrder(filter model.TaskFilter) string {
+	backward := filter.After != nil && filter.After.Backward
+	columns := keyColumns(filter.Sort)
+
+	parts := make([]string, 0, len(columns))
+	for _, column := range columns {
+		dir := " ASC"
+		if effectiveDesc(column, filter.Order, backward) {
+			dir = " DESC"
+		}
+		parts = append(parts, column.expr+dir)
+	}
+
+	return ` ORDER BY ` + strings.Join(parts, ", ")
+}
+
+// keysetClause builds the WHERE condition selecting rows strictly past the
+// cursor, e.g. for (a DESC, id ASC): a < ? OR (a = ? AND id > ?).
+func keysetClause(filter model.TaskFilter) (string, []any, error) {
+	cursor := filter.After
+	if cursor.Sort != sortKey(filter.Sort) || cursor.Desc != (filter.Order == model.OrderDesc) {
+		return "", nil, ErrInvalidCursor
+	}
+	columns := keyColumns(filter.Sort)
+	if len(cursor.Values)+1 != len(columns) {
+		return "", nil, ErrInvalidCursor
+	}
+	values := append(append([]any{}, cursor.Values...), cursor.ID)
+
+	var (
+		ors  []string
+		args []any
+	)
+	for i, column := range columns {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := " > ?"
+		if effectiveDesc(column, filter.Order, cursor.Backward) {
+			op = " < ?"
+		}
+		ands = append(ands, column.expr+op)
+		args = append(args, values[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
+
+// NewTaskCursor captures the position of task in a listing sorted by sort and
+// order so that the next page can resume right after (or before) it. The
+// sort and order are recorded so the cursor cannot be replayed against a
+// listing sorted differently.
+func NewTaskCursor(task model.Task, sort, order string, backward bool) model.TaskCursor {
+	columns := sortColumns(sort)
+	values := make([]any, 0, len(columns))
+	for _, column := range columns {
+		values = append(values, column.value(task))
+	}
+
+	return model.TaskCursor{
+		Sort:     sortKey(sort),
+		Desc:     order == model.OrderDesc,
+		Values:   values,
+		ID:       task.ID,
+		Backward: backward,
+	}
+}
+
+func timeRange(where []string, args []any, column string, from, to *time.Time) ([]string, []any) {
+	if from != nil {
+		where = append(where, column+" >= ?")
+		args = append(args, *from)
+	}
+	if to != nil {
+		where = append(where, column+" < ?")
+		args = append(args, *to)
+	}
+	return where, args
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+)
+
+func TestKeysetClauseRejectsCursorFromOtherSort(t *testing.T) {
+	task := model.Task{ID: uuid.New(), Title: "a", CreatedAt: time.Now(), Priority: model.PriorityHigh}
+
+	tests := []struct {
+		name        string
+		cursorSort  string
+		cursorOrder string
+		sort        string
+		order       string
+		wantErr     bool
+	}{
+		{"same sort", model.SortPriority, "", model.SortPriority, "", false},
+		{"default sort matches created_at", "", "", model.SortCreatedAt, model.OrderAsc, false},
+		{"other sort", model.SortTitle, "", model.SortPriority, "", true},
+		{"other sort with same column count", model.SortTitle, "", model.SortCreatedAt, "", true},
+		{"other order", model.SortTitle, model.OrderDesc, model.SortTitle, model.OrderAsc, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := NewTaskCursor(task, tt.cursorSort, tt.cursorOrder, false)
+			_, _, err := keysetClause(model.TaskFilter{Sort: tt.sort, Order: tt.order, After: &cursor})
+			if gotErr := errors.Is(err, ErrInvalidCursor); gotErr != tt.wantErr {
+				t.Fatalf("keysetClause() error = %v, want invalid cursor: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrioritySortUsesIndexedColumn(t *testing.T) {
+	order := taskOrder(model.TaskFilter{Sort: model.SortPriority})
+	if want := " ORDER BY priority DESC, due_sort ASC, id ASC"; order != want {
+		t.Fatalf("taskOrder() = %q, want %q", order, want)
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// FullTextSearch searches tasks through the MySQL/MariaDB FULLTEXT index on
+// (title, description).
+type FullTextSearch struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewFullTextSearch(db *sql.DB, log *zap.Logger) *FullTextSearch {
+	return &FullTextSearch{db: db, log: log}
+}
+
+func (s *FullTextSearch) Search(userID uuid.UUID, query string, limit int) ([]model.SearchResult, error) {
+	stmt := `SELECT ` + taskColumns + `, MATCH(title, description) AGAINST(? IN NATURAL LANGUAGE MODE) AS score
+		FROM tasks
+		WHERE user_id=? AND MATCH(title, description) AGAINST(? IN NATURAL LANGUAGE MODE)
+		ORDER BY score DESC, created_at DESC
+		LIMIT ?`
+	rows, err := s.db.Query(stmt, query, userID, query, limit)
+	if err != nil {
+		s.log.Error("db fulltext search err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.SearchResult, 0)
+	for rows.Next() {
+		var score float64
+		task, err := scanTask(scoredRow{rows, &score})
+		if err != nil {
+			s.log.Error("db scan search result err", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, model.SearchResult{Task: *task, Score: score})
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// scoredRow lets scanTask read a row that carries an extra trailing score
+// column.
+type scoredRow struct {
+	rows  *sql.Rows
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...any) error {
+	return r.rows.Scan(append(dest, r.score)...)
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -9,6 +10,16 @@ import (
 	"github.com/google/uuid"
 )
 
+const taskColumns = `id, title, description, is_done, priority, user_id, parent_id, project_id, due_at, due_all_day, due_timezone, recurrence, occurrence, created_at, updated_at, completed_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
 type TodoStore struct {
 	db  *sql.DB
 	log *zap.Logger
@@ -18,113 +29,469 @@ func NewStore(db *sql.DB, log *zap.Logger) *TodoStore {
 	return &TodoStore{db: db, log: log}
 }
 
-func (s *TodoStore) Create(task model.Task) error {
-	query := `INSERT INTO tasks (id, title, description, is_done, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(
+func scanTask(row rowScanner) (*model.Task, error) {
+	var (
+		task        model.Task
+		description sql.NullString
+		dueAt       sql.NullTime
+		dueTimezone sql.NullString
+		priority    int
+		parentID    uuid.NullUUID
+		projectID   uuid.NullUUID
+		recurrence  sql.NullString
+		completedAt sql.NullTime
+	)
+	err := row.Scan(
+		&task.ID,
+		&task.Title,
+		&description,
+		&task.IsDone,
+		&priority,
+		&task.UserId,
+		&parentID,
+		&projectID,
+		&dueAt,
+		&task.DueAllDay,
+		&dueTimezone,
+		&recurrence,
+		&task.Occurrence,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Description = description.String
+	task.Priority = model.PriorityFromRank(priority)
+	task.DueTimezone = dueTimezone.String
+	task.Recurrence = recurrence.String
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if parentID.Valid {
+		task.ParentID = &parentID.UUID
+	}
+	if projectID.Valid {
+		task.ProjectID = &projectID.UUID
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+
+	return &task, nil
+}
+
+// Create inserts a task together with its tags in one transaction. If any
+// of tagIDs is not a tag of the task's user, nothing is written and
+// sql.ErrNoRows is returned.
+func (s *TodoStore) Create(task model.Task, tagIDs []uuid.UUID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertTask(tx, task); err != nil {
+		s.log.Error("db insert err", zap.Error(err))
+		return err
+	}
+	if len(tagIDs) > 0 {
+		if err := s.replaceTags(tx, task.ID, task.UserId, tagIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func insertTask(ex execer, task model.Task) error {
+	query := `INSERT INTO tasks (` + taskColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := ex.Exec(
 		query,
 		task.ID,
 		task.Title,
 		task.Description,
 		task.IsDone,
+		task.Priority.Rank(),
 		task.UserId,
+		task.ParentID,
+		task.ProjectID,
+		task.DueAt,
+		task.DueAllDay,
+		task.DueTimezone,
+		task.Recurrence,
+		task.Occurrence,
 		task.CreatedAt,
 		task.UpdatedAt,
+		task.CompletedAt,
 	)
-	if err != nil {
-		s.log.Error("db insert err", zap.Error(err))
-		return err
-	}
-	return nil
+	return err
 }
 
 func (s *TodoStore) GetByID(taskID, userID uuid.UUID) (*model.Task, error) {
-	var task model.Task
-	query := `SELECT id, title, description, is_done, user_id, created_at, updated_at FROM tasks WHERE id=? AND user_id=?`
-	err := s.db.QueryRow(query, taskID, userID).
-		Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.IsDone,
-			&task.UserId,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id=? AND user_id=?`
+	task, err := scanTask(s.db.QueryRow(query, taskID, userID))
 	if err != nil {
 		s.log.Error("db select error", zap.Error(err))
 		return nil, err
 	}
 
-	return &task, nil
+	tasks := []model.Task{*task}
+	if err := s.loadTags(tasks); err != nil {
+		return nil, err
+	}
+
+	return &tasks[0], nil
 }
 
 func (s *TodoStore) GetByTitle(title string, userID uuid.UUID) (*model.Task, error) {
-	query := `SELECT id, title, description, is_done, user_id, created_at, updated_at FROM tasks WHERE title = ? AND user_id=?`
-	var task model.Task
-
-	err := s.db.QueryRow(query, title, userID).
-		Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.IsDone,
-			&task.UserId,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE title = ? AND user_id=?`
+	task, err := scanTask(s.db.QueryRow(query, title, userID))
 	if err != nil {
 		s.log.Error("db selecting task by title err", zap.Error(err))
 		return nil, err
 	}
 
-	return &task, nil
+	return task, nil
 }
 
-func (s *TodoStore) Update(taskID, userID uuid.UUID, task model.Task) error {
-	query := `UPDATE tasks SET title=?, description=?, is_done=? WHERE id=? AND user_id=?`
-	_, err := s.db.Exec(query, task.Title, task.Description, task.IsDone, taskID, userID)
+// Update saves a task and, unless tagIDs is nil, replaces its tags in the
+// same transaction. Unknown tags are reported as in Create.
+func (s *TodoStore) Update(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID) error {
+	tx, err := s.db.Begin()
 	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateTask(tx, taskID, userID, task); err != nil {
 		s.log.Error("db update error", zap.Error(err), zap.String("task_id", taskID.String()))
 		return err
 	}
+	if tagIDs != nil {
+		if err := s.replaceTags(tx, taskID, userID, tagIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
 
 	return nil
 }
 
-func (s *TodoStore) List(userID uuid.UUID) ([]model.Task, error) {
-	tasks := make([]model.Task, 0)
-	query := `SELECT id, title, description, is_done, user_id, created_at, updated_at FROM tasks WHERE user_id=?`
-	rows, err := s.db.Query(query, userID)
+func updateTask(ex execer, taskID, userID uuid.UUID, task model.Task) error {
+	query := `UPDATE tasks SET title=?, description=?, is_done=?, priority=?, parent_id=?, project_id=?, due_at=?, due_all_day=?, due_timezone=?, recurrence=?, occurrence=?, updated_at=?, completed_at=? WHERE id=? AND user_id=?`
+	_, err := ex.Exec(
+		query,
+		task.Title,
+		task.Description,
+		task.IsDone,
+		task.Priority.Rank(),
+		task.ParentID,
+		task.ProjectID,
+		task.DueAt,
+		task.DueAllDay,
+		task.DueTimezone,
+		task.Recurrence,
+		task.Occurrence,
+		task.UpdatedAt,
+		task.CompletedAt,
+		taskID,
+		userID,
+	)
+	return err
+}
+
+// Complete saves a task that is being marked done together with the rest of
+// its completion in one transaction: the parent and, unless tagIDs is nil,
+// its tags are updated first, then the subtasks are closed and the next task
+// of the series is created with the parent's tags.
+func (s *TodoStore) Complete(taskID, userID uuid.UUID, task model.Task, tagIDs []uuid.UUID, completion model.TaskCompletion) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateTask(tx, taskID, userID, task); err != nil {
+		s.log.Error("db update error", zap.Error(err), zap.String("task_id", taskID.String()))
+		return err
+	}
+	if tagIDs != nil {
+		if err := s.replaceTags(tx, taskID, userID, tagIDs); err != nil {
+			return err
+		}
+	}
+
+	if len(completion.Subtasks) > 0 {
+		query := `UPDATE tasks SET is_done=TRUE, updated_at=?, completed_at=? WHERE user_id=? AND id IN (` + placeholders(len(completion.Subtasks)) + `)`
+		args := []any{task.UpdatedAt, task.CompletedAt, userID}
+		for _, id := range completion.Subtasks {
+			args = append(args, id)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			s.log.Error("db mark done err", zap.Error(err))
+			return err
+		}
+	}
+
+	if next := completion.Next; next != nil {
+		if err := insertTask(tx, *next); err != nil {
+			s.log.Error("db insert err", zap.Error(err))
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO task_tags (task_id, tag_id) SELECT ?, tag_id FROM task_tags WHERE task_id=?`, next.ID, taskID)
+		if err != nil {
+			s.log.Error("db copy task tags err", zap.Error(err), zap.String("task_id", next.ID.String()))
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *TodoStore) List(userID uuid.UUID, filter model.TaskFilter) ([]model.Task, error) {
+	where := []string{"user_id=?"}
+	args := []any{userID}
+
+	where, args = timeRange(where, args, "due_at", filter.DueFrom, filter.DueTo)
+	where, args = timeRange(where, args, "created_at", filter.CreatedFrom, filter.CreatedTo)
+	where, args = timeRange(where, args, "updated_at", filter.UpdatedFrom, filter.UpdatedTo)
+	where, args = timeRange(where, args, "completed_at", filter.CompletedFrom, filter.CompletedTo)
+	if filter.IsDone != nil {
+		where = append(where, "is_done = ?")
+		args = append(args, *filter.IsDone)
+	}
+	if filter.ProjectID != nil {
+		if *filter.ProjectID == uuid.Nil {
+			where = append(where, "project_id IS NULL")
+		} else {
+			where = append(where, "project_id = ?")
+			args = append(args, *filter.ProjectID)
+		}
+	}
+	if len(filter.Tags) > 0 {
+		clause := `id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.user_id = ? AND t.name IN (` + placeholders(len(filter.Tags)) + `)`
+		args = append(args, userID)
+		for _, name := range filter.Tags {
+			args = append(args, name)
+		}
+		if filter.TagMatch == model.TagMatchAll {
+			clause += ` GROUP BY tt.task_id HAVING COUNT(DISTINCT t.id) = ?`
+			args = append(args, len(filter.Tags))
+		}
+		where = append(where, clause+`)`)
+	}
+
+	if filter.Condition != "" {
+		where = append(where, "("+filter.Condition+")")
+		args = append(args, filter.ConditionArgs...)
+	}
+	if filter.After != nil {
+		clause, keysetArgs, err := keysetClause(filter)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, clause)
+		args = append(args, keysetArgs...)
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE ` + strings.Join(where, " AND ") + taskOrder(filter)
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		s.log.Error("db select err", zap.Error(err))
 		return nil, err
 	}
+	defer rows.Close()
 
+	tasks := make([]model.Task, 0)
 	for rows.Next() {
-		var task model.Task
-		rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.IsDone,
-			&task.UserId,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
-		tasks = append(tasks, task)
+		task, err := scanTask(rows)
+		if err != nil {
+			s.log.Error("db scan err", zap.Error(err))
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.loadTags(tasks); err != nil {
+		return nil, err
 	}
 
 	return tasks, nil
 }
 
-func (s *TodoStore) Delete(taskID, userID uuid.UUID) error {
-	query := `DELETE FROM tasks WHERE id=? AND user_id=?`
-	_, err := s.db.Exec(query, taskID, userID)
+// Delete removes a task together with the given subtasks in one
+// transaction.
+func (s *TodoStore) Delete(taskID, userID uuid.UUID, subtasks []uuid.UUID) error {
+	tx, err := s.db.Begin()
 	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(subtasks) > 0 {
+		query := `DELETE FROM tasks WHERE user_id=? AND id IN (` + placeholders(len(subtasks)) + `)`
+		args := []any{userID}
+		for _, id := range subtasks {
+			args = append(args, id)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			s.log.Error("db delete subtasks err", zap.Error(err), zap.String("id", taskID.String()))
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE id=? AND user_id=?`, taskID, userID); err != nil {
 		s.log.Error("db delete error", zap.Error(err), zap.String("id", taskID.String()))
 		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
+
 	return nil
 }
+
+// ListDescendants returns every task below taskID in the hierarchy, at any
+// depth, in no particular order.
+func (s *TodoStore) ListDescendants(taskID, userID uuid.UUID) ([]model.Task, error) {
+	query := `WITH RECURSIVE subtree (id) AS (
+		SELECT id FROM tasks WHERE parent_id=? AND user_id=?
+		UNION ALL
+		SELECT t.id FROM tasks t JOIN subtree st ON t.parent_id = st.id WHERE t.user_id=?
+	)
+	SELECT ` + taskColumns + ` FROM tasks WHERE id IN (SELECT id FROM subtree) ORDER BY created_at ASC`
+	rows, err := s.db.Query(query, taskID, userID, userID)
+	if err != nil {
+		s.log.Error("db select descendants err", zap.Error(err), zap.String("task_id", taskID.String()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]model.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			s.log.Error("db scan err", zap.Error(err))
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.loadTags(tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// replaceTags replaces the tags attached to a task within tx. Only tags owned
+// by userID are accepted; if any of tagIDs is unknown, sql.ErrNoRows is
+// returned and the caller rolls back.
+func (s *TodoStore) replaceTags(tx execer, taskID, userID uuid.UUID, tagIDs []uuid.UUID) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id=?`, taskID); err != nil {
+		s.log.Error("db delete task tags err", zap.Error(err), zap.String("task_id", taskID.String()))
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE user_id=? AND id IN (` + placeholders(len(tagIDs)) + `)`
+	args := []any{taskID, userID}
+	for _, id := range tagIDs {
+		args = append(args, id)
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		s.log.Error("db insert task tags err", zap.Error(err), zap.String("task_id", taskID.String()))
+		return err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(inserted) != len(tagIDs) {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *TodoStore) loadTags(tasks []model.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[uuid.UUID]int, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		args = append(args, task.ID)
+	}
+
+	query := `SELECT tt.task_id, t.id, t.user_id, t.name, t.created_at FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id IN (` + placeholders(len(tasks)) + `) ORDER BY t.name`
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		s.log.Error("db select task tags err", zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			taskID uuid.UUID
+			tag    model.Tag
+		)
+		if err := rows.Scan(&taskID, &tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt); err != nil {
+			s.log.Error("db scan task tag err", zap.Error(err))
+			return err
+		}
+		i := index[taskID]
+		tasks[i].Tags = append(tasks[i].Tags, tag)
+	}
+
+	return rows.Err()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
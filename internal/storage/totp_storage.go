@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type TOTPStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewTOTPStore(db *sql.DB, log *zap.Logger) *TOTPStore {
+	return &TOTPStore{db: db, log: log}
+}
+
+func (s *TOTPStore) Get(userID uuid.UUID) (*model.TOTP, error) {
+	query := `SELECT user_id, secret, confirmed_at, last_step, failed_attempts, locked_until, created_at FROM user_totp WHERE user_id=?`
+	var (
+		totp        model.TOTP
+		confirmedAt sql.NullTime
+		lockedUntil sql.NullTime
+	)
+	err := s.db.QueryRow(query, userID).Scan(&totp.UserID, &totp.Secret, &confirmedAt, &totp.LastStep, &totp.FailedAttempts, &lockedUntil, &totp.CreatedAt)
+	if err != nil {
+		s.log.Error("db select totp err", zap.Error(err))
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		totp.ConfirmedAt = &confirmedAt.Time
+	}
+	if lockedUntil.Valid {
+		totp.LockedUntil = &lockedUntil.Time
+	}
+
+	return &totp, nil
+}
+
+// SavePending stores a new, unconfirmed enrolment, replacing an earlier
+// unconfirmed one. A confirmed enrolment is left untouched.
+func (s *TOTPStore) SavePending(totp model.TOTP) error {
+	query := `INSERT INTO user_totp (user_id, secret, confirmed_at, last_step, created_at) VALUES (?, ?, NULL, 0, ?)
+		ON DUPLICATE KEY UPDATE
+			secret = IF(confirmed_at IS NULL, VALUES(secret), secret),
+			created_at = IF(confirmed_at IS NULL, VALUES(created_at), created_at)`
+	if _, err := s.db.Exec(query, totp.UserID, totp.Secret, totp.CreatedAt); err != nil {
+		s.log.Error("db save totp err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Confirm enables the enrolment and replaces the user's recovery codes.
+func (s *TOTPStore) Confirm(userID uuid.UUID, step int64, at time.Time, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_totp SET confirmed_at=?, last_step=? WHERE user_id=? AND confirmed_at IS NULL`, at, step, userID)
+	if err != nil {
+		s.log.Error("db confirm totp err", zap.Error(err))
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id=?`, userID); err != nil {
+		s.log.Error("db delete recovery codes err", zap.Error(err))
+		return err
+	}
+	for _, hash := range codeHashes {
+		query := `INSERT INTO totp_recovery_codes (id, user_id, code_hash, created_at) VALUES (?, ?, ?, ?)`
+		if _, err := tx.Exec(query, uuid.New(), userID, hash, at); err != nil {
+			s.log.Error("db insert recovery code err", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimAttempt counts an attempt at the user's second factor unless it is
+// locked at now, and reports whether the attempt may go ahead. The attempt
+// that reaches maxAttempts locks the second factor until lockUntil and starts
+// the count over.
+func (s *TOTPStore) ClaimAttempt(userID uuid.UUID, now time.Time, maxAttempts int, lockUntil time.Time) (bool, error) {
+	query := `UPDATE user_totp SET
+			locked_until = IF(failed_attempts + 1 >= ?, ?, NULL),
+			failed_attempts = IF(failed_attempts + 1 >= ?, 0, failed_attempts + 1)
+		WHERE user_id=? AND (locked_until IS NULL OR locked_until <= ?)`
+	res, err := s.db.Exec(query, maxAttempts, lockUntil, maxAttempts, userID, now)
+	if err != nil {
+		s.log.Error("db claim totp attempt err", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ResetAttempts forgets the user's failed attempts and lifts a lock.
+func (s *TOTPStore) ResetAttempts(userID uuid.UUID) error {
+	if _, err := s.db.Exec(`UPDATE user_totp SET failed_attempts=0, locked_until=NULL WHERE user_id=?`, userID); err != nil {
+		s.log.Error("db reset totp attempts err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSecret replaces the sealed secret, e.g. after the encryption key has
+// been rotated.
+func (s *TOTPStore) UpdateSecret(userID uuid.UUID, secret string) error {
+	if _, err := s.db.Exec(`UPDATE user_totp SET secret=? WHERE user_id=?`, secret, userID); err != nil {
+		s.log.Error("db update totp secret err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// UseStep records step as the last accepted code and reports whether it was
+// newer than the previous one, which stops a code from being used twice.
+func (s *TOTPStore) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	res, err := s.db.Exec(`UPDATE user_totp SET last_step=? WHERE user_id=? AND last_step < ?`, step, userID, step)
+	if err != nil {
+		s.log.Error("db update totp step err", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// UseRecoveryCode consumes an unused recovery code and reports whether one
+// matched.
+func (s *TOTPStore) UseRecoveryCode(userID uuid.UUID, hash string, at time.Time) (bool, error) {
+	query := `UPDATE totp_recovery_codes SET used_at=? WHERE user_id=? AND code_hash=? AND used_at IS NULL`
+	res, err := s.db.Exec(query, at, userID, hash)
+	if err != nil {
+		s.log.Error("db use recovery code err", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (s *TOTPStore) Delete(userID uuid.UUID) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.log.Error("db begin tx err", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id=?`, userID); err != nil {
+		s.log.Error("db delete recovery codes err", zap.Error(err))
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE user_id=?`, userID); err != nil {
+		s.log.Error("db delete totp err", zap.Error(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.log.Error("db commit err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -2,12 +2,23 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/devvdark0/todo/internal/model"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// ErrDuplicateEmail is returned when a write would give two users the same
+// email address.
+var ErrDuplicateEmail = errors.New("email address belongs to another user")
+
+// mysqlDuplicateEntry is the error number of a unique index violation.
+const mysqlDuplicateEntry = 1062
+
 type UserStore struct {
 	db  *sql.DB
 	log *zap.Logger
@@ -20,10 +31,53 @@ func NewUserStore(db *sql.DB, log *zap.Logger) *UserStore {
 	}
 }
 
+const userColumns = `id, email, username, password, email_verified, verification_sent_at, display_name, timezone, locale, deletion_scheduled_at, role, disabled_at`
+
+func scanUser(row rowScanner) (*model.User, error) {
+	var (
+		user       model.User
+		sentAt     sql.NullTime
+		deletionAt sql.NullTime
+		disabledAt sql.NullTime
+	)
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Password,
+		&user.EmailVerified,
+		&sentAt,
+		&user.DisplayName,
+		&user.Timezone,
+		&user.Locale,
+		&deletionAt,
+		&user.Role,
+		&disabledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sentAt.Valid {
+		user.VerificationSentAt = &sentAt.Time
+	}
+	if deletionAt.Valid {
+		user.DeletionScheduledAt = &deletionAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+
+	return &user, nil
+}
+
 func (s *UserStore) Create(user model.User) error {
-	query := `INSERT INTO users(id, email, username, password) VALUES(?,?,?,?)`
-	_, err := s.db.Exec(query, user.ID, user.Email, user.Username, user.Password)
+	query := `INSERT INTO users(id, email, username, password, email_verified) VALUES(?,?,?,?,?)`
+	_, err := s.db.Exec(query, user.ID, user.Email, user.Username, user.Password, user.EmailVerified)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			return ErrDuplicateEmail
+		}
 		s.log.Error("db insert user error", zap.Error(err))
 		return err
 	}
@@ -32,37 +86,318 @@ func (s *UserStore) Create(user model.User) error {
 }
 
 func (s *UserStore) GetByID(id uuid.UUID) (*model.User, error) {
-	query := `SELECT id, email, username, password FROM users WHERE id=?`
-	var user model.User
-	err := s.db.QueryRow(query, id).
-		Scan(
-			&user.ID,
-			&user.Email,
-			&user.Username,
-			&user.Password,
-		)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id=?`
+	user, err := scanUser(s.db.QueryRow(query, id))
 	if err != nil {
 		s.log.Error("db select user error", zap.Error(err))
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (s *UserStore) GetByEmail(email string) (*model.User, error) {
-	query := `SELECT id, email, username, password FROM users WHERE email = ?`
-	var user model.User
-	err := s.db.QueryRow(query, email).
-		Scan(
-			&user.ID,
-			&user.Email,
-			&user.Username,
-			&user.Password,
-		)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
+	user, err := scanUser(s.db.QueryRow(query, email))
 	if err != nil {
 		s.log.Error("db select user error", zap.Error(err))
 		return nil, err
 	}
 
+	return user, nil
+}
+
+func (s *UserStore) UpdatePassword(id uuid.UUID, password string) error {
+	query := `UPDATE users SET password=? WHERE id=?`
+	_, err := s.db.Exec(query, password, id)
+	if err != nil {
+		s.log.Error("db update user password error", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserStore) UpdateProfile(user model.User) error {
+	query := `UPDATE users SET username=?, display_name=?, timezone=?, locale=? WHERE id=?`
+	_, err := s.db.Exec(query, user.Username, user.DisplayName, user.Timezone, user.Locale, user.ID)
+	if err != nil {
+		s.log.Error("db update user profile error", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ChangeEmail switches the user to a confirmed new address, provided the
+// current one is still the address the change was requested from.
+func (s *UserStore) ChangeEmail(id uuid.UUID, current, replacement string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE users SET email=?, email_verified=TRUE WHERE id=? AND email=?`, replacement, id, current)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return false, ErrDuplicateEmail
+		}
+		s.log.Error("db update user email error", zap.Error(err))
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db update user email error", zap.Error(err))
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ReplacePassword swaps the password hash only if it is still current, so a
+// password changed in the meantime is not overwritten.
+func (s *UserStore) ReplacePassword(id uuid.UUID, current, replacement string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE users SET password=? WHERE id=? AND password=?`, replacement, id, current)
+	if err != nil {
+		s.log.Error("db replace user password error", zap.Error(err))
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db replace user password error", zap.Error(err))
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// MarkEmailVerified verifies the user's address, provided it is still the
+// one the verification link was issued for.
+func (s *UserStore) MarkEmailVerified(id uuid.UUID, email string) (bool, error) {
+	res, err := s.db.Exec(`UPDATE users SET email_verified=TRUE WHERE id=? AND email=?`, id, email)
+	if err != nil {
+		s.log.Error("db update user email verified error", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ClaimVerificationSend records that a verification email goes out at the
+// given time unless one was already sent after notBefore, and reports whether
+// the caller may send it.
+func (s *UserStore) ClaimVerificationSend(id uuid.UUID, at, notBefore time.Time) (bool, error) {
+	query := `UPDATE users SET verification_sent_at=?
+		WHERE id=? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)`
+	res, err := s.db.Exec(query, at, id, notBefore)
+	if err != nil {
+		s.log.Error("db update verification sent at error", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ClaimResetSend records that a password reset email goes out at the given
+// time unless one was already sent after notBefore, and reports whether the
+// caller may send it.
+func (s *UserStore) ClaimResetSend(id uuid.UUID, at, notBefore time.Time) (bool, error) {
+	query := `UPDATE users SET password_reset_sent_at=?
+		WHERE id=? AND (password_reset_sent_at IS NULL OR password_reset_sent_at <= ?)`
+	res, err := s.db.Exec(query, at, id, notBefore)
+	if err != nil {
+		s.log.Error("db update password reset sent at error", zap.Error(err))
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db rows affected err", zap.Error(err))
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// ScheduleDeletion sets when the user is deleted; nil cancels the deletion.
+func (s *UserStore) ScheduleDeletion(id uuid.UUID, at *time.Time) error {
+	_, err := s.db.Exec(`UPDATE users SET deletion_scheduled_at=? WHERE id=?`, at, id)
+	if err != nil {
+		s.log.Error("db update user deletion schedule error", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserStore) ListDueDeletions(now time.Time) ([]uuid.UUID, error) {
+	rows, err := s.db.Query(`SELECT id FROM users WHERE deletion_scheduled_at <= ?`, now)
+	if err != nil {
+		s.log.Error("db select due deletions error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			s.log.Error("db scan due deletion error", zap.Error(err))
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Delete removes a user whose deletion is due, together with everything
+// that references it. A deletion canceled in the meantime is left alone.
+func (s *UserStore) Delete(id uuid.UUID, now time.Time) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM users WHERE id=? AND deletion_scheduled_at <= ?`, id, now)
+	if err != nil {
+		s.log.Error("db delete user error", zap.Error(err))
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		s.log.Error("db delete user error", zap.Error(err))
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (s *UserStore) SetRole(id uuid.UUID, role string) error {
+	_, err := s.db.Exec(`UPDATE users SET role=? WHERE id=?`, role, id)
+	if err != nil {
+		s.log.Error("db update user role error", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// SetDisabled sets when the user was disabled; nil enables the user again.
+func (s *UserStore) SetDisabled(id uuid.UUID, at *time.Time) error {
+	_, err := s.db.Exec(`UPDATE users SET disabled_at=? WHERE id=?`, at, id)
+	if err != nil {
+		s.log.Error("db update user disabled error", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+const adminUserColumns = userColumns + `,
+	(SELECT COUNT(*) FROM tasks t WHERE t.user_id = users.id),
+	(SELECT COUNT(*) FROM tasks t WHERE t.user_id = users.id AND t.is_done = TRUE)`
+
+func scanAdminUser(row rowScanner) (*model.AdminUser, error) {
+	var (
+		user       model.AdminUser
+		sentAt     sql.NullTime
+		deletionAt sql.NullTime
+		disabledAt sql.NullTime
+	)
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.Password,
+		&user.EmailVerified,
+		&sentAt,
+		&user.DisplayName,
+		&user.Timezone,
+		&user.Locale,
+		&deletionAt,
+		&user.Role,
+		&disabledAt,
+		&user.Tasks.Total,
+		&user.Tasks.Done,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sentAt.Valid {
+		user.VerificationSentAt = &sentAt.Time
+	}
+	if deletionAt.Valid {
+		user.DeletionScheduledAt = &deletionAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+
 	return &user, nil
 }
+
+func (s *UserStore) GetAdminUser(id uuid.UUID) (*model.AdminUser, error) {
+	query := `SELECT ` + adminUserColumns + ` FROM users WHERE id=?`
+	user, err := scanAdminUser(s.db.QueryRow(query, id))
+	if err != nil {
+		s.log.Error("db select admin user error", zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Search lists users whose email or username contains query, ordered by
+// email. An empty query lists everybody.
+func (s *UserStore) Search(query string, limit, offset int) ([]model.AdminUser, error) {
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := s.db.Query(
+		`SELECT `+adminUserColumns+` FROM users WHERE email LIKE ? OR username LIKE ? ORDER BY email LIMIT ? OFFSET ?`,
+		pattern, pattern, limit, offset,
+	)
+	if err != nil {
+		s.log.Error("db search users error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]model.AdminUser, 0)
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			s.log.Error("db scan user error", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return users, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
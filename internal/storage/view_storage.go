@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/devvdark0/todo/internal/model"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const viewColumns = `id, user_id, name, query, sort, sort_order, pinned, created_at, updated_at`
+
+type ViewStore struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewViewStore(db *sql.DB, log *zap.Logger) *ViewStore {
+	return &ViewStore{db: db, log: log}
+}
+
+func scanView(row rowScanner) (*model.SavedView, error) {
+	var view model.SavedView
+	err := row.Scan(
+		&view.ID,
+		&view.UserID,
+		&view.Name,
+		&view.Query,
+		&view.Sort,
+		&view.Order,
+		&view.Pinned,
+		&view.CreatedAt,
+		&view.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &view, nil
+}
+
+func (s *ViewStore) Create(view model.SavedView) error {
+	query := `INSERT INTO saved_views (` + viewColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(
+		query,
+		view.ID,
+		view.UserID,
+		view.Name,
+		view.Query,
+		view.Sort,
+		view.Order,
+		view.Pinned,
+		view.CreatedAt,
+		view.UpdatedAt,
+	)
+	if err != nil {
+		s.log.Error("db insert view err", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *ViewStore) GetByID(viewID, userID uuid.UUID) (*model.SavedView, error) {
+	query := `SELECT ` + viewColumns + ` FROM saved_views WHERE id=? AND user_id=?`
+	view, err := scanView(s.db.QueryRow(query, viewID, userID))
+	if err != nil {
+		s.log.Error("db select view err", zap.Error(err))
+		return nil, err
+	}
+
+	return view, nil
+}
+
+func (s *ViewStore) List(userID uuid.UUID) ([]model.SavedView, error) {
+	query := `SELECT ` + viewColumns + ` FROM saved_views WHERE user_id=? ORDER BY pinned DESC, name`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		s.log.Error("db select views err", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]model.SavedView, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			s.log.Error("db scan view err", zap.Error(err))
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.log.Error("db rows err", zap.Error(err))
+		return nil, err
+	}
+
+	return views, nil
+}
+
+func (s *ViewStore) Update(viewID, userID uuid.UUID, view model.SavedView) error {
+	query := `UPDATE saved_views SET name=?, query=?, sort=?, sort_order=?, pinned=?, updated_at=? WHERE id=? AND user_id=?`
+	_, err := s.db.Exec(
+		query,
+		view.Name,
+		view.Query,
+		view.Sort,
+		view.Order,
+		view.Pinned,
+		view.UpdatedAt,
+		viewID,
+		userID,
+	)
+	if err != nil {
+		s.log.Error("db update view err", zap.Error(err), zap.String("view_id", viewID.String()))
+		return err
+	}
+
+	return nil
+}
+
+func (s *ViewStore) Delete(viewID, userID uuid.UUID) error {
+	query := `DELETE FROM saved_views WHERE id=? AND user_id=?`
+	_, err := s.db.Exec(query, viewID, userID)
+	if err != nil {
+		s.log.Error("db delete view err", zap.Error(err), zap.String("view_id", viewID.String()))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+DROP INDEX idx_tasks_user_due ON tasks;
+
+ALTER TABLE tasks
+DROP COLUMN due_at,
+DROP COLUMN due_all_day,
+DROP COLUMN due_timezone;
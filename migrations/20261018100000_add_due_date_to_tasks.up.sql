@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+ADD COLUMN due_at DATETIME NULL,
+ADD COLUMN due_all_day BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN due_timezone VARCHAR(64);
+
+CREATE INDEX idx_tasks_user_due ON tasks(user_id, due_at);
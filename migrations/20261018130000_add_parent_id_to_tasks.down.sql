@@ -0,0 +1,3 @@
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_parent;
+DROP INDEX idx_tasks_parent ON tasks;
+ALTER TABLE tasks DROP COLUMN parent_id;
@@ -0,0 +1,10 @@
+ALTER TABLE tasks
+ADD COLUMN parent_id CHAR(36) NULL;
+
+ALTER TABLE tasks
+ADD CONSTRAINT fk_tasks_parent
+    FOREIGN KEY (parent_id)
+    REFERENCES tasks(id)
+    ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_parent ON tasks(parent_id);
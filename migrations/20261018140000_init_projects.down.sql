@@ -0,0 +1,5 @@
+ALTER TABLE tasks DROP FOREIGN KEY fk_tasks_project;
+DROP INDEX idx_tasks_user_project ON tasks;
+ALTER TABLE tasks DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    color VARCHAR(7),
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    CONSTRAINT fk_projects_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE tasks
+ADD COLUMN project_id CHAR(36) NULL;
+
+ALTER TABLE tasks
+ADD CONSTRAINT fk_tasks_project
+    FOREIGN KEY (project_id)
+    REFERENCES projects(id)
+    ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_user_project ON tasks(user_id, project_id);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+ADD COLUMN recurrence VARCHAR(255),
+ADD COLUMN occurrence INT NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP INDEX idx_tasks_user_created ON tasks;
+DROP INDEX idx_tasks_user_updated ON tasks;
+DROP INDEX idx_tasks_user_title ON tasks;
+DROP INDEX idx_tasks_user_done ON tasks;
@@ -0,0 +1,4 @@
+CREATE INDEX idx_tasks_user_created ON tasks(user_id, created_at, id);
+CREATE INDEX idx_tasks_user_updated ON tasks(user_id, updated_at, id);
+CREATE INDEX idx_tasks_user_title ON tasks(user_id, title, id);
+CREATE INDEX idx_tasks_user_done ON tasks(user_id, is_done, id);
@@ -0,0 +1 @@
+DROP INDEX ft_tasks_title_description ON tasks;
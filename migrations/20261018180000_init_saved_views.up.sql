@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS saved_views (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    query VARCHAR(1024) NOT NULL DEFAULT '',
+    sort VARCHAR(32) NOT NULL DEFAULT '',
+    sort_order VARCHAR(4) NOT NULL DEFAULT '',
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    CONSTRAINT fk_saved_views_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS token_revocations (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    jti CHAR(36) NULL,
+    revoked_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    CONSTRAINT fk_token_revocations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_token_revocations_expires ON token_revocations(expires_at);
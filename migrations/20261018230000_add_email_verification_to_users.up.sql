@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN verification_sent_at DATETIME NULL;
+
+-- Accounts created before verification existed keep working.
+UPDATE users SET email_verified = TRUE;
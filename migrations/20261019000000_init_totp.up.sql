@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id CHAR(36) NOT NULL PRIMARY KEY,
+    secret VARCHAR(255) NOT NULL,
+    confirmed_at DATETIME NULL,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    CONSTRAINT fk_user_totp_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    CONSTRAINT fk_totp_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_totp_recovery_codes_user ON totp_recovery_codes(user_id, code_hash);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    user_id CHAR(36) NULL,
+    event VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    CONSTRAINT fk_audit_log_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_audit_log_event ON audit_log(event, created_at);
+CREATE INDEX idx_audit_log_user ON audit_log(user_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN display_name,
+    DROP COLUMN timezone,
+    DROP COLUMN locale;
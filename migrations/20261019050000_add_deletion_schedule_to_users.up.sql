@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at DATETIME NULL;
+
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
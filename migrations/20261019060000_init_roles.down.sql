@@ -0,0 +1,8 @@
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_role;
+
+ALTER TABLE users
+    DROP COLUMN role,
+    DROP COLUMN disabled_at;
+
+DROP TABLE IF EXISTS roles;
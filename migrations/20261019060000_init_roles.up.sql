@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(64) NOT NULL PRIMARY KEY,
+    permissions VARCHAR(1024) NOT NULL DEFAULT '',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL
+);
+
+INSERT INTO roles (name, permissions, builtin, created_at) VALUES
+    ('user', '', TRUE, NOW()),
+    ('admin', '', TRUE, NOW());
+
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(64) NOT NULL DEFAULT 'user',
+    ADD COLUMN disabled_at DATETIME NULL,
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
@@ -0,0 +1,5 @@
+DROP INDEX idx_tasks_user_priority ON tasks;
+CREATE INDEX idx_tasks_user_priority ON tasks(user_id, priority, due_at);
+
+ALTER TABLE tasks
+DROP COLUMN due_sort;
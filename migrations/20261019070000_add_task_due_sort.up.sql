@@ -0,0 +1,5 @@
+ALTER TABLE tasks
+ADD COLUMN due_sort DATETIME AS (COALESCE(due_at, '9999-12-31 23:59:59')) STORED;
+
+DROP INDEX idx_tasks_user_priority ON tasks;
+CREATE INDEX idx_tasks_user_priority ON tasks(user_id, priority DESC, due_sort, id);
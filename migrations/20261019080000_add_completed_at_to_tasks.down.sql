@@ -0,0 +1,4 @@
+DROP INDEX idx_tasks_user_completed ON tasks;
+
+ALTER TABLE tasks
+DROP COLUMN completed_at;
@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+ADD COLUMN completed_at DATETIME NULL;
+
+UPDATE tasks SET completed_at = updated_at WHERE is_done = TRUE;
+
+CREATE INDEX idx_tasks_user_completed ON tasks(user_id, completed_at, id);
@@ -0,0 +1,2 @@
+ALTER TABLE token_revocations
+MODIFY COLUMN revoked_at DATETIME(6) NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE user_totp
+DROP COLUMN locked_until,
+DROP COLUMN failed_attempts;
@@ -0,0 +1,3 @@
+ALTER TABLE user_totp
+ADD COLUMN failed_attempts INT NOT NULL DEFAULT 0,
+ADD COLUMN locked_until DATETIME NULL;
@@ -0,0 +1,3 @@
+-- Fails while two accounts share an address; those have to be merged or
+-- renamed by hand first.
+CREATE UNIQUE INDEX idx_users_email ON users(email);
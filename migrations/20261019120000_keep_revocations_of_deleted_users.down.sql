@@ -0,0 +1,4 @@
+DELETE FROM token_revocations WHERE user_id NOT IN (SELECT id FROM users);
+
+ALTER TABLE token_revocations
+ADD CONSTRAINT fk_token_revocations_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
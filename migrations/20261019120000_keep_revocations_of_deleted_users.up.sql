@@ -0,0 +1,3 @@
+-- Revocations have to outlive the user they cut off, since deleted accounts
+-- are revoked after their row is gone. They expire on their own.
+ALTER TABLE token_revocations DROP FOREIGN KEY fk_token_revocations_user;